@@ -0,0 +1,225 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	restapi "github.com/attestantio/esd/services/api/rest"
+	"github.com/attestantio/esd/services/controls"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// runAdmin runs an admin command against the admin API of a running esd.
+func runAdmin(ctx context.Context, cmd *command) error {
+	var command string
+	var args []string
+	if len(cmd.args) > 0 {
+		command = cmd.args[0]
+		args = cmd.args[1:]
+	}
+
+	req := &restapi.AdminRequest{
+		Actor:  viper.GetString("admin.actor"),
+		Reason: viper.GetString("admin.reason"),
+	}
+	if req.Actor == "" {
+		if current, err := user.Current(); err == nil {
+			req.Actor = current.Username
+		}
+	}
+
+	method := http.MethodPost
+	switch command {
+	case "controls", "audit":
+		method = http.MethodGet
+	case "pause", "resume":
+		if len(args) > 1 {
+			return newUsageError("usage: esd admin %s [action]", command)
+		}
+		if len(args) == 1 {
+			req.Action = args[0]
+		}
+	case "mute":
+		if len(args) != 2 {
+			return newUsageError("usage: esd admin mute <validator> <duration>")
+		}
+		req.Validator = args[0]
+		req.Duration = args[1]
+	case "unmute":
+		if len(args) != 1 {
+			return newUsageError("usage: esd admin unmute <validator>")
+		}
+		req.Validator = args[0]
+	case "disarm", "arm":
+		if len(args) != 0 {
+			return newUsageError("usage: esd admin %s", command)
+		}
+	case "":
+		return newUsageError("no admin command supplied; one of controls, audit, pause, resume, mute, unmute, disarm, arm")
+	default:
+		return newUsageError("unknown admin command %q", command)
+	}
+
+	majordomo, err := initMajordomo(ctx)
+	if err != nil {
+		return err
+	}
+	token, err := fetchAdminToken(ctx, majordomo)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return errors.New("no admin token configured")
+	}
+
+	res, err := callAdmin(ctx, method, command, token, req)
+	if err != nil {
+		return err
+	}
+	if cmd.json {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, res, "", "  "); err != nil {
+			return errors.Wrap(err, "invalid response from admin API")
+		}
+		fmt.Fprintf(os.Stdout, "%s\n", indented.String())
+
+		return nil
+	}
+
+	if command == "audit" {
+		entries := make([]*controls.AuditEntry, 0)
+		if err := json.Unmarshal(res, &entries); err != nil {
+			return errors.Wrap(err, "invalid response from admin API")
+		}
+
+		return printAudit(entries)
+	}
+
+	state := &controls.State{}
+	if err := json.Unmarshal(res, state); err != nil {
+		return errors.Wrap(err, "invalid response from admin API")
+	}
+	printControls(state)
+
+	return nil
+}
+
+// printControls prints the state of the controls.
+func printControls(state *controls.State) {
+	fmt.Fprintf(os.Stdout, "Disarmed:       %t\n", state.Disarmed)
+	fmt.Fprintf(os.Stdout, "Paused:         %t\n", state.Paused)
+	pausedActions := "none"
+	if len(state.PausedActions) > 0 {
+		pausedActions = strings.Join(state.PausedActions, ", ")
+	}
+	fmt.Fprintf(os.Stdout, "Paused actions: %s\n", pausedActions)
+	if len(state.Mutes) == 0 {
+		fmt.Fprintf(os.Stdout, "Muted:          none\n")
+		return
+	}
+	indices := make([]phase0.ValidatorIndex, 0, len(state.Mutes))
+	for index := range state.Mutes {
+		indices = append(indices, index)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+	fmt.Fprintf(os.Stdout, "Muted:\n")
+	for _, index := range indices {
+		fmt.Fprintf(os.Stdout, "  %d until %s\n", index, state.Mutes[index].Format(time.RFC3339))
+	}
+}
+
+// printAudit prints the audit log of changes to the controls.
+func printAudit(entries []*controls.AuditEntry) error {
+	if len(entries) == 0 {
+		fmt.Fprintf(os.Stdout, "No changes recorded\n")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "TIME\tACTOR\tCHANGE\tREASON\n")
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", entry.Time.Format(time.RFC3339), entry.Actor, entry.Change, entry.Reason)
+	}
+
+	return tw.Flush()
+}
+
+// apiURL returns the base URL of the API of a running esd.
+func apiURL() (string, error) {
+	base := viper.GetString("admin.url")
+	if base == "" {
+		if viper.GetString("api.listen-address") == "" {
+			return "", errors.New("no admin URL or API listen address configured")
+		}
+		base = fmt.Sprintf("http://%s", viper.GetString("api.listen-address"))
+	}
+
+	return strings.TrimSuffix(base, "/"), nil
+}
+
+// callAdmin calls the admin API, returning the body of the response.
+func callAdmin(ctx context.Context, method string, command string, token string, req *restapi.AdminRequest) ([]byte, error) {
+	base, err := apiURL()
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if method == http.MethodPost {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal request")
+		}
+		body = bytes.NewReader(data)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/v1/admin/%s", base, command), body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to call admin API")
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("admin API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	return data, nil
+}
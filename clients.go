@@ -15,10 +15,12 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	"github.com/attestantio/esd/util"
 	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
 	autoclient "github.com/attestantio/go-eth2-client/auto"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
@@ -53,3 +55,27 @@ func fetchClient(ctx context.Context, address string) (eth2client.Service, error
 
 	return client, nil
 }
+
+// checkNetwork returns the name of the network that the beacon node is on, and an error
+// if it is not the network configured in eth2client.network.
+func checkNetwork(ctx context.Context, eth2Client eth2client.Service) (string, error) {
+	specProvider, isProvider := eth2Client.(eth2client.SpecProvider)
+	if !isProvider {
+		return "", errors.New("client does not provide spec")
+	}
+	specResponse, err := specProvider.Spec(ctx, &api.SpecOpts{})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to obtain spec")
+	}
+	network, isString := specResponse.Data["CONFIG_NAME"].(string)
+	if !isString {
+		return "", errors.New("CONFIG_NAME not found in spec")
+	}
+
+	expected := viper.GetString("eth2client.network")
+	if expected != "" && expected != network {
+		return network, fmt.Errorf("beacon node is on network %q, expected %q", network, expected)
+	}
+
+	return network, nil
+}
@@ -0,0 +1,454 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/attestantio/esd/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Exit codes.
+const (
+	// exitSuccess is returned when a command succeeds.
+	exitSuccess = 0
+	// exitFailure is returned when a command fails, including when a check or script
+	// that it runs fails.
+	exitFailure = 1
+	// exitUsage is returned when a command is used incorrectly.
+	exitUsage = 2
+)
+
+// command is a subcommand of esd.
+type command struct {
+	// name is the name of the command.
+	name string
+	// usage is the usage of the command after its name.
+	usage string
+	// summary is a one-line description of the command.
+	summary string
+	// description is a full description of the command.
+	description string
+	// minArgs and maxArgs are the number of arguments that the command accepts;
+	// maxArgs of -1 is unlimited.
+	minArgs int
+	maxArgs int
+	// output is true if the command supports the output flag.
+	output bool
+	// noConfig is true if the command does not read the configuration file.
+	noConfig bool
+	// setFlags adds the flags specific to the command, if any.
+	setFlags func(flags *pflag.FlagSet)
+	// run runs the command.  The run command is run by main2 itself.
+	run func(ctx context.Context, cmd *command) error
+
+	flags *pflag.FlagSet
+	args  []string
+	json  bool
+}
+
+// usageError is an error in the use of a command.
+type usageError struct {
+	command string
+	msg     string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// newUsageError creates a new usage error.
+func newUsageError(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// commands returns the commands, in the order in which they are listed.
+func commands() []*command {
+	return []*command{
+		{
+			name:    "run",
+			usage:   "run",
+			summary: "Watch the beacon chain for slashings (default)",
+			description: "Watches the beacon chain for slashings, running scripts and notifiers when they are found.  " +
+				"Runs until it receives SIGINT or SIGTERM, and reloads its configuration on SIGHUP.",
+		},
+		{
+			name:    "scan",
+			usage:   "scan [flags] <block>...",
+			summary: "Process blocks for slashings and exit",
+			description: "Processes the supplied blocks, given by slot, root or 'head', and shows the slashings that they contain.  " +
+				"Scripts and notifiers are only run if --run-scripts is set, when each runs at most once for a slashing and esd must not be running.  " +
+				"Exits with status 1 if a block cannot be processed.",
+			minArgs: 1,
+			maxArgs: -1,
+			output:  true,
+			run:     runScan,
+			setFlags: func(flags *pflag.FlagSet) {
+				flags.Bool("run-scripts", false, "Run the scripts and notifiers for the slashings found")
+			},
+		},
+		{
+			name:    "test",
+			usage:   "test [flags]",
+			summary: "Test the slashing scripts and exit",
+			description: "Runs the attester and proposer slashing scripts for a validator index.  " +
+				"Exits with status 1 if a script fails.",
+			output: true,
+			run:    runTestScripts,
+			setFlags: func(flags *pflag.FlagSet) {
+				flags.Uint64("validator-index", 12345678, "Validator index to pass to the scripts")
+			},
+		},
+		{
+			name:    "drill",
+			usage:   "drill [flags] [validator]",
+			summary: "Run a fire drill with a synthetic slashing and exit",
+			description: "Builds a synthetic slashing for a watched validator, given by index or public key or else the first in the watchlist, " +
+				"and passes it through the penalty estimate, notifiers and scripts, including those for a mass slashing and the lifecycle milestones.  " +
+				"Scripts are skipped unless --run-scripts is set, when they are run with ESD_DRILL=1.  " +
+				"Nothing is recorded in the ledger, history or lifecycle.  " +
+				"Exits with status 1 if any step fails.",
+			maxArgs: 1,
+			output:  true,
+			run:     runDrill,
+			setFlags: func(flags *pflag.FlagSet) {
+				flags.String("offence", "attester", "Offence for the synthetic slashing (attester or proposer)")
+				flags.Bool("run-scripts", false, "Run the scripts rather than skipping them")
+			},
+		},
+		{
+			name:    "history",
+			usage:   "history [flags] [id]",
+			summary: "Show the recorded slashings",
+			description: "Shows the slashings recorded in the history, or the slashing with the supplied ID.  " +
+				"If esd is running then the history is read from its API.",
+			maxArgs: 1,
+			output:  true,
+			run:     runHistory,
+		},
+		{
+			name:    "config",
+			usage:   "config [flags] check",
+			summary: "Check the configuration",
+			description: "Checks the scripts, beacon node, secrets, notifiers and watchlist in the configuration, and shows the effective configuration with the source of each value.  " +
+				"Exits with status 1 if a check fails.",
+			minArgs: 1,
+			maxArgs: 1,
+			output:  true,
+			run:     runConfig,
+		},
+		{
+			name:        "version",
+			usage:       "version [flags]",
+			summary:     "Show the version",
+			description: "Shows the version of esd.",
+			output:      true,
+			noConfig:    true,
+			run:         runVersion,
+		},
+		{
+			name:    "retry-failed",
+			usage:   "retry-failed [flags]",
+			summary: "Retry the blocks that could not be fetched",
+			description: "Processes the blocks recorded as failed, removing those that succeed.  " +
+				"Exits with status 1 if any block remains failed.  Cannot be run while esd is running.",
+			output: true,
+			run:    runRetryFailed,
+		},
+		{
+			name:        "penalty",
+			usage:       "penalty [flags] <validator index>...",
+			summary:     "Estimate the penalties for validators if slashed now",
+			description: "Estimates the penalties that the supplied validators would incur if they were slashed now.",
+			minArgs:     1,
+			maxArgs:     -1,
+			output:      true,
+			run:         runPenalty,
+		},
+		{
+			name:        "admin",
+			usage:       "admin [flags] <command> [arguments]",
+			summary:     "Run an admin command against a running esd",
+			description: "Runs one of controls, audit, pause, resume, mute, unmute, disarm or arm against the admin API of a running esd.",
+			maxArgs:     -1,
+			output:      true,
+			run:         runAdmin,
+		},
+		{
+			name:        "help",
+			usage:       "help [command]",
+			summary:     "Show help for a command",
+			description: "Shows help for esd, or for the supplied command.",
+			maxArgs:     1,
+			noConfig:    true,
+			run:         runHelp,
+		},
+	}
+}
+
+// findCommand returns the command with the given name, or nil if there is none.
+func findCommand(name string) *command {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+
+	return nil
+}
+
+// parseCommandLine selects the command from the command line and parses its flags,
+// which include the global flags.
+func parseCommandLine(args []string) (*command, error) {
+	name, rest := splitCommand(args)
+	explicit := name != ""
+	if !explicit {
+		name = "run"
+	}
+	cmd := findCommand(name)
+	if cmd == nil {
+		return nil, newUsageError("unknown command %q", name)
+	}
+
+	if err := cmd.parse(rest); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			if explicit {
+				printCommandHelp(os.Stdout, cmd)
+			} else {
+				printHelp(os.Stdout)
+			}
+		}
+
+		return nil, err
+	}
+
+	if !explicit {
+		// Map the flags that used to select modes to their commands.
+		var alias *command
+		var aliasArgs []string
+		switch {
+		case flagSet(cmd.flags, "version"):
+			alias = findCommand("version")
+		case flagSet(cmd.flags, "test-scripts"):
+			alias = findCommand("test")
+		case flagSet(cmd.flags, "test-block"):
+			alias = findCommand("scan")
+			// The flag used to run the scripts for the block.
+			aliasArgs = []string{"--run-scripts", pflag.Lookup("test-block").Value.String()}
+		}
+		if alias != nil {
+			if err := alias.parse(aliasArgs); err != nil {
+				return nil, err
+			}
+			cmd = alias
+		}
+	}
+
+	return cmd, nil
+}
+
+// splitCommand finds the name of the command in the arguments, skipping global flags and
+// their values, and returns it along with the remaining arguments.
+func splitCommand(args []string) (string, []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "-") {
+			if !strings.Contains(arg, "=") {
+				if flag := pflag.Lookup(strings.TrimLeft(arg, "-")); flag != nil && flag.NoOptDefVal == "" {
+					// Flag takes the next argument as its value.
+					i++
+				}
+			}
+
+			continue
+		}
+
+		rest := append(append([]string{}, args[:i]...), args[i+1:]...)
+
+		return arg, rest
+	}
+
+	return "", args
+}
+
+// parse parses the arguments of the command.
+func (c *command) parse(args []string) error {
+	c.flags = pflag.NewFlagSet("esd "+c.name, pflag.ContinueOnError)
+	c.flags.SetOutput(io.Discard)
+	c.flags.AddFlagSet(pflag.CommandLine)
+	c.addFlags(c.flags)
+	if err := c.flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return err
+		}
+
+		return &usageError{command: c.name, msg: err.Error()}
+	}
+	c.flags.Visit(func(flag *pflag.Flag) {
+		if flag.Deprecated != "" {
+			fmt.Fprintf(os.Stderr, "Flag --%s is deprecated; %s\n", flag.Name, flag.Deprecated)
+		}
+	})
+
+	c.args = c.flags.Args()
+	if len(c.args) < c.minArgs || (c.maxArgs != -1 && len(c.args) > c.maxArgs) {
+		return &usageError{command: c.name, msg: fmt.Sprintf("incorrect arguments; usage: esd %s", c.usage)}
+	}
+
+	if c.output {
+		output, err := c.flags.GetString("output")
+		if err != nil {
+			return errors.Wrap(err, "failed to obtain output format")
+		}
+		switch output {
+		case "text":
+		case "json":
+			c.json = true
+		default:
+			return &usageError{command: c.name, msg: fmt.Sprintf("unknown output format %q; must be text or json", output)}
+		}
+	}
+
+	return nil
+}
+
+// addFlags adds the flags specific to the command to the flag set.
+func (c *command) addFlags(flags *pflag.FlagSet) {
+	if c.setFlags != nil {
+		c.setFlags(flags)
+	}
+	if c.output {
+		flags.String("output", "text", "Output format: text or json")
+	}
+}
+
+// flagSet returns true if the boolean or string flag was set on the command line.
+func flagSet(flags *pflag.FlagSet, name string) bool {
+	flag := flags.Lookup(name)
+	if flag == nil || !flag.Changed {
+		return false
+	}
+
+	return flag.Value.String() != "" && flag.Value.String() != "false"
+}
+
+// runCommand runs a command other than run, returning the exit code.
+func runCommand(ctx context.Context, cmd *command) int {
+	if err := cmd.run(ctx, cmd); err != nil {
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			if usageErr.command == "" {
+				usageErr.command = cmd.name
+			}
+			printUsageError(err)
+
+			return exitUsage
+		}
+		log.Error().Err(err).Msg("Failed to run command")
+
+		return exitFailure
+	}
+
+	return exitSuccess
+}
+
+// printUsageError prints an error in the use of a command.
+func printUsageError(err error) {
+	fmt.Fprintf(os.Stderr, "esd: %v\n", err)
+	var usageErr *usageError
+	if errors.As(err, &usageErr) && usageErr.command != "" {
+		fmt.Fprintf(os.Stderr, "Run 'esd help %s' for usage.\n", usageErr.command)
+	} else {
+		fmt.Fprintf(os.Stderr, "Run 'esd help' for usage.\n")
+	}
+}
+
+// printHelp prints the general help.
+func printHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage: esd [global flags] [command] [flags] [arguments]\n\n")
+	fmt.Fprintf(w, "esd watches the beacon chain for slashings, and runs scripts and notifiers when they are found.\n\n")
+	fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-14s%s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nGlobal flags, which apply to all commands:\n%s", pflag.CommandLine.FlagUsages())
+	fmt.Fprintf(w, "\nRun 'esd help <command>' for more information on a command.\n")
+}
+
+// printCommandHelp prints the help for a command.
+func printCommandHelp(w io.Writer, cmd *command) {
+	fmt.Fprintf(w, "Usage: esd %s\n\n%s\n", cmd.usage, cmd.description)
+	flags := pflag.NewFlagSet(cmd.name, pflag.ContinueOnError)
+	cmd.addFlags(flags)
+	if flags.HasFlags() {
+		fmt.Fprintf(w, "\nFlags:\n%s", flags.FlagUsages())
+	}
+	fmt.Fprintf(w, "\nGlobal flags are listed by 'esd help'.\n")
+	fmt.Fprintf(w, "Exit status is %d on success, %d on failure and %d on incorrect usage.\n", exitSuccess, exitFailure, exitUsage)
+}
+
+// runHelp prints help for esd or a command.
+func runHelp(_ context.Context, cmd *command) error {
+	if len(cmd.args) == 0 {
+		printHelp(os.Stdout)
+		return nil
+	}
+	helpCmd := findCommand(cmd.args[0])
+	if helpCmd == nil {
+		return newUsageError("unknown command %q", cmd.args[0])
+	}
+	printCommandHelp(os.Stdout, helpCmd)
+
+	return nil
+}
+
+// versionInfo is the output of the version command.
+type versionInfo struct {
+	Version    string `json:"version"`
+	CommitHash string `json:"commit_hash,omitempty"`
+}
+
+// runVersion prints the version.
+func runVersion(_ context.Context, cmd *command) error {
+	if cmd.json {
+		return printJSON(&versionInfo{
+			Version:    ReleaseVersion,
+			CommitHash: util.CommitHash(),
+		})
+	}
+	fmt.Fprintf(os.Stdout, "%s\n", ReleaseVersion)
+
+	return nil
+}
+
+// printJSON prints data as indented JSON.
+func printJSON(data any) error {
+	res, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal output")
+	}
+	fmt.Fprintf(os.Stdout, "%s\n", string(res))
+
+	return nil
+}
@@ -0,0 +1,273 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+// resetGlobalFlags replaces the global flags with a fresh set, as parsing the command
+// line changes them.
+func resetGlobalFlags(t *testing.T) {
+	t.Helper()
+
+	pflag.CommandLine = pflag.NewFlagSet("esd", pflag.ContinueOnError)
+	require.NoError(t, addGlobalFlags())
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		cmd  string
+		rest []string
+	}{
+		{
+			name: "Empty",
+			args: []string{},
+			cmd:  "",
+			rest: []string{},
+		},
+		{
+			name: "Command",
+			args: []string{"scan", "head"},
+			cmd:  "scan",
+			rest: []string{"head"},
+		},
+		{
+			name: "FlagWithValue",
+			args: []string{"--eth2client.address", "localhost:5051", "scan", "head"},
+			cmd:  "scan",
+			rest: []string{"--eth2client.address", "localhost:5051", "head"},
+		},
+		{
+			name: "FlagWithInlineValue",
+			args: []string{"--eth2client.address=localhost:5051", "history"},
+			cmd:  "history",
+			rest: []string{"--eth2client.address=localhost:5051"},
+		},
+		{
+			name: "BoolFlag",
+			args: []string{"--slashings.verify", "history"},
+			cmd:  "history",
+			rest: []string{"--slashings.verify"},
+		},
+		{
+			name: "FlagAfterCommand",
+			args: []string{"penalty", "--output=json", "1"},
+			cmd:  "penalty",
+			rest: []string{"--output=json", "1"},
+		},
+		{
+			name: "DeprecatedBoolFlag",
+			args: []string{"--version"},
+			cmd:  "",
+			rest: []string{"--version"},
+		},
+		{
+			name: "DeprecatedStringFlag",
+			args: []string{"--test-block", "123"},
+			cmd:  "",
+			rest: []string{"--test-block", "123"},
+		},
+		{
+			name: "Terminator",
+			args: []string{"--", "scan"},
+			cmd:  "",
+			rest: []string{"--", "scan"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetGlobalFlags(t)
+			cmd, rest := splitCommand(test.args)
+			require.Equal(t, test.cmd, cmd)
+			require.Equal(t, test.rest, rest)
+		})
+	}
+}
+
+func TestParseCommandLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		cmd     string
+		cmdArgs []string
+		json    bool
+		err     string
+	}{
+		{
+			name:    "Default",
+			args:    []string{},
+			cmd:     "run",
+			cmdArgs: []string{},
+		},
+		{
+			name:    "DefaultWithFlags",
+			args:    []string{"--eth2client.address", "localhost:5051"},
+			cmd:     "run",
+			cmdArgs: []string{},
+		},
+		{
+			name:    "VersionAlias",
+			args:    []string{"--version"},
+			cmd:     "version",
+			cmdArgs: []string{},
+		},
+		{
+			name:    "TestScriptsAlias",
+			args:    []string{"--test-scripts"},
+			cmd:     "test",
+			cmdArgs: []string{},
+		},
+		{
+			name:    "TestBlockAlias",
+			args:    []string{"--test-block", "123"},
+			cmd:     "scan",
+			cmdArgs: []string{"123"},
+		},
+		{
+			name:    "TestBlockAliasInline",
+			args:    []string{"--log-level", "debug", "--test-block=head"},
+			cmd:     "scan",
+			cmdArgs: []string{"head"},
+		},
+		{
+			name:    "ExplicitCommandNotAliased",
+			args:    []string{"history", "--version"},
+			cmd:     "history",
+			cmdArgs: []string{},
+		},
+		{
+			name:    "Scan",
+			args:    []string{"scan", "head", "123"},
+			cmd:     "scan",
+			cmdArgs: []string{"head", "123"},
+		},
+		{
+			name:    "VersionJSON",
+			args:    []string{"version", "--output=json"},
+			cmd:     "version",
+			cmdArgs: []string{},
+			json:    true,
+		},
+		{
+			name:    "PenaltyJSON",
+			args:    []string{"penalty", "--output", "json", "1", "2"},
+			cmd:     "penalty",
+			cmdArgs: []string{"1", "2"},
+			json:    true,
+		},
+		{
+			name:    "RetryFailedJSON",
+			args:    []string{"retry-failed", "--output=json"},
+			cmd:     "retry-failed",
+			cmdArgs: []string{},
+			json:    true,
+		},
+		{
+			name:    "AdminJSON",
+			args:    []string{"admin", "--output=json", "controls"},
+			cmd:     "admin",
+			cmdArgs: []string{"controls"},
+			json:    true,
+		},
+		{
+			name:    "AdminText",
+			args:    []string{"admin", "mute", "1", "2h"},
+			cmd:     "admin",
+			cmdArgs: []string{"mute", "1", "2h"},
+		},
+		{
+			name: "UnknownCommand",
+			args: []string{"unknown"},
+			err:  `unknown command "unknown"`,
+		},
+		{
+			name: "MissingArguments",
+			args: []string{"scan"},
+			err:  "incorrect arguments; usage: esd scan [flags] <block>...",
+		},
+		{
+			name: "TooManyArguments",
+			args: []string{"history", "a", "b"},
+			err:  "incorrect arguments; usage: esd history [flags] [id]",
+		},
+		{
+			name: "UnknownOutput",
+			args: []string{"penalty", "--output=xml", "1"},
+			err:  `unknown output format "xml"; must be text or json`,
+		},
+		{
+			name: "OutputNotSupported",
+			args: []string{"run", "--output=json"},
+			err:  "unknown flag: --output",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetGlobalFlags(t)
+			cmd, err := parseCommandLine(test.args)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.cmd, cmd.name)
+			require.Equal(t, test.cmdArgs, cmd.args)
+			require.Equal(t, test.json, cmd.json)
+		})
+	}
+}
+
+func TestScanRunScripts(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		runScripts bool
+	}{
+		{
+			name:       "Default",
+			args:       []string{"scan", "head"},
+			runScripts: false,
+		},
+		{
+			name:       "RunScripts",
+			args:       []string{"scan", "--run-scripts", "head"},
+			runScripts: true,
+		},
+		{
+			name:       "TestBlockAlias",
+			args:       []string{"--test-block", "123"},
+			runScripts: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetGlobalFlags(t)
+			cmd, err := parseCommandLine(test.args)
+			require.NoError(t, err)
+			require.Equal(t, "scan", cmd.name)
+			runScripts, err := cmd.flags.GetBool("run-scripts")
+			require.NoError(t, err)
+			require.Equal(t, test.runScripts, runScripts)
+		})
+	}
+}
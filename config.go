@@ -0,0 +1,295 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	webhooknotifier "github.com/attestantio/esd/services/notifiers/webhook"
+	"github.com/attestantio/esd/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// envKeyReplacer maps configuration keys to the names of environment variables.
+var envKeyReplacer = strings.NewReplacer("-", "_", ".", "_")
+
+// secretKeys are the configuration keys whose values are masked when printed.
+var secretKeys = map[string]bool{
+	"api.admin-token":      true,
+	"majordomo.asm.secret": true,
+}
+
+// maskedValue replaces the value of a secret.
+const maskedValue = "********"
+
+// configReport is the output of the config check command.
+type configReport struct {
+	ConfigFile string           `json:"config_file,omitempty"`
+	Checks     []*configCheck   `json:"checks"`
+	Settings   []*configSetting `json:"settings"`
+}
+
+// configCheck is the result of checking a configuration setting.
+type configCheck struct {
+	Key    string `json:"key"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// configSetting is a configuration setting, with the source of its value.
+type configSetting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// newConfigCheck creates the result of a check.
+func newConfigCheck(key string, detail string, err error) *configCheck {
+	check := &configCheck{
+		Key:    key,
+		OK:     err == nil,
+		Detail: detail,
+	}
+	if err != nil {
+		check.Error = err.Error()
+	}
+
+	return check
+}
+
+// runConfig runs a configuration command.
+func runConfig(ctx context.Context, cmd *command) error {
+	switch cmd.args[0] {
+	case "check":
+		return runConfigCheck(ctx, cmd)
+	default:
+		return newUsageError("unknown config command %q; must be check", cmd.args[0])
+	}
+}
+
+// runConfigCheck validates the configuration, and prints the results along with the
+// effective configuration.
+func runConfigCheck(ctx context.Context, cmd *command) error {
+	report := &configReport{
+		ConfigFile: viper.ConfigFileUsed(),
+		Checks:     checkConfig(ctx),
+		Settings:   make([]*configSetting, 0),
+	}
+	keys := viper.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		report.Settings = append(report.Settings, &configSetting{
+			Key:    key,
+			Value:  configValue(key),
+			Source: configSource(key),
+		})
+	}
+	failed := 0
+	for _, check := range report.Checks {
+		if !check.OK {
+			failed++
+		}
+	}
+
+	if cmd.json {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	} else if err := printConfigReport(report); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d configuration checks failed", failed)
+	}
+
+	return nil
+}
+
+// printConfigReport prints the report of the config check command as text.
+func printConfigReport(report *configReport) error {
+	configFile := report.ConfigFile
+	if configFile == "" {
+		configFile = "none"
+	}
+	fmt.Fprintf(os.Stdout, "Configuration file: %s\n\n", configFile)
+
+	fmt.Fprintf(os.Stdout, "Checks:\n")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, check := range report.Checks {
+		if check.OK {
+			fmt.Fprintf(tw, "  ok\t%s\t%s\n", check.Key, check.Detail)
+		} else {
+			fmt.Fprintf(tw, "  FAIL\t%s\t%s\n", check.Key, check.Error)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return errors.Wrap(err, "failed to print checks")
+	}
+
+	fmt.Fprintf(os.Stdout, "\nEffective configuration:\n")
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, setting := range report.Settings {
+		fmt.Fprintf(tw, "  %s\t%s\t(%s)\n", setting.Key, setting.Value, setting.Source)
+	}
+	if err := tw.Flush(); err != nil {
+		return errors.Wrap(err, "failed to print configuration")
+	}
+
+	return nil
+}
+
+// checkConfig checks the scripts, beacon node, secrets, notifiers and watchlist.
+func checkConfig(ctx context.Context) []*configCheck {
+	checks := make([]*configCheck, 0)
+
+	for _, key := range scriptKeys {
+		script := viper.GetString(key)
+		if script == "" {
+			continue
+		}
+		checks = append(checks, newConfigCheck(key, script, checkScript(script)))
+	}
+
+	checks = append(checks, checkBeaconNode(ctx))
+
+	if viper.GetString("api.admin-token") != "" {
+		majordomo, err := initMajordomo(ctx)
+		if err == nil {
+			_, err = fetchAdminToken(ctx, majordomo)
+		}
+		checks = append(checks, newConfigCheck("api.admin-token", "resolved", err))
+	}
+
+	for _, webhookURL := range viper.GetStringSlice("notifiers.webhook.urls") {
+		_, err := webhooknotifier.New(ctx,
+			webhooknotifier.WithLogLevel(util.LogLevel("notifiers.webhook")),
+			webhooknotifier.WithURL(webhookURL),
+			webhooknotifier.WithTimeout(viper.GetDuration("notifiers.webhook.timeout")),
+		)
+		if err != nil {
+			err = errors.Wrap(err, maskURL(webhookURL))
+		}
+		checks = append(checks, newConfigCheck("notifiers.webhook.urls", maskURL(webhookURL), err))
+	}
+
+	detail := ""
+	watchlist, err := startWatchlist(ctx)
+	if err == nil {
+		detail = "all validators"
+		if watchlist.Size() > 0 {
+			detail = fmt.Sprintf("%d validators", watchlist.Size())
+		}
+	}
+	checks = append(checks, newConfigCheck("watchlist.validators", detail, err))
+
+	return checks
+}
+
+// checkBeaconNode checks that the beacon node is reachable and on the expected network.
+func checkBeaconNode(ctx context.Context) *configCheck {
+	address := viper.GetString("eth2client.address")
+	if address == "" {
+		return newConfigCheck("eth2client.address", "", errors.New("no beacon node address supplied"))
+	}
+	// The address can hold credentials, so only its host is shown.
+	maskedAddress := maskURL(address)
+	eth2Client, err := fetchClient(ctx, address)
+	if err != nil {
+		return newConfigCheck("eth2client.address", maskedAddress, errors.Wrap(err, fmt.Sprintf("failed to connect to %s", maskedAddress)))
+	}
+	network, err := checkNetwork(ctx, eth2Client)
+	if err != nil {
+		return newConfigCheck("eth2client.address", maskedAddress, err)
+	}
+
+	return newConfigCheck("eth2client.address", fmt.Sprintf("%s on network %s", maskedAddress, network), nil)
+}
+
+// configValue returns the value of a configuration key for printing, with secrets masked.
+func configValue(key string) string {
+	var value string
+	switch {
+	case key == "notifiers.webhook.urls":
+		urls := viper.GetStringSlice(key)
+		for i := range urls {
+			urls[i] = maskURL(urls[i])
+		}
+		value = strings.Join(urls, ",")
+	case key == "eth2client.address":
+		value = viper.GetString(key)
+		if value != "" {
+			value = maskURL(value)
+		}
+	default:
+		value = viper.GetString(key)
+		if value == "" {
+			if slice := viper.GetStringSlice(key); len(slice) > 0 {
+				value = strings.Join(slice, ",")
+			}
+		}
+	}
+	if value != "" && secretKeys[key] {
+		value = maskedValue
+	}
+
+	return value
+}
+
+// configSource returns the source of the value of a configuration key, in order of
+// precedence.
+func configSource(key string) string {
+	if flag := pflag.Lookup(key); flag != nil && flag.Changed {
+		return "flag"
+	}
+	if _, exists := os.LookupEnv("ESD_" + strings.ToUpper(envKeyReplacer.Replace(key))); exists {
+		return "env"
+	}
+	if viper.InConfig(key) {
+		return "file"
+	}
+
+	return "default"
+}
+
+// maskURL masks the parts of a URL other than its scheme and host, as webhook URLs and
+// beacon node addresses commonly hold credentials.  The URL can omit its scheme.
+func maskURL(input string) string {
+	schemeless := !strings.Contains(input, "://")
+	toParse := input
+	if schemeless {
+		toParse = "http://" + input
+	}
+	parsedURL, err := url.Parse(toParse)
+	if err != nil || parsedURL.Host == "" {
+		return maskedValue
+	}
+	if parsedURL.User == nil && (parsedURL.Path == "" || parsedURL.Path == "/") && parsedURL.RawQuery == "" {
+		return input
+	}
+	if schemeless {
+		return fmt.Sprintf("%s/%s", parsedURL.Host, maskedValue)
+	}
+
+	return fmt.Sprintf("%s://%s/%s", parsedURL.Scheme, parsedURL.Host, maskedValue)
+}
@@ -0,0 +1,411 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/attestantio/esd/services/chaintime"
+	standardlifecycle "github.com/attestantio/esd/services/lifecycle/standard"
+	"github.com/attestantio/esd/services/slashings"
+	headslashings "github.com/attestantio/esd/services/slashings/head"
+	"github.com/attestantio/esd/util"
+	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// drillReport is the report of a drill.
+type drillReport struct {
+	Event *slashings.SlashingEvent `json:"event"`
+	Steps []*drillStep             `json:"steps"`
+}
+
+// drillStep is the result of a single step of a drill.
+type drillStep struct {
+	Step    string `json:"step"`
+	Target  string `json:"target,omitempty"`
+	Success bool   `json:"success"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// newDrillStep creates a drill step with the given outcome.
+func newDrillStep(step string, target string, err error) *drillStep {
+	res := &drillStep{
+		Step:    step,
+		Target:  target,
+		Success: err == nil,
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	return res
+}
+
+// addResults adds the results of the actions run for a drill to the report.
+func (r *drillReport) addResults(results []*slashings.DrillResult) {
+	for _, result := range results {
+		r.Steps = append(r.Steps, &drillStep{
+			Step:    result.Action,
+			Target:  result.Target,
+			Success: result.Success,
+			Skipped: result.Skipped,
+			Output:  result.Output,
+			Error:   result.Error,
+		})
+	}
+}
+
+// runDrill builds a synthetic slashing for a watched validator and passes it through
+// the penalty estimate, notifiers and scripts, including those for a mass slashing and
+// the lifecycle milestones, reporting the outcome of each.  Scripts are only run if
+// requested, and are otherwise reported as skipped.
+func runDrill(ctx context.Context, cmd *command) error {
+	offence, err := cmd.flags.GetString("offence")
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain offence")
+	}
+	runScripts, err := cmd.flags.GetBool("run-scripts")
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain run scripts")
+	}
+	if offence != string(slashings.TypeAttester) && offence != string(slashings.TypeProposer) {
+		return newUsageError("invalid offence %q; must be attester or proposer", offence)
+	}
+
+	validatorID := ""
+	switch {
+	case len(cmd.args) > 0:
+		validatorID = cmd.args[0]
+	case len(viper.GetStringSlice("watchlist.validators")) > 0:
+		validatorID = strings.TrimSpace(viper.GetStringSlice("watchlist.validators")[0])
+	default:
+		return newUsageError("a validator is required when the watchlist is empty")
+	}
+
+	eth2Client, err := fetchClient(ctx, viper.GetString("eth2client.address"))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to fetch client %q", maskURL(viper.GetString("eth2client.address"))))
+	}
+
+	chainTime, err := startChainTime(ctx, eth2Client)
+	if err != nil {
+		return err
+	}
+
+	watchlist, err := startWatchlist(ctx)
+	if err != nil {
+		return err
+	}
+
+	validator, err := drillValidator(ctx, eth2Client, validatorID)
+	if err != nil {
+		return err
+	}
+	if !watchlist.Watched(validator.Index, validator.Validator.PublicKey) {
+		return fmt.Errorf("validator %s is not in the watchlist", validatorID)
+	}
+
+	event, err := drillEvent(ctx, eth2Client, chainTime, validator, slashings.Type(offence))
+	if err != nil {
+		return err
+	}
+	report := &drillReport{
+		Event: event,
+		Steps: make([]*drillStep, 0),
+	}
+
+	penalties, err := startPenalties(ctx, eth2Client, viper.GetBool("slashings.estimate-penalties"))
+	if err != nil {
+		return err
+	}
+	if penalties != nil {
+		res, err := penalties.Estimate(ctx, "head", []phase0.ValidatorIndex{event.ValidatorIndex})
+		if err == nil {
+			event.Penalty = res[event.ValidatorIndex]
+		}
+		report.Steps = append(report.Steps, newDrillStep("penalty-estimate", "", err))
+	}
+
+	handlers, err := startNotifiers(ctx)
+	if err != nil {
+		return err
+	}
+
+	controls, err := startControls(ctx)
+	if err != nil {
+		return err
+	}
+
+	slashingsSvc, err := headslashings.New(ctx,
+		headslashings.WithLogLevel(util.LogLevel("slashings")),
+		headslashings.WithETH2Client(eth2Client),
+		headslashings.WithChainTime(chainTime),
+		headslashings.WithAttesterSlashedScript(viper.GetString("slashings.attester-slashed-script")),
+		headslashings.WithProposerSlashedScript(viper.GetString("slashings.proposer-slashed-script")),
+		headslashings.WithBatchScript(viper.GetString("slashings.batch-script")),
+		headslashings.WithScriptTimeout(viper.GetDuration("scripts.timeout")),
+		headslashings.WithMassSlashingWindow(viper.GetUint64("slashings.mass.window-epochs")),
+		headslashings.WithMassSlashingCount(viper.GetUint64("slashings.mass.count")),
+		headslashings.WithMassSlashingBalance(phase0.Gwei(viper.GetUint64("slashings.mass.balance"))),
+		headslashings.WithMassSlashingScript(viper.GetString("slashings.mass.script")),
+		headslashings.WithPenalties(penalties),
+		headslashings.WithHandlers(handlers),
+		headslashings.WithControls(controls),
+		headslashings.WithDrill(true),
+		headslashings.WithDrillScripts(runScripts),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create slashings service")
+	}
+	report.addResults(slashingsSvc.Drill(ctx, event))
+
+	lifecycle, err := startLifecycle(ctx, eth2Client, nil, chainTime, watchlist, controls, handlers,
+		standardlifecycle.WithDrill(true),
+		standardlifecycle.WithDrillScripts(runScripts),
+	)
+	if err != nil {
+		return err
+	}
+	report.addResults(lifecycle.Drill(ctx, event))
+
+	if cmd.json {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	} else if err := printDrillReport(report); err != nil {
+		return err
+	}
+
+	if len(report.Steps) == 0 {
+		return errors.New("nothing to drill; no scripts or notifiers are configured")
+	}
+	failed := 0
+	for _, step := range report.Steps {
+		if !step.Success && !step.Skipped {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d drill steps failed", failed, len(report.Steps))
+	}
+
+	return nil
+}
+
+// drillValidator obtains the validator with the given index or public key from the
+// beacon node.
+func drillValidator(ctx context.Context, eth2Client eth2client.Service, validatorID string) (*apiv1.Validator, error) {
+	provider, isProvider := eth2Client.(eth2client.ValidatorsProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide validators")
+	}
+
+	opts := &api.ValidatorsOpts{
+		State: "head",
+	}
+	if strings.HasPrefix(validatorID, "0x") {
+		data, err := hex.DecodeString(strings.TrimPrefix(validatorID, "0x"))
+		if err != nil || len(data) != phase0.PublicKeyLength {
+			return nil, newUsageError("invalid validator public key %q", validatorID)
+		}
+		var pubKey phase0.BLSPubKey
+		copy(pubKey[:], data)
+		opts.PubKeys = []phase0.BLSPubKey{pubKey}
+	} else {
+		index, err := strconv.ParseUint(validatorID, 10, 64)
+		if err != nil {
+			return nil, newUsageError("invalid validator %q; must be an index or a public key", validatorID)
+		}
+		opts.Indices = []phase0.ValidatorIndex{phase0.ValidatorIndex(index)}
+	}
+
+	response, err := provider.Validators(ctx, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain validator")
+	}
+	for _, validator := range response.Data {
+		return validator, nil
+	}
+
+	return nil, fmt.Errorf("validator %s not found", validatorID)
+}
+
+// drillEvent creates a synthetic slashing event for the validator, included in the
+// current head block, with a single offence of the given type.
+func drillEvent(ctx context.Context,
+	eth2Client eth2client.Service,
+	chainTime chaintime.Service,
+	validator *apiv1.Validator,
+	offenceType slashings.Type,
+) (
+	*slashings.SlashingEvent,
+	error,
+) {
+	provider, isProvider := eth2Client.(eth2client.BeaconBlockHeadersProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide block headers")
+	}
+	response, err := provider.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+		Block: "head",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain head block header")
+	}
+	head := response.Data
+	if head.Header == nil || head.Header.Message == nil {
+		return nil, errors.New("head block header missing")
+	}
+
+	var offence *slashings.Offence
+	if offenceType == slashings.TypeProposer {
+		offence, err = drillProposerOffence(head, validator.Index)
+	} else {
+		offence, err = drillAttesterOffence(head, chainTime.SlotToEpoch(head.Header.Message.Slot), validator.Index)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &slashings.SlashingEvent{
+		ValidatorIndex:   validator.Index,
+		PubKey:           validator.Validator.PublicKey,
+		EffectiveBalance: validator.Validator.EffectiveBalance,
+		Offences:         []*slashings.Offence{offence},
+		Slot:             head.Header.Message.Slot,
+		BlockRoot:        head.Root,
+		ProposerIndex:    head.Header.Message.ProposerIndex,
+		Source:           slashings.SourceDrill,
+		Status:           slashings.StatusPending,
+	}, nil
+}
+
+// drillAttesterOffence creates a synthetic double vote by the validator, for the
+// epoch of the head block.
+func drillAttesterOffence(head *apiv1.BeaconBlockHeader,
+	target phase0.Epoch,
+	index phase0.ValidatorIndex,
+) (
+	*slashings.Offence,
+	error,
+) {
+	slot := head.Header.Message.Slot
+	source := target
+	if source > 0 {
+		source--
+	}
+
+	attestation := func(root phase0.Root) *phase0.IndexedAttestation {
+		return &phase0.IndexedAttestation{
+			AttestingIndices: []uint64{uint64(index)},
+			Data: &phase0.AttestationData{
+				Slot:            slot,
+				BeaconBlockRoot: root,
+				Source:          &phase0.Checkpoint{Epoch: source},
+				Target:          &phase0.Checkpoint{Epoch: target, Root: root},
+			},
+		}
+	}
+	slashing := &phase0.AttesterSlashing{
+		Attestation1: attestation(head.Root),
+		Attestation2: attestation(head.Header.Message.ParentRoot),
+	}
+	evidenceHash, err := slashing.HashTreeRoot()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to calculate evidence hash")
+	}
+
+	return &slashings.Offence{
+		Kind:         slashings.OffenceDoubleVote,
+		EvidenceHash: evidenceHash,
+		Attestation1: slashing.Attestation1,
+		Attestation2: slashing.Attestation2,
+	}, nil
+}
+
+// drillProposerOffence creates a synthetic double proposal by the validator, for the
+// slot of the head block.
+func drillProposerOffence(head *apiv1.BeaconBlockHeader, index phase0.ValidatorIndex) (*slashings.Offence, error) {
+	header := func(bodyRoot phase0.Root) *phase0.SignedBeaconBlockHeader {
+		return &phase0.SignedBeaconBlockHeader{
+			Message: &phase0.BeaconBlockHeader{
+				Slot:          head.Header.Message.Slot,
+				ProposerIndex: index,
+				ParentRoot:    head.Header.Message.ParentRoot,
+				StateRoot:     head.Header.Message.StateRoot,
+				BodyRoot:      bodyRoot,
+			},
+		}
+	}
+	slashing := &phase0.ProposerSlashing{
+		SignedHeader1: header(head.Header.Message.BodyRoot),
+		SignedHeader2: header(head.Root),
+	}
+	evidenceHash, err := slashing.HashTreeRoot()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to calculate evidence hash")
+	}
+
+	return &slashings.Offence{
+		Kind:         slashings.OffenceDoubleProposal,
+		EvidenceHash: evidenceHash,
+		Header1:      slashing.SignedHeader1,
+		Header2:      slashing.SignedHeader2,
+	}, nil
+}
+
+// printDrillReport prints a drill report as text.
+func printDrillReport(report *drillReport) error {
+	event := report.Event
+	fmt.Fprintf(os.Stdout, "Drill for validator %d (%#x) at slot %d: %s\n",
+		event.ValidatorIndex, event.PubKey, event.Slot, offencesText(event.Offences))
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, step := range report.Steps {
+		result := "PASS"
+		switch {
+		case step.Skipped:
+			result = "SKIP"
+		case !step.Success:
+			result = "FAIL"
+		}
+		line := fmt.Sprintf("%s\t%s\t%s", result, step.Step, step.Target)
+		if step.Error != "" {
+			line = fmt.Sprintf("%s\t%s", line, step.Error)
+		}
+		fmt.Fprintln(writer, line)
+	}
+	if err := writer.Flush(); err != nil {
+		return errors.Wrap(err, "failed to print drill report")
+	}
+	for _, step := range report.Steps {
+		if !step.Success && step.Output != "" {
+			fmt.Fprintf(os.Stdout, "Output of %s:\n%s\n", step.Step, step.Output)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	historysvc "github.com/attestantio/esd/services/history"
+	standardhistory "github.com/attestantio/esd/services/history/standard"
+	"github.com/pkg/errors"
+)
+
+// historyReader reads the slashing history.
+type historyReader interface {
+	// Slashing fetches a single slashing by its ID.
+	Slashing(ctx context.Context, id string) (*historysvc.Slashing, error)
+
+	// Slashings fetches all slashings, most recent first.
+	Slashings(ctx context.Context, filter *historysvc.Filter) ([]*historysvc.Slashing, error)
+}
+
+// openHistory opens the history database for reading.  The database can only be opened
+// by one process at a time, so if esd is running the history is read from its API.
+func openHistory(ctx context.Context) (historyReader, error) {
+	history, err := startHistory(ctx, true)
+	if err == nil {
+		return history, nil
+	}
+	if !errors.Is(err, standardhistory.ErrLocked) {
+		return nil, err
+	}
+
+	base, urlErr := apiURL()
+	if urlErr != nil {
+		return nil, errors.Wrap(err, "cannot read history from the API of the running esd either")
+	}
+
+	return &apiHistory{base: base}, nil
+}
+
+// apiHistory reads the slashing history from the API of a running esd.
+type apiHistory struct {
+	base string
+}
+
+// Slashing fetches a single slashing by its ID.
+func (h *apiHistory) Slashing(ctx context.Context, id string) (*historysvc.Slashing, error) {
+	res := &historysvc.Slashing{}
+	if err := h.get(ctx, fmt.Sprintf("/v1/slashings/%s", url.PathEscape(id)), res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Slashings fetches all slashings, most recent first.  Only the limit of the filter is used.
+func (h *apiHistory) Slashings(ctx context.Context, filter *historysvc.Filter) ([]*historysvc.Slashing, error) {
+	limit := math.MaxInt32
+	if filter != nil && filter.Limit > 0 {
+		limit = filter.Limit
+	}
+
+	res := make([]*historysvc.Slashing, 0)
+	if err := h.get(ctx, fmt.Sprintf("/v1/slashings?limit=%d", limit), &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// get calls the API, decoding the response into res.
+func (h *apiHistory) get(ctx context.Context, path string, res any) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.base+path, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to call API of running esd")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && strings.HasPrefix(path, "/v1/slashings/") {
+		return historysvc.ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return errors.Wrap(err, "failed to decode response")
+	}
+
+	return nil
+}
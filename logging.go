@@ -26,6 +26,9 @@ import (
 // log.
 var log zerolog.Logger
 
+// logLevel is the level of the local logger, which can be changed on reload.
+var logLevel *util.LevelFilter
+
 // initLogging initialises logging.
 func initLogging() error {
 	// We set the global logging level to trace, because if the global log level is higher than the
@@ -41,8 +44,55 @@ func initLogging() error {
 		zerologger.Logger = zerologger.Logger.Output(f)
 	}
 
-	// Set the local logger from the global logger.
-	log = zerologger.Logger.With().Logger().Level(util.LogLevel(""))
+	// Set the local logger from the global logger.  The level is applied by a filter so
+	// that it can be changed on reload.
+	logLevel = util.NewLevelFilter(util.LogLevel(""))
+	log = zerologger.Logger.With().Logger().Level(zerolog.TraceLevel).Hook(logLevel)
 
 	return nil
 }
+
+// logLevelSetter is a service whose log level can be changed while it is running.
+type logLevelSetter interface {
+	SetLogLevel(level zerolog.Level)
+}
+
+// reloadableLogLevels are the services whose log levels are changed on reload, by module.
+var reloadableLogLevels = make(map[string]logLevelSetter)
+
+// restartLogModules are the modules whose log levels are only applied on startup.
+var restartLogModules = []string{
+	"penalties",
+	"notifiers.webhook",
+	"watchlist",
+	"chaintime",
+	"deadletter",
+	"metrics.prometheus",
+	"majordomo",
+}
+
+// registerLogLevel records a service whose log level can be changed on reload.
+func registerLogLevel(module string, service any) {
+	if setter, isSetter := service.(logLevelSetter); isSetter {
+		reloadableLogLevels[module] = setter
+	}
+}
+
+// reloadLogLevels applies the configured log levels to the running services, and reports
+// those modules whose configured levels cannot be applied until restart.
+func reloadLogLevels() {
+	logLevel.SetLevel(util.LogLevel(""))
+	for module, setter := range reloadableLogLevels {
+		setter.SetLogLevel(util.LogLevel(module))
+	}
+
+	restartModules := make([]string, 0)
+	for _, module := range restartLogModules {
+		if viper.IsSet(module + ".log-level") {
+			restartModules = append(restartModules, module)
+		}
+	}
+	if len(restartModules) > 0 {
+		log.Info().Strs("modules", restartModules).Msg("Log levels of these modules are not reloadable; restart to apply them")
+	}
+}
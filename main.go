@@ -25,15 +25,42 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
+	restapi "github.com/attestantio/esd/services/api/rest"
+	"github.com/attestantio/esd/services/chaintime"
+	standardchaintime "github.com/attestantio/esd/services/chaintime/standard"
+	controlssvc "github.com/attestantio/esd/services/controls"
+	filecontrols "github.com/attestantio/esd/services/controls/file"
+	"github.com/attestantio/esd/services/deadletter"
+	filedeadletter "github.com/attestantio/esd/services/deadletter/file"
+	healthsvc "github.com/attestantio/esd/services/health"
+	standardhealth "github.com/attestantio/esd/services/health/standard"
+	historysvc "github.com/attestantio/esd/services/history"
+	standardhistory "github.com/attestantio/esd/services/history/standard"
+	ledgersvc "github.com/attestantio/esd/services/ledger"
+	standardledger "github.com/attestantio/esd/services/ledger/standard"
+	lifecyclesvc "github.com/attestantio/esd/services/lifecycle"
+	standardlifecycle "github.com/attestantio/esd/services/lifecycle/standard"
 	"github.com/attestantio/esd/services/metrics"
 	nullmetrics "github.com/attestantio/esd/services/metrics/null"
 	prometheusmetrics "github.com/attestantio/esd/services/metrics/prometheus"
+	webhooknotifier "github.com/attestantio/esd/services/notifiers/webhook"
+	penaltiessvc "github.com/attestantio/esd/services/penalties"
+	standardpenalties "github.com/attestantio/esd/services/penalties/standard"
+	"github.com/attestantio/esd/services/slashings"
 	headslashings "github.com/attestantio/esd/services/slashings/head"
+	streamsvc "github.com/attestantio/esd/services/stream"
+	memorystream "github.com/attestantio/esd/services/stream/memory"
+	watchlistsvc "github.com/attestantio/esd/services/watchlist"
+	staticwatchlist "github.com/attestantio/esd/services/watchlist/static"
 	"github.com/attestantio/esd/util"
+	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
@@ -60,23 +87,28 @@ func main2() int {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	if err := fetchConfig(); err != nil {
-		zerologger.Error().Err(err).Msg("Failed to fetch configuration")
-		return 1
+	cmd, err := fetchConfig()
+	if err != nil {
+		var usageErr *usageError
+		switch {
+		case errors.Is(err, pflag.ErrHelp):
+			return exitSuccess
+		case errors.As(err, &usageErr):
+			printUsageError(err)
+			return exitUsage
+		default:
+			zerologger.Error().Err(err).Msg("Failed to fetch configuration")
+			return exitFailure
+		}
 	}
 
 	if err := initLogging(); err != nil {
 		log.Error().Err(err).Msg("Failed to initialise logging")
-		return 1
+		return exitFailure
 	}
 
-	exit, err := runCommands(ctx)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to run command")
-		return 1
-	}
-	if exit {
-		return 0
+	if cmd.name != "run" {
+		return runCommand(ctx, cmd)
 	}
 
 	logModules()
@@ -88,14 +120,14 @@ func main2() int {
 
 	if err := e2types.InitBLS(); err != nil {
 		log.Error().Err(err).Msg("Failed to initialise BLS library")
-		return 1
+		return exitFailure
 	}
 
 	log.Trace().Msg("Starting metrics service")
 	monitor, err := startMonitor(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to start metrics service")
-		return 1
+		return exitFailure
 	}
 	if err := registerMetrics(ctx, monitor); err != nil {
 		log.Error().Err(err).Msg("Failed to register metrics")
@@ -106,49 +138,77 @@ func main2() int {
 	majordomo, err := initMajordomo(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to initialise majordomo")
-		return 1
+		return exitFailure
 	}
 
-	if err := startServices(ctx, monitor, majordomo); err != nil {
+	// Readiness is set by the services as they determine the state of the beacon node.
+	services, err := startServices(ctx, monitor, majordomo)
+	if err != nil {
 		log.Error().Err(err).Msg("Failed to initialise services")
-		return 1
+		return exitFailure
 	}
-	setReady(ctx, true)
 
 	log.Info().Msg("All services operational")
 
 	// Wait for signal.
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, os.Interrupt)
 	for {
 		sig := <-sigCh
+		if sig == syscall.SIGHUP {
+			reloadConfig(ctx, services)
+			continue
+		}
 		if sig == syscall.SIGINT || sig == syscall.SIGTERM || sig == os.Interrupt || sig == os.Kill {
 			break
 		}
 	}
 
 	log.Info().Msg("Stopping ESD")
+	if err := notifySystemd("STOPPING=1"); err != nil {
+		log.Warn().Err(err).Msg("Failed to notify systemd of shutdown")
+	}
+	if err := drain(ctx, services.slashings, sigCh); err != nil {
+		log.Error().Err(err).Msg("Failed to stop cleanly")
+		return exitFailure
+	}
+	log.Info().Msg("Stopped ESD")
 
-	return 0
+	return exitSuccess
 }
 
-// fetchConfig fetches configuration from various sources.
-func fetchConfig() error {
-	pflag.String("base-dir", "", "base directory for configuration files")
-	pflag.Bool("version", false, "show version and exit")
-	pflag.String("log-level", "info", "minimum level of messsages to log")
-	pflag.String("log-file", "", "redirect log output to a file")
-	pflag.String("profile-address", "", "Address on which to run Go profile server")
-	pflag.String("tracing-address", "", "Address to which to send tracing data")
-	pflag.String("eth2client.address", "", "Address for beacon node")
-	pflag.Duration("eth2client.timeout", 2*time.Minute, "Timeout for beacon node requests")
-	pflag.String("slashings.attester-slashed-script", "", "Script to run when attester is slashed")
-	pflag.String("slashings.proposer-slashed-script", "", "Script to run when proposer is slashed")
-	pflag.Bool("test-scripts", false, "Test scripts using validator index 12345678 and exit")
-	pflag.String("test-block", "", "Test scripts using supplied block and exit")
-	pflag.Parse()
+// drain drains the slashings service, until the drain timeout passes or a further
+// signal is received.
+func drain(ctx context.Context, slashingsSvc *headslashings.Service, sigCh <-chan os.Signal) error {
+	drainCtx, cancel := context.WithTimeout(ctx, viper.GetDuration("shutdown.drain-timeout"))
+	defer cancel()
+
+	go func() {
+		select {
+		case <-sigCh:
+			log.Warn().Msg("Received further signal; abandoning drain")
+			cancel()
+		case <-drainCtx.Done():
+		}
+	}()
+
+	return slashingsSvc.Drain(drainCtx)
+}
+
+// fetchConfig fetches configuration from various sources, returning the command to run.
+func fetchConfig() (*command, error) {
+	if err := addGlobalFlags(); err != nil {
+		return nil, err
+	}
+	cmd, err := parseCommandLine(os.Args[1:])
+	if err != nil {
+		return nil, err
+	}
 	if err := viper.BindPFlags(pflag.CommandLine); err != nil {
-		return errors.Wrap(err, "failed to bind pflags to viper")
+		return nil, errors.Wrap(err, "failed to bind pflags to viper")
+	}
+	if cmd.noConfig {
+		return cmd, nil
 	}
 
 	if viper.GetString("base-dir") != "" {
@@ -159,7 +219,7 @@ func fetchConfig() error {
 		// Home directory.
 		home, err := homedir.Dir()
 		if err != nil {
-			return errors.Wrap(err, "failed to obtain home directory")
+			return nil, errors.Wrap(err, "failed to obtain home directory")
 		}
 		viper.AddConfigPath(home)
 		viper.SetConfigName(".esd")
@@ -167,14 +227,86 @@ func fetchConfig() error {
 
 	// Environment settings.
 	viper.SetEnvPrefix("ESD")
-	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_", ".", "_"))
+	viper.SetEnvKeyReplacer(envKeyReplacer)
 	viper.AutomaticEnv()
 
 	if err := viper.ReadInConfig(); err != nil {
 		if !errors.Is(err, &viper.ConfigFileNotFoundError{}) {
-			return errors.Wrap(err, "failed to read configuration file")
+			return nil, errors.Wrap(err, "failed to read configuration file")
 		}
 	}
+	// Keep the configuration as read, so that it can be restored if a reload fails.
+	if viper.ConfigFileUsed() != "" {
+		appliedConfig, err = os.ReadFile(viper.ConfigFileUsed())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read configuration file")
+		}
+	}
+
+	return cmd, nil
+}
+
+// addGlobalFlags adds the global flags, which hold the configuration.
+func addGlobalFlags() error {
+	pflag.String("base-dir", "", "base directory for configuration files")
+	pflag.Bool("version", false, "show version and exit")
+	if err := pflag.CommandLine.MarkDeprecated("version", "use 'esd version' instead"); err != nil {
+		return errors.Wrap(err, "failed to deprecate flag")
+	}
+	pflag.String("log-level", "info", "minimum level of messsages to log")
+	pflag.String("log-file", "", "redirect log output to a file")
+	pflag.String("profile-address", "", "Address on which to run Go profile server")
+	pflag.String("tracing-address", "", "Address to which to send tracing data")
+	pflag.String("eth2client.address", "", "Address for beacon node")
+	pflag.Duration("eth2client.timeout", 2*time.Minute, "Timeout for beacon node requests")
+	pflag.String("eth2client.network", "", "Name of the network that the beacon node must be on, for example mainnet (not checked if not set)")
+	pflag.String("slashings.attester-slashed-script", "", "Script to run when attester is slashed")
+	pflag.String("slashings.proposer-slashed-script", "", "Script to run when proposer is slashed")
+	pflag.String("slashings.batch-script", "", "Script to run once per block with all slashed validators")
+	pflag.Duration("scripts.timeout", 5*time.Minute, "Time after which a script that has not finished is killed (0 for no timeout)")
+	pflag.Bool("slashings.estimate-penalties", false, "Estimate the penalties for slashed validators")
+	pflag.Bool("slashings.verify", false, "Verify the evidence for slashings before running scripts")
+	pflag.Uint64("slashings.mass.window-epochs", 8, "Number of epochs over which slashings are counted towards a mass slashing")
+	pflag.Uint64("slashings.mass.count", 0, "Number of slashed validators in the window that constitutes a mass slashing (0 to disable)")
+	pflag.Uint64("slashings.mass.balance", 0, "Total effective balance in Gwei of slashed validators in the window that constitutes a mass slashing (0 to disable)")
+	pflag.String("slashings.mass.script", "", "Script to run when a mass slashing is detected")
+	pflag.Duration("slashings.sync-check-interval", 12*time.Second, "Interval between checks of the beacon node sync status")
+	pflag.Bool("slashings.notify-on-hold", true, "Report slashings from optimistic blocks while their scripts are held")
+	pflag.Uint64("slashings.stale-slots", 4, "Number of slots without a head event before resubscribing to the event stream")
+	pflag.Int("slashings.fetch-workers", 4, "Number of workers fetching blocks concurrently")
+	pflag.Int("slashings.action-workers", 4, "Number of workers running scripts concurrently")
+	pflag.Int("slashings.queue-size", 64, "Size of each queue in the processing pipeline")
+	pflag.Int("slashings.fetch-retries", 5, "Number of times to retry fetching a block before recording it as failed")
+	pflag.Duration("slashings.fetch-retry-delay", time.Second, "Initial delay before retrying to fetch a block")
+	pflag.String("slashings.checkpoint-file", "checkpoint.json", "File in which to record the slot from which to resume after a graceful shutdown")
+	pflag.Duration("shutdown.drain-timeout", 30*time.Second, "Time to wait on shutdown for blocks being processed and their actions to finish")
+	pflag.String("slashings.failed-blocks-file", "failed-blocks.json", "File in which to record blocks that could not be fetched")
+	pflag.String("history.path", "history.db", "Database in which to record the history of slashings")
+	pflag.String("ledger.path", "ledger.db", "Database in which to record the actions run for slashings")
+	pflag.StringSlice("watchlist.validators", nil, "Indices or public keys of validators to watch (default all)")
+	pflag.String("lifecycle.path", "lifecycle.json", "File in which to record slashed validators being tracked through their lifecycle")
+	pflag.String("lifecycle.exited-script", "", "Script to run when a slashed validator exits")
+	pflag.String("lifecycle.correlation-penalty-script", "", "Script to run when the correlation penalty is applied to a slashed validator")
+	pflag.String("lifecycle.withdrawable-script", "", "Script to run when a slashed validator becomes withdrawable")
+	pflag.String("lifecycle.withdrawn-script", "", "Script to run when the funds of a slashed validator have been withdrawn")
+	pflag.String("api.listen-address", "", "Address on which to serve the REST API (disabled if not set)")
+	pflag.Duration("health.liveness-timeout", 5*time.Minute, "Time for which blocks can be pending without being processed before esd is not live")
+	pflag.Int("api.events.buffer-size", 1024, "Number of recent events held for replay by the event stream")
+	pflag.String("api.admin-token", "", "Majordomo URL of the bearer token for the admin API (disabled if not set)")
+	pflag.String("controls.path", "controls.json", "File in which to record the operator controls")
+	pflag.String("admin.url", "", "URL of the API for admin commands (default from api.listen-address)")
+	pflag.String("admin.actor", "", "Name recorded in the audit log for admin commands (default the current user)")
+	pflag.String("admin.reason", "", "Reason recorded in the audit log for admin commands")
+	pflag.StringSlice("notifiers.webhook.urls", nil, "URLs to which to post slashing events")
+	pflag.Duration("notifiers.webhook.timeout", 5*time.Second, "Timeout for posting slashing events to webhooks")
+	pflag.Bool("test-scripts", false, "Test scripts using validator index 12345678 and exit")
+	if err := pflag.CommandLine.MarkDeprecated("test-scripts", "use 'esd test' instead"); err != nil {
+		return errors.Wrap(err, "failed to deprecate flag")
+	}
+	pflag.String("test-block", "", "Test scripts using supplied block and exit")
+	if err := pflag.CommandLine.MarkDeprecated("test-block", "use 'esd scan <block>' instead"); err != nil {
+		return errors.Wrap(err, "failed to deprecate flag")
+	}
 
 	return nil
 }
@@ -197,102 +329,606 @@ func initProfiling() {
 	}
 }
 
-func startServices(ctx context.Context, monitor metrics.Service, _ majordomo.Service) error {
+// startServices starts the services, returning those that can be reloaded or drained
+// while running.
+func startServices(ctx context.Context,
+	monitor metrics.Service,
+	majordomo majordomo.Service,
+) (
+	*runningServices,
+	error,
+) {
 	log.Trace().Msg("Starting Ethereum 2 client service")
 	eth2Client, err := fetchClient(ctx, viper.GetString("eth2client.address"))
 	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("failed to fetch client %q", viper.GetString("eth2client.address")))
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to fetch client %q", maskURL(viper.GetString("eth2client.address"))))
+	}
+	if _, err := checkNetwork(ctx, eth2Client); err != nil {
+		return nil, err
 	}
 
-	_, err = headslashings.New(ctx,
+	chainTime, err := startChainTime(ctx, eth2Client)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetter, err := startDeadLetter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := startHistory(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := startLedger(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	handlers, err := startNotifiers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	penalties, err := startPenalties(ctx, eth2Client, viper.GetBool("slashings.estimate-penalties"))
+	if err != nil {
+		return nil, err
+	}
+
+	watchlist, err := startWatchlist(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	controls, err := startControls(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := startStream(ctx, monitor)
+	if err != nil {
+		return nil, err
+	}
+	if stream != nil {
+		// The event stream is informed of slashings and lifecycle events alongside the notifiers.
+		handlers = append(handlers, stream)
+	}
+
+	lifecycle, err := startLifecycle(ctx, eth2Client, monitor, chainTime, watchlist, controls, handlers)
+	if err != nil {
+		return nil, err
+	}
+	// The lifecycle service is informed of slashings alongside the notifiers.
+	handlers = append(handlers, lifecycle)
+
+	slashingsSvc, err := headslashings.New(ctx,
 		headslashings.WithLogLevel(util.LogLevel("slashings")),
 		headslashings.WithMonitor(monitor),
+		headslashings.WithDeadLetter(deadLetter),
+		headslashings.WithHistory(history),
+		headslashings.WithLedger(ledger),
 		headslashings.WithETH2Client(eth2Client),
+		headslashings.WithChainTime(chainTime),
 		headslashings.WithAttesterSlashedScript(viper.GetString("slashings.attester-slashed-script")),
 		headslashings.WithProposerSlashedScript(viper.GetString("slashings.proposer-slashed-script")),
+		headslashings.WithBatchScript(viper.GetString("slashings.batch-script")),
+		headslashings.WithScriptTimeout(viper.GetDuration("scripts.timeout")),
+		headslashings.WithHandlers(handlers),
+		headslashings.WithVerify(viper.GetBool("slashings.verify")),
+		headslashings.WithPenalties(penalties),
+		headslashings.WithControls(controls),
+		headslashings.WithMassSlashingWindow(viper.GetUint64("slashings.mass.window-epochs")),
+		headslashings.WithMassSlashingCount(viper.GetUint64("slashings.mass.count")),
+		headslashings.WithMassSlashingBalance(phase0.Gwei(viper.GetUint64("slashings.mass.balance"))),
+		headslashings.WithMassSlashingScript(viper.GetString("slashings.mass.script")),
+		headslashings.WithSyncCheckInterval(viper.GetDuration("slashings.sync-check-interval")),
+		headslashings.WithNotifyOnHold(viper.GetBool("slashings.notify-on-hold")),
+		headslashings.WithReadinessHandler(setReady),
+		headslashings.WithStaleSlots(viper.GetUint64("slashings.stale-slots")),
+		headslashings.WithFetchWorkers(viper.GetInt("slashings.fetch-workers")),
+		headslashings.WithActionWorkers(viper.GetInt("slashings.action-workers")),
+		headslashings.WithQueueSize(viper.GetInt("slashings.queue-size")),
+		headslashings.WithFetchRetries(viper.GetInt("slashings.fetch-retries")),
+		headslashings.WithFetchRetryDelay(viper.GetDuration("slashings.fetch-retry-delay")),
+		headslashings.WithCheckpointFile(resolvePath(viper.GetString("slashings.checkpoint-file"))),
 	)
 	if err != nil {
-		return errors.Wrap(err, "failed to create slashings service")
+		return nil, errors.Wrap(err, "failed to create slashings service")
 	}
 
-	return nil
-}
-
-// runCommands returns true if it ran a command and requests exit.
-func runCommands(ctx context.Context) (bool, error) {
-	if viper.GetBool("version") {
-		fmt.Printf("%s\n", ReleaseVersion)
-		return true, nil
+	health, err := startHealth(ctx, slashingsSvc)
+	if err != nil {
+		return nil, err
 	}
 
-	if viper.GetBool("test-scripts") {
-		return runTestScripts(ctx)
+	api, err := startAPI(ctx, majordomo, chainTime, history, slashingsSvc, watchlist, stream, health, controls)
+	if err != nil {
+		return nil, err
 	}
 
-	if viper.GetString("test-block") != "" {
-		return runTestBlock(ctx)
-	}
+	startSystemd(ctx, slashingsSvc, health)
+
+	return &runningServices{
+		majordomo: majordomo,
+		slashings: slashingsSvc,
+		lifecycle: lifecycle,
+		watchlist: watchlist,
+		stream:    stream,
+		api:       api,
+	}, nil
+}
 
-	return false, nil
+// testResult is the result of testing a script.
+type testResult struct {
+	Key     string `json:"key"`
+	Script  string `json:"script"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
 }
 
-func runTestScripts(ctx context.Context) (bool, error) {
+// runTestScripts runs the attester and proposer slashing scripts for a validator index.
+func runTestScripts(ctx context.Context, cmd *command) error {
+	index, err := cmd.flags.GetUint64("validator-index")
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain validator index")
+	}
+
 	eth2Client, err := fetchClient(ctx, viper.GetString("eth2client.address"))
 	if err != nil {
-		return false, errors.Wrap(err, fmt.Sprintf("failed to fetch client %q", viper.GetString("eth2client.address")))
+		return errors.Wrap(err, fmt.Sprintf("failed to fetch client %q", maskURL(viper.GetString("eth2client.address"))))
+	}
+
+	chainTime, err := startChainTime(ctx, eth2Client)
+	if err != nil {
+		return err
 	}
 
-	slashings, err := headslashings.New(ctx,
+	slashingsSvc, err := headslashings.New(ctx,
 		headslashings.WithLogLevel(util.LogLevel("slashings")),
 		headslashings.WithETH2Client(eth2Client),
+		headslashings.WithChainTime(chainTime),
 		headslashings.WithAttesterSlashedScript(viper.GetString("slashings.attester-slashed-script")),
 		headslashings.WithProposerSlashedScript(viper.GetString("slashings.proposer-slashed-script")),
 	)
 	if err != nil {
-		return false, errors.Wrap(err, "failed to create slashings service")
+		return errors.Wrap(err, "failed to create slashings service")
 	}
 
-	if viper.GetString("slashings.attester-slashed-script") != "" {
-		fmt.Fprintf(os.Stdout, "Testing attester slashing script with validator index 12345678\n")
-		if err := slashings.OnAttesterSlashed(ctx, 12345678); err != nil {
-			fmt.Fprintf(os.Stdout, "Attester slashing script failed: %v\n", err)
-			return true, nil
+	tests := []struct {
+		action string
+		key    string
+		run    func(ctx context.Context, index phase0.ValidatorIndex) error
+	}{
+		{
+			action: "Attester slashing",
+			key:    "slashings.attester-slashed-script",
+			run:    slashingsSvc.OnAttesterSlashed,
+		},
+		{
+			action: "Proposer slashing",
+			key:    "slashings.proposer-slashed-script",
+			run:    slashingsSvc.OnProposerSlashed,
+		},
+	}
+	results := make([]*testResult, 0, len(tests))
+	failed := 0
+	for _, test := range tests {
+		script := viper.GetString(test.key)
+		if script == "" {
+			if !cmd.json {
+				fmt.Fprintf(os.Stdout, "No %s script\n", strings.ToLower(test.action))
+			}
+			continue
 		}
-	} else {
-		fmt.Println("No attester slashing script")
+		if !cmd.json {
+			fmt.Fprintf(os.Stdout, "Testing %s script with validator index %d\n", strings.ToLower(test.action), index)
+		}
+		result := &testResult{
+			Key:     test.key,
+			Script:  script,
+			Success: true,
+		}
+		if err := test.run(ctx, phase0.ValidatorIndex(index)); err != nil {
+			failed++
+			result.Success = false
+			result.Error = err.Error()
+			if !cmd.json {
+				fmt.Fprintf(os.Stdout, "%s script failed: %v\n", test.action, err)
+			}
+		}
+		results = append(results, result)
 	}
 
-	if viper.GetString("slashings.proposer-slashed-script") != "" {
-		fmt.Fprintf(os.Stdout, "Testing proposer slashing script with validator index 12345678\n")
-		if err := slashings.OnProposerSlashed(ctx, 12345678); err != nil {
-			fmt.Fprintf(os.Stdout, "Proposer slashing script failed: %v\n", err)
-			return true, nil
+	if cmd.json {
+		if err := printJSON(results); err != nil {
+			return err
 		}
-	} else {
-		fmt.Fprintf(os.Stdout, "No proposer slashing script\n")
 	}
+	if failed > 0 {
+		return fmt.Errorf("%d scripts failed", failed)
+	}
+
+	return nil
+}
 
-	return true, nil
+// scanResult is the result of scanning a block.
+type scanResult struct {
+	Block     string                     `json:"block"`
+	Slashings []*slashings.SlashingEvent `json:"slashings"`
 }
 
-func runTestBlock(ctx context.Context) (bool, error) {
+// scanCollector collects the slashings found when scanning a block.
+type scanCollector struct {
+	events []*slashings.SlashingEvent
+}
+
+// OnSlashing collects a slashing event.
+func (c *scanCollector) OnSlashing(_ context.Context, event *slashings.SlashingEvent) error {
+	c.events = append(c.events, event)
+
+	return nil
+}
+
+// runScan processes blocks, showing the slashings that they contain and optionally running
+// their scripts and notifiers.
+func runScan(ctx context.Context, cmd *command) error {
 	eth2Client, err := fetchClient(ctx, viper.GetString("eth2client.address"))
 	if err != nil {
-		return false, errors.Wrap(err, fmt.Sprintf("failed to fetch client %q", viper.GetString("eth2client.address")))
+		return errors.Wrap(err, fmt.Sprintf("failed to fetch client %q", maskURL(viper.GetString("eth2client.address"))))
+	}
+
+	chainTime, err := startChainTime(ctx, eth2Client)
+	if err != nil {
+		return err
+	}
+
+	penalties, err := startPenalties(ctx, eth2Client, viper.GetBool("slashings.estimate-penalties"))
+	if err != nil {
+		return err
+	}
+
+	controls, err := startControls(ctx)
+	if err != nil {
+		return err
+	}
+
+	runScripts, err := cmd.flags.GetBool("run-scripts")
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain run scripts")
+	}
+	params := []headslashings.Parameter{
+		headslashings.WithLogLevel(util.LogLevel("slashings")),
+		headslashings.WithETH2Client(eth2Client),
+		headslashings.WithChainTime(chainTime),
+		headslashings.WithScriptTimeout(viper.GetDuration("scripts.timeout")),
+		headslashings.WithVerify(viper.GetBool("slashings.verify")),
+		headslashings.WithPenalties(penalties),
+		headslashings.WithControls(controls),
+	}
+	var actionParams []headslashings.Parameter
+	var notifiers []slashings.Handler
+	if runScripts {
+		// Scripts and notifiers run at most once for each slashing, so record them in the
+		// ledger and history shared with esd.
+		history, err := startHistory(ctx, false)
+		if err != nil {
+			return lockedError(err, "running scripts for scanned blocks")
+		}
+		ledger, err := startLedger(ctx)
+		if err != nil {
+			return lockedError(err, "running scripts for scanned blocks")
+		}
+		notifiers, err = startNotifiers(ctx)
+		if err != nil {
+			return err
+		}
+		actionParams = append(params,
+			headslashings.WithHistory(history),
+			headslashings.WithLedger(ledger),
+			headslashings.WithAttesterSlashedScript(viper.GetString("slashings.attester-slashed-script")),
+			headslashings.WithProposerSlashedScript(viper.GetString("slashings.proposer-slashed-script")),
+			headslashings.WithBatchScript(viper.GetString("slashings.batch-script")),
+		)
+	}
+
+	results := make([]*scanResult, 0, len(cmd.args))
+	for _, block := range cmd.args {
+		// Collect the slashings without the ledger, so that those already reported are
+		// shown, then run the scripts and notifiers if requested.
+		collector := &scanCollector{
+			events: make([]*slashings.SlashingEvent, 0),
+		}
+		_, err = headslashings.New(ctx, append(params,
+			headslashings.WithHandlers([]slashings.Handler{collector}),
+			headslashings.WithBlock(block),
+		)...)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed to scan block %s", block))
+		}
+		if runScripts && len(collector.events) > 0 {
+			_, err = headslashings.New(ctx, append(actionParams,
+				headslashings.WithHandlers(notifiers),
+				headslashings.WithBlock(block),
+			)...)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("failed to run scripts for block %s", block))
+			}
+		}
+		results = append(results, &scanResult{
+			Block:     block,
+			Slashings: collector.events,
+		})
+	}
+
+	if cmd.json {
+		return printJSON(results)
+	}
+	for _, result := range results {
+		if len(result.Slashings) == 0 {
+			fmt.Fprintf(os.Stdout, "Block %s: no slashings\n", result.Block)
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "Block %s: %d slashed validators\n", result.Block, len(result.Slashings))
+		for _, event := range result.Slashings {
+			fmt.Fprintf(os.Stdout, "  validator %d: %s\n", event.ValidatorIndex, offencesText(event.Offences))
+		}
+	}
+
+	return nil
+}
+
+// offencesText returns a description of offences.
+func offencesText(offences []*slashings.Offence) string {
+	descriptions := make([]string, 0, len(offences))
+	for _, offence := range offences {
+		descriptions = append(descriptions, offence.Description())
+	}
+
+	return strings.Join(descriptions, "; ")
+}
+
+// retryFailedResult is the output of the retry-failed command.
+type retryFailedResult struct {
+	Processed int `json:"processed"`
+	Remaining int `json:"remaining"`
+}
+
+// runRetryFailed processes the blocks recorded as failed.
+func runRetryFailed(ctx context.Context, cmd *command) error {
+	eth2Client, err := fetchClient(ctx, viper.GetString("eth2client.address"))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to fetch client %q", maskURL(viper.GetString("eth2client.address"))))
+	}
+
+	chainTime, err := startChainTime(ctx, eth2Client)
+	if err != nil {
+		return err
+	}
+
+	// A running esd holds the databases and its own copy of the failed blocks, which it
+	// would overwrite, so open the databases first to refuse to run alongside it.
+	history, err := startHistory(ctx, false)
+	if err != nil {
+		return lockedError(err, "retrying failed blocks")
+	}
+
+	ledger, err := startLedger(ctx)
+	if err != nil {
+		return lockedError(err, "retrying failed blocks")
+	}
+
+	deadLetter, err := startDeadLetter(ctx)
+	if err != nil {
+		return err
+	}
+	entries, err := deadLetter.Entries(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain failed blocks")
+	}
+	if len(entries) == 0 {
+		if cmd.json {
+			return printJSON(&retryFailedResult{})
+		}
+		fmt.Fprintf(os.Stdout, "No failed blocks\n")
+
+		return nil
+	}
+	if !cmd.json {
+		fmt.Fprintf(os.Stdout, "Retrying %d failed blocks\n", len(entries))
+	}
+
+	handlers, err := startNotifiers(ctx)
+	if err != nil {
+		return err
+	}
+
+	penalties, err := startPenalties(ctx, eth2Client, viper.GetBool("slashings.estimate-penalties"))
+	if err != nil {
+		return err
+	}
+
+	controls, err := startControls(ctx)
+	if err != nil {
+		return err
 	}
 
 	_, err = headslashings.New(ctx,
 		headslashings.WithLogLevel(util.LogLevel("slashings")),
 		headslashings.WithETH2Client(eth2Client),
+		headslashings.WithChainTime(chainTime),
+		headslashings.WithDeadLetter(deadLetter),
+		headslashings.WithHistory(history),
+		headslashings.WithLedger(ledger),
 		headslashings.WithAttesterSlashedScript(viper.GetString("slashings.attester-slashed-script")),
 		headslashings.WithProposerSlashedScript(viper.GetString("slashings.proposer-slashed-script")),
-		headslashings.WithBlock(viper.GetString("test-block")),
+		headslashings.WithBatchScript(viper.GetString("slashings.batch-script")),
+		headslashings.WithScriptTimeout(viper.GetDuration("scripts.timeout")),
+		headslashings.WithHandlers(handlers),
+		headslashings.WithVerify(viper.GetBool("slashings.verify")),
+		headslashings.WithPenalties(penalties),
+		headslashings.WithControls(controls),
+		headslashings.WithRetryFailed(true),
 	)
 	if err != nil {
-		return false, errors.Wrap(err, "failed to create slashings service")
+		return errors.Wrap(err, "failed to retry failed blocks")
 	}
 
-	return true, nil
+	remaining, err := deadLetter.Entries(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain failed blocks")
+	}
+	if cmd.json {
+		if err := printJSON(&retryFailedResult{
+			Processed: len(entries) - len(remaining),
+			Remaining: len(remaining),
+		}); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(os.Stdout, "%d blocks processed, %d remain failed\n", len(entries)-len(remaining), len(remaining))
+	}
+	if len(remaining) > 0 {
+		return errors.New("not all failed blocks could be processed")
+	}
+
+	return nil
+}
+
+// lockedError explains an error opening a database for a command that cannot run while
+// esd is running.
+func lockedError(err error, action string) error {
+	if errors.Is(err, standardhistory.ErrLocked) || errors.Is(err, standardledger.ErrLocked) {
+		return errors.Wrap(err, fmt.Sprintf("esd appears to be running; stop it before %s", action))
+	}
+
+	return err
+}
+
+// runHistory prints the recorded slashings, or a single slashing if an ID is supplied.
+func runHistory(ctx context.Context, cmd *command) error {
+	history, err := openHistory(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(cmd.args) == 1 {
+		slashing, err := history.Slashing(ctx, cmd.args[0])
+		if err != nil {
+			return errors.Wrap(err, "failed to obtain slashing")
+		}
+		if cmd.json {
+			return printJSON(slashing)
+		}
+		printSlashing(slashing)
+
+		return nil
+	}
+
+	slashings, err := history.Slashings(ctx, &historysvc.Filter{})
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain slashings")
+	}
+	if cmd.json {
+		return printJSON(slashings)
+	}
+	if len(slashings) == 0 {
+		fmt.Fprintf(os.Stdout, "No slashings recorded\n")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\tSLOT\tVALIDATOR\tOFFENCES\tSTATUS\tACTIONS\n")
+	for _, slashing := range slashings {
+		succeeded := 0
+		for _, action := range slashing.Actions {
+			if action.Success {
+				succeeded++
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%d/%d succeeded\n",
+			slashing.ID(),
+			slashing.Slot,
+			slashing.ValidatorIndex,
+			offencesText(slashing.Offences),
+			slashing.Status,
+			succeeded,
+			len(slashing.Actions),
+		)
+	}
+
+	return tw.Flush()
+}
+
+// printSlashing prints the details of a recorded slashing.
+func printSlashing(slashing *historysvc.Slashing) {
+	fmt.Fprintf(os.Stdout, "ID:         %s\n", slashing.ID())
+	fmt.Fprintf(os.Stdout, "Slot:       %d\n", slashing.Slot)
+	fmt.Fprintf(os.Stdout, "Block root: %#x\n", slashing.BlockRoot)
+	fmt.Fprintf(os.Stdout, "Validator:  %d (%#x)\n", slashing.ValidatorIndex, slashing.PubKey)
+	fmt.Fprintf(os.Stdout, "Offences:   %s\n", offencesText(slashing.Offences))
+	fmt.Fprintf(os.Stdout, "Status:     %s\n", slashing.Status)
+	fmt.Fprintf(os.Stdout, "First seen: %s\n", slashing.FirstSeen.Format(time.RFC3339))
+	if len(slashing.Actions) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stdout, "Actions:\n")
+	for _, action := range slashing.Actions {
+		outcome := "succeeded"
+		if !action.Success {
+			outcome = fmt.Sprintf("failed: %s", action.Error)
+		}
+		fmt.Fprintf(os.Stdout, "  %s at %s: %s\n", action.Action, action.Started.Format(time.RFC3339), outcome)
+	}
+}
+
+// runPenalty prints the estimated penalties for the supplied validators if slashed now.
+func runPenalty(ctx context.Context, cmd *command) error {
+	indices := make([]phase0.ValidatorIndex, 0, len(cmd.args))
+	for _, arg := range cmd.args {
+		index, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return newUsageError("invalid validator index %q", arg)
+		}
+		indices = append(indices, phase0.ValidatorIndex(index))
+	}
+
+	eth2Client, err := fetchClient(ctx, viper.GetString("eth2client.address"))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to fetch client %q", maskURL(viper.GetString("eth2client.address"))))
+	}
+
+	penalties, err := startPenalties(ctx, eth2Client, true)
+	if err != nil {
+		return err
+	}
+
+	res, err := penalties.Estimate(ctx, "head", indices)
+	if err != nil {
+		return errors.Wrap(err, "failed to estimate penalties")
+	}
+	if cmd.json {
+		return printJSON(res)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "VALIDATOR\tEFFECTIVE BALANCE\tINITIAL PENALTY\tCORRELATION EPOCH\tCORRELATION PENALTY\n")
+	for _, index := range indices {
+		penalty, exists := res[index]
+		if !exists {
+			fmt.Fprintf(tw, "%d\tunknown\t\t\t\n", index)
+			continue
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\n",
+			index,
+			penalty.EffectiveBalance,
+			penalty.InitialPenalty,
+			penalty.CorrelationEpoch,
+			penalty.CorrelationPenalty,
+		)
+	}
+
+	return tw.Flush()
 }
 
 func logModules() {
@@ -326,6 +962,303 @@ func resolvePath(path string) string {
 	return filepath.Join(baseDir, path)
 }
 
+// startPenalties starts the penalty estimation service if enabled.
+func startPenalties(ctx context.Context, eth2Client eth2client.Service, enabled bool) (penaltiessvc.Service, error) {
+	if !enabled {
+		// Service is not required, so do not return it.
+		//nolint:nilnil
+		return nil, nil
+	}
+
+	log.Trace().Msg("Starting penalties service")
+	specProvider, isProvider := eth2Client.(eth2client.SpecProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide spec")
+	}
+	beaconStateProvider, isProvider := eth2Client.(eth2client.BeaconStateProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide beacon state")
+	}
+
+	penalties, err := standardpenalties.New(ctx,
+		standardpenalties.WithLogLevel(util.LogLevel("penalties")),
+		standardpenalties.WithSpecProvider(specProvider),
+		standardpenalties.WithBeaconStateProvider(beaconStateProvider),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start penalties service")
+	}
+
+	return penalties, nil
+}
+
+// startNotifiers starts the notifiers that are informed of slashing events.
+func startNotifiers(ctx context.Context) ([]slashings.Handler, error) {
+	handlers := make([]slashings.Handler, 0)
+	for _, url := range viper.GetStringSlice("notifiers.webhook.urls") {
+		log.Trace().Str("url", url).Msg("Starting webhook notifier")
+		notifier, err := webhooknotifier.New(ctx,
+			webhooknotifier.WithLogLevel(util.LogLevel("notifiers.webhook")),
+			webhooknotifier.WithURL(url),
+			webhooknotifier.WithTimeout(viper.GetDuration("notifiers.webhook.timeout")),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to start webhook notifier")
+		}
+		handlers = append(handlers, notifier)
+	}
+
+	return handlers, nil
+}
+
+func startWatchlist(ctx context.Context) (*staticwatchlist.Service, error) {
+	watchlist, err := staticwatchlist.New(ctx,
+		staticwatchlist.WithLogLevel(util.LogLevel("watchlist")),
+		staticwatchlist.WithValidators(viper.GetStringSlice("watchlist.validators")),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start watchlist service")
+	}
+
+	return watchlist, nil
+}
+
+func startLifecycle(ctx context.Context,
+	eth2Client eth2client.Service,
+	monitor metrics.Service,
+	chainTime chaintime.Service,
+	watchlist watchlistsvc.Service,
+	controls controlssvc.Service,
+	handlers []slashings.Handler,
+	params ...standardlifecycle.Parameter,
+) (
+	*standardlifecycle.Service,
+	error,
+) {
+	log.Trace().Msg("Starting lifecycle service")
+	specProvider, isProvider := eth2Client.(eth2client.SpecProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide spec")
+	}
+	validatorsProvider, isProvider := eth2Client.(eth2client.ValidatorsProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide validators")
+	}
+
+	lifecycle, err := standardlifecycle.New(ctx, append([]standardlifecycle.Parameter{
+		standardlifecycle.WithLogLevel(util.LogLevel("lifecycle")),
+		standardlifecycle.WithMonitor(monitor),
+		standardlifecycle.WithSpecProvider(specProvider),
+		standardlifecycle.WithValidatorsProvider(validatorsProvider),
+		standardlifecycle.WithChainTime(chainTime),
+		standardlifecycle.WithWatchlist(watchlist),
+		standardlifecycle.WithControls(controls),
+		standardlifecycle.WithPath(resolvePath(viper.GetString("lifecycle.path"))),
+		standardlifecycle.WithScripts(lifecycleScripts()),
+		standardlifecycle.WithScriptTimeout(viper.GetDuration("scripts.timeout")),
+		standardlifecycle.WithHandlers(handlers),
+	}, params...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start lifecycle service")
+	}
+
+	return lifecycle, nil
+}
+
+// lifecycleScripts returns the scripts to run when slashed validators reach milestones.
+func lifecycleScripts() map[lifecyclesvc.Milestone]string {
+	return map[lifecyclesvc.Milestone]string{
+		lifecyclesvc.MilestoneExited:             viper.GetString("lifecycle.exited-script"),
+		lifecyclesvc.MilestoneCorrelationPenalty: viper.GetString("lifecycle.correlation-penalty-script"),
+		lifecyclesvc.MilestoneWithdrawable:       viper.GetString("lifecycle.withdrawable-script"),
+		lifecyclesvc.MilestoneWithdrawn:          viper.GetString("lifecycle.withdrawn-script"),
+	}
+}
+
+func startHealth(ctx context.Context, status slashings.StatusProvider) (healthsvc.Service, error) {
+	health, err := standardhealth.New(ctx,
+		standardhealth.WithLogLevel(util.LogLevel("health")),
+		standardhealth.WithStatusProvider(status),
+		standardhealth.WithLivenessTimeout(viper.GetDuration("health.liveness-timeout")),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start health service")
+	}
+	registerLogLevel("health", health)
+
+	return health, nil
+}
+
+// startStream starts the event stream if the API, through which it is served, is enabled.
+func startStream(ctx context.Context, monitor metrics.Service) (streamsvc.Service, error) {
+	if viper.GetString("api.listen-address") == "" {
+		// Service is not required, so do not return it.
+		//nolint:nilnil
+		return nil, nil
+	}
+
+	stream, err := memorystream.New(ctx,
+		memorystream.WithLogLevel(util.LogLevel("stream")),
+		memorystream.WithMonitor(monitor),
+		memorystream.WithBufferSize(viper.GetInt("api.events.buffer-size")),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start event stream")
+	}
+	registerLogLevel("stream", stream)
+
+	return stream, nil
+}
+
+func startControls(ctx context.Context) (controlssvc.Service, error) {
+	controls, err := filecontrols.New(ctx,
+		filecontrols.WithLogLevel(util.LogLevel("controls")),
+		filecontrols.WithPath(resolvePath(viper.GetString("controls.path"))),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start controls service")
+	}
+	registerLogLevel("controls", controls)
+
+	return controls, nil
+}
+
+// controlledActions returns the names of the actions that can be paused.
+func controlledActions() []string {
+	actions := append([]string{}, headslashings.Actions...)
+	for _, milestone := range lifecyclesvc.Milestones {
+		actions = append(actions, standardlifecycle.Action(milestone))
+	}
+
+	return actions
+}
+
+// fetchAdminToken fetches the bearer token for the admin API, if configured.
+func fetchAdminToken(ctx context.Context, majordomo majordomo.Service) (string, error) {
+	if viper.GetString("api.admin-token") == "" {
+		return "", nil
+	}
+
+	token, err := majordomo.Fetch(ctx, viper.GetString("api.admin-token"))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to obtain admin token")
+	}
+	if len(strings.TrimSpace(string(token))) == 0 {
+		return "", errors.New("admin token is empty")
+	}
+
+	return strings.TrimSpace(string(token)), nil
+}
+
+func startAPI(ctx context.Context,
+	majordomo majordomo.Service,
+	chainTime chaintime.Service,
+	history historysvc.Service,
+	status slashings.StatusProvider,
+	watchlist watchlistsvc.Service,
+	stream streamsvc.Service,
+	health healthsvc.Service,
+	controls controlssvc.Service,
+) (
+	*restapi.Service,
+	error,
+) {
+	if viper.GetString("api.listen-address") == "" {
+		log.Debug().Msg("No API listen address supplied; API not starting")
+		// Service is not required, so do not return it.
+		//nolint:nilnil
+		return nil, nil
+	}
+
+	adminToken, err := fetchAdminToken(ctx, majordomo)
+	if err != nil {
+		return nil, err
+	}
+
+	api, err := restapi.New(ctx,
+		restapi.WithLogLevel(util.LogLevel("api")),
+		restapi.WithListenAddress(viper.GetString("api.listen-address")),
+		restapi.WithChainTime(chainTime),
+		restapi.WithHistory(history),
+		restapi.WithStatusProvider(status),
+		restapi.WithWatchlist(watchlist),
+		restapi.WithStream(stream),
+		restapi.WithHealth(health),
+		restapi.WithControls(controls),
+		restapi.WithAdminToken(adminToken),
+		restapi.WithActions(controlledActions()),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start API service")
+	}
+	registerLogLevel("api", api)
+	log.Info().Str("listen_address", viper.GetString("api.listen-address")).Msg("Started API service")
+
+	return api, nil
+}
+
+func startChainTime(ctx context.Context, eth2Client eth2client.Service) (chaintime.Service, error) {
+	log.Trace().Msg("Starting chain time service")
+	genesisProvider, isProvider := eth2Client.(eth2client.GenesisProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide genesis")
+	}
+	specProvider, isProvider := eth2Client.(eth2client.SpecProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide spec")
+	}
+
+	chainTime, err := standardchaintime.New(ctx,
+		standardchaintime.WithLogLevel(util.LogLevel("chaintime")),
+		standardchaintime.WithGenesisProvider(genesisProvider),
+		standardchaintime.WithSpecProvider(specProvider),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start chain time service")
+	}
+
+	return chainTime, nil
+}
+
+func startDeadLetter(ctx context.Context) (deadletter.Service, error) {
+	deadLetter, err := filedeadletter.New(ctx,
+		filedeadletter.WithLogLevel(util.LogLevel("deadletter")),
+		filedeadletter.WithPath(resolvePath(viper.GetString("slashings.failed-blocks-file"))),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start dead-letter service")
+	}
+
+	return deadLetter, nil
+}
+
+func startHistory(ctx context.Context, readOnly bool) (historysvc.Service, error) {
+	history, err := standardhistory.New(ctx,
+		standardhistory.WithLogLevel(util.LogLevel("history")),
+		standardhistory.WithPath(resolvePath(viper.GetString("history.path"))),
+		standardhistory.WithReadOnly(readOnly),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start history service")
+	}
+	registerLogLevel("history", history)
+
+	return history, nil
+}
+
+func startLedger(ctx context.Context) (ledgersvc.Service, error) {
+	ledger, err := standardledger.New(ctx,
+		standardledger.WithLogLevel(util.LogLevel("ledger")),
+		standardledger.WithPath(resolvePath(viper.GetString("ledger.path"))),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start ledger service")
+	}
+	registerLogLevel("ledger", ledger)
+
+	return ledger, nil
+}
+
 func startMonitor(ctx context.Context) (metrics.Service, error) {
 	log.Trace().Msg("Starting metrics service")
 	var monitor metrics.Service
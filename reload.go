@@ -0,0 +1,170 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+
+	restapi "github.com/attestantio/esd/services/api/rest"
+	standardlifecycle "github.com/attestantio/esd/services/lifecycle/standard"
+	headslashings "github.com/attestantio/esd/services/slashings/head"
+	streamsvc "github.com/attestantio/esd/services/stream"
+	staticwatchlist "github.com/attestantio/esd/services/watchlist/static"
+	"github.com/attestantio/esd/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	majordomo "github.com/wealdtech/go-majordomo"
+)
+
+// appliedConfig is the contents of the configuration file in use by the running services.
+var appliedConfig []byte
+
+// runningServices are the services that can be reloaded or drained while esd is running.
+type runningServices struct {
+	majordomo majordomo.Service
+	slashings *headslashings.Service
+	lifecycle *standardlifecycle.Service
+	watchlist *staticwatchlist.Service
+	stream    streamsvc.Service
+	api       *restapi.Service
+}
+
+// scriptKeys are the configuration keys of the scripts that esd runs.
+var scriptKeys = []string{
+	"slashings.attester-slashed-script",
+	"slashings.proposer-slashed-script",
+	"slashings.batch-script",
+	"slashings.mass.script",
+	"lifecycle.exited-script",
+	"lifecycle.correlation-penalty-script",
+	"lifecycle.withdrawable-script",
+	"lifecycle.withdrawn-script",
+}
+
+// reloadConfig reloads the configuration, logging the outcome.
+func reloadConfig(ctx context.Context, services *runningServices) {
+	log.Info().Str("config_file", viper.ConfigFileUsed()).Msg("Reloading configuration")
+	if err := reload(ctx, services); err != nil {
+		log.Error().Err(err).Msg("Failed to reload configuration; continuing with previous configuration")
+		return
+	}
+	log.Info().Int("watchlist_size", services.watchlist.Size()).Msg("Configuration reloaded")
+}
+
+// reload re-reads the configuration file and secrets, and applies the watchlist, scripts,
+// notifiers and log levels to the running services.  The new configuration is validated
+// in full before it is applied, and if it is invalid the previous configuration remains.
+func reload(ctx context.Context, services *runningServices) error {
+	if viper.ConfigFileUsed() == "" {
+		return errors.New("no configuration file in use")
+	}
+	data, err := os.ReadFile(viper.ConfigFileUsed())
+	if err != nil {
+		return errors.Wrap(err, "failed to read configuration file")
+	}
+	if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+		return errors.Wrap(err, "failed to parse configuration file")
+	}
+
+	if err := applyConfig(ctx, services); err != nil {
+		// Restore the previous configuration, so that it matches the running services.
+		if restoreErr := viper.ReadConfig(bytes.NewReader(appliedConfig)); restoreErr != nil {
+			log.Warn().Err(restoreErr).Msg("Failed to restore previous configuration")
+		}
+
+		return err
+	}
+	appliedConfig = data
+
+	return nil
+}
+
+// applyConfig applies the configuration to the running services.  Everything that can
+// fail is checked before any service is changed.
+func applyConfig(ctx context.Context, services *runningServices) error {
+	for _, key := range scriptKeys {
+		if err := checkScript(viper.GetString(key)); err != nil {
+			return errors.Wrap(err, key)
+		}
+	}
+
+	if viper.GetDuration("scripts.timeout") < 0 {
+		return errors.New("scripts.timeout: timeout cannot be negative")
+	}
+
+	handlers, err := startNotifiers(ctx)
+	if err != nil {
+		return err
+	}
+
+	adminToken := ""
+	if services.api != nil {
+		adminToken, err = fetchAdminToken(ctx, services.majordomo)
+		if err != nil {
+			return err
+		}
+		if (adminToken != "") != services.api.AdminEnabled() {
+			return errors.New("api.admin-token: admin API can only be enabled or disabled on startup")
+		}
+	}
+
+	// The watchlist is unchanged if any of its validators is invalid.
+	if err := services.watchlist.Update(viper.GetStringSlice("watchlist.validators")); err != nil {
+		return errors.Wrap(err, "watchlist.validators")
+	}
+
+	if services.api != nil {
+		if err := services.api.SetAdminToken(adminToken); err != nil {
+			return errors.Wrap(err, "api.admin-token")
+		}
+	}
+
+	if services.stream != nil {
+		handlers = append(handlers, services.stream)
+	}
+	services.lifecycle.Reconfigure(ctx,
+		standardlifecycle.WithLogLevel(util.LogLevel("lifecycle")),
+		standardlifecycle.WithScripts(lifecycleScripts()),
+		standardlifecycle.WithScriptTimeout(viper.GetDuration("scripts.timeout")),
+		standardlifecycle.WithHandlers(handlers),
+	)
+	handlers = append(handlers, services.lifecycle)
+	services.slashings.Reconfigure(ctx,
+		headslashings.WithLogLevel(util.LogLevel("slashings")),
+		headslashings.WithAttesterSlashedScript(viper.GetString("slashings.attester-slashed-script")),
+		headslashings.WithProposerSlashedScript(viper.GetString("slashings.proposer-slashed-script")),
+		headslashings.WithBatchScript(viper.GetString("slashings.batch-script")),
+		headslashings.WithScriptTimeout(viper.GetDuration("scripts.timeout")),
+		headslashings.WithMassSlashingScript(viper.GetString("slashings.mass.script")),
+		headslashings.WithHandlers(handlers),
+	)
+	reloadLogLevels()
+
+	return nil
+}
+
+// checkScript checks that a script, if supplied, exists and is executable.
+func checkScript(script string) error {
+	if script == "" {
+		return nil
+	}
+	if _, err := exec.LookPath(script); err != nil {
+		return errors.Wrap(err, "script is not usable")
+	}
+
+	return nil
+}
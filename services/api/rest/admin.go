@@ -0,0 +1,214 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/attestantio/esd/services/controls"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// AdminRequest is the body of a request to change the controls.
+type AdminRequest struct {
+	// Actor is the operator making the change; defaults to the remote address.
+	Actor string `json:"actor,omitempty"`
+	// Reason is the reason for the change, recorded in the audit log.
+	Reason string `json:"reason,omitempty"`
+	// Action is the action to pause or resume; all actions if empty.
+	Action string `json:"action,omitempty"`
+	// Validator is the index of the validator to mute or unmute.
+	Validator string `json:"validator,omitempty"`
+	// Duration is the duration for which to mute the validator, for example "2h".
+	Duration string `json:"duration,omitempty"`
+	// Until is the time until which to mute the validator, as an alternative to Duration.
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// admin wraps an admin handler, checking the method and the bearer token.
+func (s *Service) admin(method string, handler func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(*s.adminToken.Load())) != 1 {
+			s.log.Warn().Str("remote_address", r.RemoteAddr).Str("path", r.URL.Path).Msg("Unauthorized admin request")
+			s.sendError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if r.Method != method {
+			s.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// getControls returns the current state of the controls.
+func (s *Service) getControls(w http.ResponseWriter, r *http.Request) {
+	s.sendResponse(w, http.StatusOK, s.controls.State(r.Context()))
+}
+
+// getAudit returns the audit log of changes to the controls.
+func (s *Service) getAudit(w http.ResponseWriter, r *http.Request) {
+	s.sendResponse(w, http.StatusOK, s.controls.Audit(r.Context()))
+}
+
+// postPause pauses an action, or all actions.
+func (s *Service) postPause(w http.ResponseWriter, r *http.Request) {
+	req, change, ok := s.parseAdminRequest(w, r)
+	if !ok {
+		return
+	}
+	if !s.checkAction(w, req.Action) {
+		return
+	}
+	s.applyChange(w, r, s.controls.Pause(r.Context(), change, req.Action))
+}
+
+// postResume resumes an action, or all actions.
+func (s *Service) postResume(w http.ResponseWriter, r *http.Request) {
+	req, change, ok := s.parseAdminRequest(w, r)
+	if !ok {
+		return
+	}
+	if !s.checkAction(w, req.Action) {
+		return
+	}
+	s.applyChange(w, r, s.controls.Resume(r.Context(), change, req.Action))
+}
+
+// postMute mutes a validator for a time window.
+func (s *Service) postMute(w http.ResponseWriter, r *http.Request) {
+	req, change, ok := s.parseAdminRequest(w, r)
+	if !ok {
+		return
+	}
+	index, ok := s.parseValidator(w, req.Validator)
+	if !ok {
+		return
+	}
+
+	var until time.Time
+	switch {
+	case req.Until != nil && req.Duration != "":
+		s.sendError(w, http.StatusBadRequest, "only one of duration and until can be supplied")
+		return
+	case req.Until != nil:
+		until = *req.Until
+	case req.Duration != "":
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			s.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid duration %q", req.Duration))
+			return
+		}
+		until = time.Now().Add(duration)
+	default:
+		s.sendError(w, http.StatusBadRequest, "no duration or until supplied")
+		return
+	}
+	if !until.After(time.Now()) {
+		s.sendError(w, http.StatusBadRequest, "mute must end in the future")
+		return
+	}
+
+	s.applyChange(w, r, s.controls.Mute(r.Context(), change, index, until))
+}
+
+// postUnmute unmutes a validator.
+func (s *Service) postUnmute(w http.ResponseWriter, r *http.Request) {
+	req, change, ok := s.parseAdminRequest(w, r)
+	if !ok {
+		return
+	}
+	index, ok := s.parseValidator(w, req.Validator)
+	if !ok {
+		return
+	}
+	s.applyChange(w, r, s.controls.Unmute(r.Context(), change, index))
+}
+
+// postDisarm puts esd into notify-only mode.
+func (s *Service) postDisarm(w http.ResponseWriter, r *http.Request) {
+	_, change, ok := s.parseAdminRequest(w, r)
+	if !ok {
+		return
+	}
+	s.applyChange(w, r, s.controls.SetDisarmed(r.Context(), change, true))
+}
+
+// postArm takes esd out of notify-only mode.
+func (s *Service) postArm(w http.ResponseWriter, r *http.Request) {
+	_, change, ok := s.parseAdminRequest(w, r)
+	if !ok {
+		return
+	}
+	s.applyChange(w, r, s.controls.SetDisarmed(r.Context(), change, false))
+}
+
+// parseAdminRequest parses the body of an admin request.  An empty body is allowed.
+func (s *Service) parseAdminRequest(w http.ResponseWriter, r *http.Request) (*AdminRequest, *controls.Change, bool) {
+	req := &AdminRequest{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024*1024)).Decode(req); err != nil {
+			s.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+			return nil, nil, false
+		}
+	}
+
+	change := &controls.Change{
+		Actor:  req.Actor,
+		Reason: req.Reason,
+	}
+	if change.Actor == "" {
+		change.Actor = r.RemoteAddr
+	}
+
+	return req, change, true
+}
+
+// parseValidator parses a validator index.
+func (s *Service) parseValidator(w http.ResponseWriter, validator string) (phase0.ValidatorIndex, bool) {
+	index, err := strconv.ParseUint(validator, 10, 64)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid validator %q", validator))
+		return 0, false
+	}
+
+	return phase0.ValidatorIndex(index), true
+}
+
+// checkAction checks that an action is known, if supplied.
+func (s *Service) checkAction(w http.ResponseWriter, action string) bool {
+	if action != "" && !s.actions[action] {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("unknown action %q", action))
+		return false
+	}
+
+	return true
+}
+
+// applyChange responds to a change to the controls with the new state.
+func (s *Service) applyChange(w http.ResponseWriter, r *http.Request, err error) {
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to change controls")
+		s.sendError(w, http.StatusInternalServerError, "failed to change controls")
+		return
+	}
+	s.sendResponse(w, http.StatusOK, s.controls.State(r.Context()))
+}
@@ -0,0 +1,260 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/attestantio/esd/services/stream"
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+)
+
+// heartbeatInterval is the interval between heartbeats sent to idle SSE subscribers.
+const heartbeatInterval = 15 * time.Second
+
+// eventTypeMissed is the type of the event sent to a subscriber when some of the
+// events that it asked to replay are no longer held.
+const eventTypeMissed = "missed"
+
+// missedData is the data of a missed event.
+type missedData struct {
+	// FromID is the ID from which the subscriber asked to replay.
+	FromID uint64 `json:"from_id"`
+}
+
+// eventsRequest is a parsed request to subscribe to events.
+type eventsRequest struct {
+	fromID uint64
+	types  map[stream.EventType]bool
+}
+
+// wants returns true if the subscriber wants events of the given type.
+func (e *eventsRequest) wants(eventType stream.EventType) bool {
+	return len(e.types) == 0 || e.types[eventType]
+}
+
+// getEvents streams events to the client as server-sent events.
+func (s *Service) getEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	flusher, isFlusher := w.(http.Flusher)
+	if !isFlusher {
+		s.sendError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	req, err := parseEventsRequest(r)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	subscription := s.stream.Subscribe(ctx, req.fromID)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if subscription.Missed {
+		if err := writeSSE(w, 0, eventTypeMissed, &missedData{FromID: req.fromID}); err != nil {
+			return
+		}
+	}
+	for _, event := range subscription.Replay {
+		if !req.wants(event.Type) {
+			continue
+		}
+		if err := writeSSE(w, event.ID, string(event.Type), event.Data); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+	s.log.Trace().Str("remote", r.RemoteAddr).Uint64("from_id", req.fromID).Int("replayed", len(subscription.Replay)).Msg("SSE subscriber connected")
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-subscription.Events:
+			if !ok {
+				// Dropped by the stream; the client will reconnect and replay.
+				return
+			}
+			if !req.wants(event.Type) {
+				continue
+			}
+			if err := writeSSE(w, event.ID, string(event.Type), event.Data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeSSE writes a single server-sent event.  An ID of 0 is omitted.
+func writeSSE(w http.ResponseWriter, id uint64, eventType string, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode event")
+	}
+	if id != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", id); err != nil {
+			return errors.Wrap(err, "failed to write event")
+		}
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, encoded); err != nil {
+		return errors.Wrap(err, "failed to write event")
+	}
+
+	return nil
+}
+
+// serveEventsWebSocket streams events to the client as JSON messages over a websocket.
+func (s *Service) serveEventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	req, err := parseEventsRequest(r)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	server := websocket.Server{
+		// Events are read-only and also available over SSE, so accept any origin.
+		Handshake: func(_ *websocket.Config, _ *http.Request) error {
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			s.streamWebSocket(r.Context(), conn, req)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// streamWebSocket sends events over a websocket until the client disconnects.
+func (s *Service) streamWebSocket(ctx context.Context, conn *websocket.Conn, req *eventsRequest) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer conn.Close()
+
+	// The client does not send anything, but reading detects its disconnection.
+	go func() {
+		defer cancel()
+		buf := make([]byte, 512)
+		for {
+			if _, err := conn.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	subscription := s.stream.Subscribe(ctx, req.fromID)
+	if subscription.Missed {
+		if err := websocket.JSON.Send(conn, map[string]any{
+			"type": eventTypeMissed,
+			"data": &missedData{FromID: req.fromID},
+		}); err != nil {
+			return
+		}
+	}
+	for _, event := range subscription.Replay {
+		if !req.wants(event.Type) {
+			continue
+		}
+		if err := websocket.JSON.Send(conn, event); err != nil {
+			return
+		}
+	}
+	s.log.Trace().Str("remote", conn.Request().RemoteAddr).Uint64("from_id", req.fromID).Int("replayed", len(subscription.Replay)).Msg("Websocket subscriber connected")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				// Dropped by the stream; the client will reconnect and replay.
+				return
+			}
+			if !req.wants(event.Type) {
+				continue
+			}
+			if err := websocket.JSON.Send(conn, event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseEventsRequest parses the ID from which to replay and the types of event wanted.
+// The ID is taken from the Last-Event-ID header sent by reconnecting SSE clients, or
+// else from the "from" query parameter.
+func parseEventsRequest(r *http.Request) (*eventsRequest, error) {
+	req := &eventsRequest{
+		types: make(map[stream.EventType]bool),
+	}
+
+	from := r.Header.Get("Last-Event-ID")
+	if from == "" {
+		from = r.URL.Query().Get("from")
+	}
+	if from != "" {
+		fromID, err := strconv.ParseUint(from, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid event ID")
+		}
+		req.fromID = fromID
+	}
+
+	for _, value := range r.URL.Query()["types"] {
+		for _, item := range strings.Split(value, ",") {
+			eventType := stream.EventType(strings.TrimSpace(item))
+			if !knownEventType(eventType) {
+				return nil, fmt.Errorf("unknown event type %q", item)
+			}
+			req.types[eventType] = true
+		}
+	}
+
+	return req, nil
+}
+
+// knownEventType returns true if the event type is published by the stream.
+func knownEventType(eventType stream.EventType) bool {
+	for _, known := range stream.EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+
+	return false
+}
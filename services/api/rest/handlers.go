@@ -0,0 +1,198 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/attestantio/esd/services/history"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+)
+
+// defaultLimit is the maximum number of slashings returned if no limit is supplied.
+const defaultLimit = 100
+
+// statusResponse is the response to a status request.
+type statusResponse struct {
+	*slashings.Status
+	// WatchlistSize is the number of entries in the watchlist, 0 if all validators are watched.
+	WatchlistSize int `json:"watchlist_size"`
+}
+
+// errorResponse is the response to a failed request.
+type errorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// getSlashings returns the slashings matching the filter in the query parameters, most recent first.
+func (s *Service) getSlashings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	filter, err := s.parseFilter(r)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := s.history.Slashings(r.Context(), filter)
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to obtain slashings")
+		s.sendError(w, http.StatusInternalServerError, "failed to obtain slashings")
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, res)
+}
+
+// getSlashing returns a single slashing, with its evidence and the results of its actions.
+func (s *Service) getSlashing(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/v1/slashings/")
+	if _, _, _, err := history.ParseID(id); err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := s.history.Slashing(r.Context(), id)
+	switch {
+	case errors.Is(err, history.ErrNotFound):
+		s.sendError(w, http.StatusNotFound, "slashing not found")
+	case err != nil:
+		s.log.Error().Str("id", id).Err(err).Msg("Failed to obtain slashing")
+		s.sendError(w, http.StatusInternalServerError, "failed to obtain slashing")
+	default:
+		s.sendResponse(w, http.StatusOK, res)
+	}
+}
+
+// getStatus returns the status of esd and its beacon node.
+func (s *Service) getStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, &statusResponse{
+		Status:        s.status.Status(r.Context()),
+		WatchlistSize: s.watchlist.Size(),
+	})
+}
+
+// parseFilter parses the filter for slashings from the query parameters.
+func (s *Service) parseFilter(r *http.Request) (*history.Filter, error) {
+	query := r.URL.Query()
+	filter := &history.Filter{
+		Type:   slashings.Type(query.Get("type")),
+		Kind:   slashings.OffenceKind(query.Get("kind")),
+		Status: slashings.FinalityStatus(query.Get("status")),
+		Limit:  defaultLimit,
+	}
+
+	switch filter.Type {
+	case "", slashings.TypeAttester, slashings.TypeProposer:
+	default:
+		return nil, fmt.Errorf("invalid type %q", filter.Type)
+	}
+	switch filter.Kind {
+	case "", slashings.OffenceDoubleVote, slashings.OffenceSurroundVote, slashings.OffenceDoubleProposal, slashings.OffenceUnknown:
+	default:
+		return nil, fmt.Errorf("invalid kind %q", filter.Kind)
+	}
+	switch filter.Status {
+	case "", slashings.StatusPending, slashings.StatusFinalized, slashings.StatusOrphaned:
+	default:
+		return nil, fmt.Errorf("invalid status %q", filter.Status)
+	}
+
+	// Validators can be supplied as repeated parameters, comma-separated lists, or both.
+	for _, validators := range query["validator"] {
+		for _, validator := range strings.Split(validators, ",") {
+			index, err := strconv.ParseUint(strings.TrimSpace(validator), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid validator %q", validator)
+			}
+			filter.ValidatorIndices = append(filter.ValidatorIndices, phase0.ValidatorIndex(index))
+		}
+	}
+
+	// No slashing can be after the current epoch, so later epochs are rejected or clamped,
+	// which also keeps the slot calculations from overflowing.
+	currentEpoch := s.chainTime.CurrentEpoch()
+	if val := query.Get("from_epoch"); val != "" {
+		epoch, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from_epoch %q", val)
+		}
+		if phase0.Epoch(epoch) > currentEpoch {
+			return nil, fmt.Errorf("from_epoch %d is after the current epoch %d", epoch, currentEpoch)
+		}
+		fromSlot := s.chainTime.FirstSlotOfEpoch(phase0.Epoch(epoch))
+		filter.FromSlot = &fromSlot
+	}
+	if val := query.Get("to_epoch"); val != "" {
+		epoch, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to_epoch %q", val)
+		}
+		if phase0.Epoch(epoch) > currentEpoch {
+			epoch = uint64(currentEpoch)
+		}
+		toSlot := s.chainTime.FirstSlotOfEpoch(phase0.Epoch(epoch)+1) - 1
+		filter.ToSlot = &toSlot
+	}
+	if filter.FromSlot != nil && filter.ToSlot != nil && *filter.FromSlot > *filter.ToSlot {
+		return nil, errors.New("from_epoch is after to_epoch")
+	}
+
+	if val := query.Get("limit"); val != "" {
+		limit, err := strconv.Atoi(val)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("invalid limit %q", val)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+// sendResponse sends a JSON response.
+func (s *Service) sendResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		s.log.Debug().Err(err).Msg("Failed to send response")
+	}
+}
+
+// sendError sends a JSON error response.
+func (s *Service) sendError(w http.ResponseWriter, statusCode int, message string) {
+	s.sendResponse(w, statusCode, &errorResponse{
+		Code:    statusCode,
+		Message: message,
+	})
+}
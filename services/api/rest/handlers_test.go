@@ -0,0 +1,215 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/attestantio/esd/services/history"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// testChainTime is a chain time service at a fixed epoch.
+type testChainTime struct {
+	currentEpoch phase0.Epoch
+}
+
+func (c *testChainTime) GenesisTime() time.Time                    { return time.Time{} }
+func (c *testChainTime) SlotDuration() time.Duration               { return 12 * time.Second }
+func (c *testChainTime) SlotsPerEpoch() uint64                     { return 32 }
+func (c *testChainTime) StartOfSlot(_ phase0.Slot) time.Time       { return time.Time{} }
+func (c *testChainTime) StartOfEpoch(_ phase0.Epoch) time.Time     { return time.Time{} }
+func (c *testChainTime) CurrentSlot() phase0.Slot                  { return c.FirstSlotOfEpoch(c.currentEpoch) }
+func (c *testChainTime) CurrentEpoch() phase0.Epoch                { return c.currentEpoch }
+func (c *testChainTime) SlotToEpoch(slot phase0.Slot) phase0.Epoch { return phase0.Epoch(slot / 32) }
+func (c *testChainTime) FirstSlotOfEpoch(epoch phase0.Epoch) phase0.Slot {
+	return phase0.Slot(epoch * 32)
+}
+
+// testHistory is a history service that records the filter it is passed.
+type testHistory struct {
+	filter *history.Filter
+}
+
+func (h *testHistory) SetSlashing(_ context.Context, _ *history.Slashing) error { return nil }
+
+func (h *testHistory) Slashing(_ context.Context, _ string) (*history.Slashing, error) {
+	return nil, history.ErrNotFound
+}
+
+func (h *testHistory) Slashings(_ context.Context, filter *history.Filter) ([]*history.Slashing, error) {
+	h.filter = filter
+
+	return []*history.Slashing{}, nil
+}
+
+func (h *testHistory) SetStatus(_ context.Context, _ string, _ slashings.FinalityStatus) error {
+	return nil
+}
+
+func (h *testHistory) AddActionResult(_ context.Context, _ string, _ *history.ActionResult) error {
+	return nil
+}
+
+func slotPtr(slot phase0.Slot) *phase0.Slot {
+	return &slot
+}
+
+func TestGetSlashingsFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		statusCode int
+		filter     *history.Filter
+		message    string
+	}{
+		{
+			name:       "Empty",
+			statusCode: http.StatusOK,
+			filter:     &history.Filter{Limit: defaultLimit},
+		},
+		{
+			name:       "All",
+			query:      "validator=3,1&validator=2&type=attester&kind=surround_vote&status=finalized&from_epoch=10&to_epoch=20&limit=5",
+			statusCode: http.StatusOK,
+			filter: &history.Filter{
+				ValidatorIndices: []phase0.ValidatorIndex{3, 1, 2},
+				Type:             slashings.TypeAttester,
+				Kind:             slashings.OffenceSurroundVote,
+				Status:           slashings.StatusFinalized,
+				FromSlot:         slotPtr(320),
+				ToSlot:           slotPtr(671),
+				Limit:            5,
+			},
+		},
+		{
+			name:       "KindUnknown",
+			query:      "kind=unknown",
+			statusCode: http.StatusOK,
+			filter:     &history.Filter{Kind: slashings.OffenceUnknown, Limit: defaultLimit},
+		},
+		{
+			name:       "KindInvalid",
+			query:      "kind=triple_vote",
+			statusCode: http.StatusBadRequest,
+			message:    `invalid kind "triple_vote"`,
+		},
+		{
+			name:       "TypeInvalid",
+			query:      "type=builder",
+			statusCode: http.StatusBadRequest,
+			message:    `invalid type "builder"`,
+		},
+		{
+			name:       "StatusInvalid",
+			query:      "status=lost",
+			statusCode: http.StatusBadRequest,
+			message:    `invalid status "lost"`,
+		},
+		{
+			name:       "ValidatorInvalid",
+			query:      "validator=1,x",
+			statusCode: http.StatusBadRequest,
+			message:    `invalid validator "x"`,
+		},
+		{
+			name:       "LimitInvalid",
+			query:      "limit=0",
+			statusCode: http.StatusBadRequest,
+			message:    `invalid limit "0"`,
+		},
+		{
+			name:       "FromEpochAfterToEpoch",
+			query:      "from_epoch=20&to_epoch=10",
+			statusCode: http.StatusBadRequest,
+			message:    "from_epoch is after to_epoch",
+		},
+		{
+			name:       "FromEpochFuture",
+			query:      "from_epoch=101",
+			statusCode: http.StatusBadRequest,
+			message:    "from_epoch 101 is after the current epoch 100",
+		},
+		{
+			name:       "FromEpochMax",
+			query:      "from_epoch=18446744073709551615",
+			statusCode: http.StatusBadRequest,
+			message:    "from_epoch 18446744073709551615 is after the current epoch 100",
+		},
+		{
+			name:       "ToEpochCurrent",
+			query:      "to_epoch=100",
+			statusCode: http.StatusOK,
+			filter:     &history.Filter{ToSlot: slotPtr(3231), Limit: defaultLimit},
+		},
+		{
+			// Clamped to the current epoch rather than overflowing.
+			name:       "ToEpochMax",
+			query:      "to_epoch=18446744073709551615",
+			statusCode: http.StatusOK,
+			filter:     &history.Filter{ToSlot: slotPtr(3231), Limit: defaultLimit},
+		},
+		{
+			name:       "ToEpochInvalid",
+			query:      "to_epoch=-1",
+			statusCode: http.StatusBadRequest,
+			message:    `invalid to_epoch "-1"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := &testHistory{}
+			s := &Service{
+				log:       zerolog.Nop(),
+				chainTime: &testChainTime{currentEpoch: 100},
+				history:   h,
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/slashings?"+test.query, nil)
+			rec := httptest.NewRecorder()
+			s.getSlashings(rec, req)
+			require.Equal(t, test.statusCode, rec.Code)
+			if test.statusCode != http.StatusOK {
+				res := &errorResponse{}
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), res))
+				require.Equal(t, test.message, res.Message)
+				require.Nil(t, h.filter)
+
+				return
+			}
+			require.Equal(t, test.filter, h.filter)
+		})
+	}
+}
+
+func TestGetSlashingsMethod(t *testing.T) {
+	s := &Service{
+		log:       zerolog.Nop(),
+		chainTime: &testChainTime{currentEpoch: 100},
+		history:   &testHistory{},
+	}
+
+	rec := httptest.NewRecorder()
+	s.getSlashings(rec, httptest.NewRequest(http.MethodPost, "/v1/slashings", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
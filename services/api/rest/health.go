@@ -0,0 +1,49 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/attestantio/esd/services/health"
+)
+
+// getReadiness reports if esd is ready, with a 503 status if not.
+func (s *Service) getReadiness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	s.sendReport(w, s.health.Readiness(r.Context()))
+}
+
+// getLiveness reports if esd is live, with a 503 status if not.
+func (s *Service) getLiveness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	s.sendReport(w, s.health.Liveness(r.Context()))
+}
+
+// sendReport sends a health report, with a status code that probes can act on.
+func (s *Service) sendReport(w http.ResponseWriter, report *health.Report) {
+	statusCode := http.StatusOK
+	if !report.OK {
+		statusCode = http.StatusServiceUnavailable
+	}
+	s.sendResponse(w, statusCode, report)
+}
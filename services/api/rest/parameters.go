@@ -0,0 +1,165 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"errors"
+
+	"github.com/attestantio/esd/services/chaintime"
+	"github.com/attestantio/esd/services/controls"
+	"github.com/attestantio/esd/services/health"
+	"github.com/attestantio/esd/services/history"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/esd/services/stream"
+	"github.com/attestantio/esd/services/watchlist"
+	"github.com/rs/zerolog"
+)
+
+type parameters struct {
+	logLevel      zerolog.Level
+	listenAddress string
+	chainTime     chaintime.Service
+	history       history.Service
+	status        slashings.StatusProvider
+	watchlist     watchlist.Service
+	stream        stream.Service
+	health        health.Service
+	controls      controls.Service
+	adminToken    string
+	actions       []string
+}
+
+// Parameter is the interface for service parameters.
+type Parameter interface {
+	apply(p *parameters)
+}
+
+type parameterFunc func(*parameters)
+
+func (f parameterFunc) apply(p *parameters) {
+	f(p)
+}
+
+// WithLogLevel sets the log level for the module.
+func WithLogLevel(logLevel zerolog.Level) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.logLevel = logLevel
+	})
+}
+
+// WithListenAddress sets the address on which to serve the API.
+func WithListenAddress(address string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.listenAddress = address
+	})
+}
+
+// WithChainTime sets the chain time service for this module.
+func WithChainTime(chainTime chaintime.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.chainTime = chainTime
+	})
+}
+
+// WithHistory sets the history service from which slashings are served.
+func WithHistory(history history.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.history = history
+	})
+}
+
+// WithStatusProvider sets the provider of the status of the slashings service.
+func WithStatusProvider(provider slashings.StatusProvider) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.status = provider
+	})
+}
+
+// WithWatchlist sets the watchlist.
+func WithWatchlist(watchlist watchlist.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.watchlist = watchlist
+	})
+}
+
+// WithStream sets the event stream served to subscribers.  The event endpoints are
+// only served if this is set.
+func WithStream(stream stream.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.stream = stream
+	})
+}
+
+// WithHealth sets the health service reported by the health endpoints.  The health
+// endpoints are only served if this is set.
+func WithHealth(health health.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.health = health
+	})
+}
+
+// WithControls sets the operator controls managed by the admin API.
+func WithControls(controls controls.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.controls = controls
+	})
+}
+
+// WithAdminToken sets the bearer token required by the admin API.  The admin API is
+// only served if this is set.
+func WithAdminToken(token string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.adminToken = token
+	})
+}
+
+// WithActions sets the names of the actions that can be paused through the admin API.
+func WithActions(actions []string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.actions = actions
+	})
+}
+
+// parseAndCheckParameters parses and checks parameters to ensure that mandatory parameters are present and correct.
+func parseAndCheckParameters(params ...Parameter) (*parameters, error) {
+	parameters := parameters{
+		logLevel: zerolog.GlobalLevel(),
+	}
+	for _, p := range params {
+		if params != nil {
+			p.apply(&parameters)
+		}
+	}
+
+	if parameters.listenAddress == "" {
+		return nil, errors.New("no listen address specified")
+	}
+	if parameters.chainTime == nil {
+		return nil, errors.New("no chain time specified")
+	}
+	if parameters.history == nil {
+		return nil, errors.New("no history specified")
+	}
+	if parameters.status == nil {
+		return nil, errors.New("no status provider specified")
+	}
+	if parameters.watchlist == nil {
+		return nil, errors.New("no watchlist specified")
+	}
+	if parameters.adminToken != "" && parameters.controls == nil {
+		return nil, errors.New("no controls specified for admin API")
+	}
+
+	return &parameters, nil
+}
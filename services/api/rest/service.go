@@ -0,0 +1,160 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rest provides a REST API for the slashings found by esd, its status and
+// its events.
+package rest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/attestantio/esd/services/chaintime"
+	"github.com/attestantio/esd/services/controls"
+	"github.com/attestantio/esd/services/health"
+	"github.com/attestantio/esd/services/history"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/esd/services/stream"
+	"github.com/attestantio/esd/services/watchlist"
+	"github.com/attestantio/esd/util"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+)
+
+// Service is a REST API service.
+type Service struct {
+	log        zerolog.Logger
+	logLevel   *util.LevelFilter
+	chainTime  chaintime.Service
+	history    history.Service
+	status     slashings.StatusProvider
+	watchlist  watchlist.Service
+	stream     stream.Service
+	health     health.Service
+	controls   controls.Service
+	adminToken atomic.Pointer[string]
+	actions    map[string]bool
+	server     *http.Server
+}
+
+// New creates a new REST API service.
+func New(ctx context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.  The level is applied by a filter so that it can be reconfigured.
+	logLevel := util.NewLevelFilter(parameters.logLevel)
+	log := zerologger.With().Str("service", "api").Str("impl", "rest").Logger().Level(zerolog.TraceLevel).Hook(logLevel)
+
+	s := &Service{
+		log:       log,
+		logLevel:  logLevel,
+		chainTime: parameters.chainTime,
+		history:   parameters.history,
+		status:    parameters.status,
+		watchlist: parameters.watchlist,
+		stream:    parameters.stream,
+		health:    parameters.health,
+		controls:  parameters.controls,
+		actions:   make(map[string]bool, len(parameters.actions)),
+	}
+	s.adminToken.Store(&parameters.adminToken)
+	for _, action := range parameters.actions {
+		s.actions[action] = true
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/slashings", s.getSlashings)
+	mux.HandleFunc("/v1/slashings/", s.getSlashing)
+	mux.HandleFunc("/v1/status", s.getStatus)
+	if s.health != nil {
+		mux.HandleFunc("/readyz", s.getReadiness)
+		mux.HandleFunc("/healthz", s.getLiveness)
+	}
+	if s.stream != nil {
+		mux.HandleFunc("/v1/events", s.getEvents)
+		mux.HandleFunc("/v1/events/ws", s.serveEventsWebSocket)
+	}
+	if parameters.adminToken != "" {
+		mux.HandleFunc("/v1/admin/controls", s.admin(http.MethodGet, s.getControls))
+		mux.HandleFunc("/v1/admin/audit", s.admin(http.MethodGet, s.getAudit))
+		mux.HandleFunc("/v1/admin/pause", s.admin(http.MethodPost, s.postPause))
+		mux.HandleFunc("/v1/admin/resume", s.admin(http.MethodPost, s.postResume))
+		mux.HandleFunc("/v1/admin/mute", s.admin(http.MethodPost, s.postMute))
+		mux.HandleFunc("/v1/admin/unmute", s.admin(http.MethodPost, s.postUnmute))
+		mux.HandleFunc("/v1/admin/disarm", s.admin(http.MethodPost, s.postDisarm))
+		mux.HandleFunc("/v1/admin/arm", s.admin(http.MethodPost, s.postArm))
+	}
+	s.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		// Tie requests to the service, so that event streams end when it stops.
+		BaseContext: func(_ net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	// Listen synchronously, so that a bad address is reported on startup.
+	listener, err := net.Listen("tcp", parameters.listenAddress)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to listen")
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("API server failed")
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.server.Shutdown(shutdownCtx); err != nil {
+			log.Warn().Err(err).Msg("Failed to shut down API server")
+		}
+	}()
+
+	return s, nil
+}
+
+// SetLogLevel changes the log level of the running service.
+func (s *Service) SetLogLevel(level zerolog.Level) {
+	s.logLevel.SetLevel(level)
+	s.log.Info().Str("log_level", level.String()).Msg("Reconfigured")
+}
+
+// AdminEnabled returns true if the admin API is enabled.
+func (s *Service) AdminEnabled() bool {
+	return *s.adminToken.Load() != ""
+}
+
+// SetAdminToken replaces the bearer token for the admin API.  The admin API can only be
+// enabled or disabled on startup, so the token cannot be set if it was not supplied then,
+// or cleared if it was.
+func (s *Service) SetAdminToken(token string) error {
+	current := *s.adminToken.Load()
+	switch {
+	case current == "" && token != "":
+		return errors.New("admin API was not enabled on startup; restart to enable it")
+	case current != "" && token == "":
+		return errors.New("admin API was enabled on startup; restart to disable it")
+	}
+	s.adminToken.Store(&token)
+
+	return nil
+}
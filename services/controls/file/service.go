@@ -0,0 +1,312 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package file provides operator controls that are stored in a JSON file.
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/attestantio/esd/services/controls"
+	"github.com/attestantio/esd/util"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+)
+
+// Service provides operator controls stored in a JSON file.
+type Service struct {
+	log      zerolog.Logger
+	logLevel *util.LevelFilter
+	path     string
+	data     *fileData
+	mu       sync.RWMutex
+}
+
+// fileData is the data stored in the file.
+type fileData struct {
+	State *controls.State        `json:"state"`
+	Audit []*controls.AuditEntry `json:"audit"`
+}
+
+// New creates a new file-backed controls service.
+func New(_ context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.  The level is applied by a filter so that it can be reconfigured.
+	logLevel := util.NewLevelFilter(parameters.logLevel)
+	log := zerologger.With().Str("service", "controls").Str("impl", "file").Logger().Level(zerolog.TraceLevel).Hook(logLevel)
+
+	s := &Service{
+		log:      log,
+		logLevel: logLevel,
+		path:     parameters.path,
+		data: &fileData{
+			State: &controls.State{},
+			Audit: make([]*controls.AuditEntry, 0),
+		},
+	}
+
+	data, err := os.ReadFile(parameters.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		log.Trace().Str("path", parameters.path).Msg("No existing controls file")
+	case err != nil:
+		return nil, errors.Wrap(err, "failed to read controls file")
+	default:
+		if err := json.Unmarshal(data, s.data); err != nil {
+			return nil, errors.Wrap(err, "failed to parse controls file")
+		}
+		if s.data.State == nil {
+			s.data.State = &controls.State{}
+		}
+	}
+	if s.data.State.Mutes == nil {
+		s.data.State.Mutes = make(map[phase0.ValidatorIndex]time.Time)
+	}
+	if s.data.State.PausedActions == nil {
+		s.data.State.PausedActions = make([]string, 0)
+	}
+
+	state := s.data.State
+	if state.Disarmed || state.Paused || len(state.PausedActions) > 0 || len(state.Mutes) > 0 {
+		log.Warn().
+			Bool("disarmed", state.Disarmed).
+			Bool("paused", state.Paused).
+			Strs("paused_actions", state.PausedActions).
+			Int("muted_validators", len(state.Mutes)).
+			Msg("Controls are restricting actions")
+	}
+
+	return s, nil
+}
+
+// SetLogLevel changes the log level of the running service.
+func (s *Service) SetLogLevel(level zerolog.Level) {
+	s.logLevel.SetLevel(level)
+	s.log.Info().Str("log_level", level.String()).Msg("Reconfigured")
+}
+
+// Blocked returns a description of why the action should not run for the given
+// validators, or an empty string if it can run.  An action is blocked if any of
+// the validators are muted.
+func (s *Service) Blocked(action string, indices ...phase0.ValidatorIndex) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state := s.data.State
+	switch {
+	case state.Disarmed:
+		return "disarmed"
+	case state.Paused:
+		return "all actions paused"
+	case contains(state.PausedActions, action):
+		return fmt.Sprintf("action %s paused", action)
+	}
+	for _, index := range indices {
+		if until, exists := state.Mutes[index]; exists && time.Now().Before(until) {
+			return fmt.Sprintf("validator %d muted until %s", index, until.Format(time.RFC3339))
+		}
+	}
+
+	return ""
+}
+
+// State returns the current state of the controls.
+func (s *Service) State(_ context.Context) *controls.State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireMutes()
+
+	return copyState(s.data.State)
+}
+
+// Audit returns the audit log of changes to the controls, oldest first.
+func (s *Service) Audit(_ context.Context) []*controls.AuditEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]*controls.AuditEntry{}, s.data.Audit...)
+}
+
+// Pause pauses an action, or all actions if the action is empty.
+func (s *Service) Pause(_ context.Context, change *controls.Change, action string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if action == "" {
+		return s.apply(change, "pause all actions", func(state *controls.State) {
+			state.Paused = true
+		})
+	}
+
+	return s.apply(change, fmt.Sprintf("pause action %s", action), func(state *controls.State) {
+		if !contains(state.PausedActions, action) {
+			state.PausedActions = append(state.PausedActions, action)
+			sort.Strings(state.PausedActions)
+		}
+	})
+}
+
+// Resume resumes an action, or all actions if the action is empty.
+func (s *Service) Resume(_ context.Context, change *controls.Change, action string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if action == "" {
+		return s.apply(change, "resume all actions", func(state *controls.State) {
+			state.Paused = false
+			state.PausedActions = make([]string, 0)
+		})
+	}
+
+	return s.apply(change, fmt.Sprintf("resume action %s", action), func(state *controls.State) {
+		paused := make([]string, 0, len(state.PausedActions))
+		for _, pausedAction := range state.PausedActions {
+			if pausedAction != action {
+				paused = append(paused, pausedAction)
+			}
+		}
+		state.PausedActions = paused
+	})
+}
+
+// Mute stops actions running for a validator until the given time.
+func (s *Service) Mute(_ context.Context, change *controls.Change, index phase0.ValidatorIndex, until time.Time) error {
+	if !until.After(time.Now()) {
+		return errors.New("mute must end in the future")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.apply(change, fmt.Sprintf("mute validator %d until %s", index, until.UTC().Format(time.RFC3339)), func(state *controls.State) {
+		state.Mutes[index] = until.UTC()
+	})
+}
+
+// Unmute allows actions to run for a muted validator.
+func (s *Service) Unmute(_ context.Context, change *controls.Change, index phase0.ValidatorIndex) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.apply(change, fmt.Sprintf("unmute validator %d", index), func(state *controls.State) {
+		delete(state.Mutes, index)
+	})
+}
+
+// SetDisarmed sets whether esd is disarmed, in which case no actions run.
+func (s *Service) SetDisarmed(_ context.Context, change *controls.Change, disarmed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	description := "arm"
+	if disarmed {
+		description = "disarm"
+	}
+
+	return s.apply(change, description, func(state *controls.State) {
+		state.Disarmed = disarmed
+	})
+}
+
+// apply makes a change to a copy of the state and records it in the audit log.  The
+// copy is saved before it replaces the state, so a change that cannot be saved has no
+// effect.  It must be called with the lock held.
+func (s *Service) apply(change *controls.Change, description string, update func(state *controls.State)) error {
+	s.expireMutes()
+	state := copyState(s.data.State)
+	update(state)
+	entry := &controls.AuditEntry{
+		Time:   time.Now().UTC(),
+		Actor:  change.Actor,
+		Change: description,
+		Reason: change.Reason,
+	}
+	data := &fileData{
+		State: state,
+		Audit: append(append(make([]*controls.AuditEntry, 0, len(s.data.Audit)+1), s.data.Audit...), entry),
+	}
+	if err := s.save(data); err != nil {
+		return err
+	}
+	s.data = data
+
+	s.log.Info().
+		Str("actor", entry.Actor).
+		Str("change", entry.Change).
+		Str("reason", entry.Reason).
+		Msg("Controls changed")
+
+	return nil
+}
+
+// copyState returns a copy of the state that shares no memory with it.
+func copyState(state *controls.State) *controls.State {
+	res := &controls.State{
+		Disarmed:      state.Disarmed,
+		Paused:        state.Paused,
+		PausedActions: append([]string{}, state.PausedActions...),
+		Mutes:         make(map[phase0.ValidatorIndex]time.Time, len(state.Mutes)),
+	}
+	for index, until := range state.Mutes {
+		res.Mutes[index] = until
+	}
+
+	return res
+}
+
+// expireMutes removes mutes that have ended.  It must be called with the lock held.
+func (s *Service) expireMutes() {
+	now := time.Now()
+	for index, until := range s.data.State.Mutes {
+		if !now.Before(until) {
+			delete(s.data.State.Mutes, index)
+		}
+	}
+}
+
+// save writes the controls to disk.
+func (s *Service) save(contents *fileData) error {
+	data, err := json.MarshalIndent(contents, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal controls")
+	}
+
+	if err := util.WriteFileAtomic(s.path, data, 0o600); err != nil {
+		return errors.Wrap(err, "failed to write controls file")
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
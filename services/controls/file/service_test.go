@@ -0,0 +1,68 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/attestantio/esd/services/controls"
+	"github.com/attestantio/esd/services/controls/file"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangesPersist(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "controls.json")
+	change := &controls.Change{Actor: "test", Reason: "testing"}
+
+	s, err := file.New(ctx, file.WithPath(path))
+	require.NoError(t, err)
+	require.NoError(t, s.Pause(ctx, change, "batch-script"))
+	require.NoError(t, s.Mute(ctx, change, 5, time.Now().Add(time.Hour)))
+	require.NoError(t, s.SetDisarmed(ctx, change, true))
+
+	reloaded, err := file.New(ctx, file.WithPath(path))
+	require.NoError(t, err)
+	require.Equal(t, s.State(ctx), reloaded.State(ctx))
+	require.Len(t, reloaded.Audit(ctx), 3)
+	require.Equal(t, "disarmed", reloaded.Blocked("batch-script", 5))
+}
+
+func TestFailedSaveLeavesStateUnchanged(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "controls")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	change := &controls.Change{Actor: "test"}
+
+	s, err := file.New(ctx, file.WithPath(filepath.Join(dir, "controls.json")))
+	require.NoError(t, err)
+	require.NoError(t, s.Pause(ctx, change, "batch-script"))
+	state := s.State(ctx)
+
+	// Remove the directory, so that the file cannot be saved.
+	require.NoError(t, os.RemoveAll(dir))
+
+	require.Error(t, s.SetDisarmed(ctx, change, true))
+	require.Error(t, s.Pause(ctx, change, ""))
+	require.Error(t, s.Resume(ctx, change, "batch-script"))
+	require.Error(t, s.Mute(ctx, change, 5, time.Now().Add(time.Hour)))
+	require.Equal(t, state, s.State(ctx))
+	require.Len(t, s.Audit(ctx), 1)
+	require.Equal(t, "action batch-script paused", s.Blocked("batch-script"))
+	require.Equal(t, "", s.Blocked("attester-slashed-script", 5))
+}
@@ -0,0 +1,85 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package controls holds the operator controls that stop actions from running
+// without stopping detection.
+package controls
+
+import (
+	"context"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// State is the current state of the controls.
+type State struct {
+	// Disarmed is true if no actions run, and slashings are only notified.
+	Disarmed bool `json:"disarmed"`
+	// Paused is true if all actions are paused.
+	Paused bool `json:"paused"`
+	// PausedActions are the individual actions that are paused.
+	PausedActions []string `json:"paused_actions"`
+	// Mutes are the validators for which actions do not run, with the time until which
+	// they are muted.
+	Mutes map[phase0.ValidatorIndex]time.Time `json:"mutes"`
+}
+
+// AuditEntry is a record of a change to the controls.
+type AuditEntry struct {
+	// Time is the time of the change.
+	Time time.Time `json:"time"`
+	// Actor is the operator that made the change.
+	Actor string `json:"actor"`
+	// Change describes the change.
+	Change string `json:"change"`
+	// Reason is the reason given for the change, if any.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Change is a change to the controls, made by an operator.
+type Change struct {
+	// Actor is the operator making the change.
+	Actor string
+	// Reason is the reason for the change, if supplied.
+	Reason string
+}
+
+// Service is the controls service.
+type Service interface {
+	// Blocked returns a description of why the action should not run for the given
+	// validators, or an empty string if it can run.  An action is blocked if any of
+	// the validators are muted.
+	Blocked(action string, indices ...phase0.ValidatorIndex) string
+
+	// State returns the current state of the controls.
+	State(ctx context.Context) *State
+
+	// Audit returns the audit log of changes to the controls, oldest first.
+	Audit(ctx context.Context) []*AuditEntry
+
+	// Pause pauses an action, or all actions if the action is empty.
+	Pause(ctx context.Context, change *Change, action string) error
+
+	// Resume resumes an action, or all actions if the action is empty.
+	Resume(ctx context.Context, change *Change, action string) error
+
+	// Mute stops actions running for a validator until the given time.
+	Mute(ctx context.Context, change *Change, index phase0.ValidatorIndex, until time.Time) error
+
+	// Unmute allows actions to run for a muted validator.
+	Unmute(ctx context.Context, change *Change, index phase0.ValidatorIndex) error
+
+	// SetDisarmed sets whether esd is disarmed, in which case no actions run.
+	SetDisarmed(ctx context.Context, change *Change, disarmed bool) error
+}
@@ -0,0 +1,140 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/attestantio/esd/services/deadletter"
+	"github.com/attestantio/esd/util"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+)
+
+// Service is a dead-letter service that stores its entries in a JSON file.
+type Service struct {
+	log     zerolog.Logger
+	path    string
+	entries map[string]*deadletter.Entry
+	mu      sync.Mutex
+}
+
+// New creates a new file-backed dead-letter service.
+func New(_ context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.
+	log := zerologger.With().Str("service", "deadletter").Str("impl", "file").Logger()
+	if parameters.logLevel != log.GetLevel() {
+		log = log.Level(parameters.logLevel)
+	}
+
+	s := &Service{
+		log:     log,
+		path:    parameters.path,
+		entries: make(map[string]*deadletter.Entry),
+	}
+
+	data, err := os.ReadFile(parameters.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		log.Trace().Str("path", parameters.path).Msg("No existing dead-letter file")
+	case err != nil:
+		return nil, errors.Wrap(err, "failed to read dead-letter file")
+	default:
+		entries := make([]*deadletter.Entry, 0)
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, errors.Wrap(err, "failed to parse dead-letter file")
+		}
+		for _, entry := range entries {
+			s.entries[entry.BlockID] = entry
+		}
+		log.Trace().Int("entries", len(entries)).Msg("Loaded dead-letter entries")
+	}
+
+	return s, nil
+}
+
+// Add adds or updates an entry.
+func (s *Service) Add(_ context.Context, entry *deadletter.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, exists := s.entries[entry.BlockID]; exists {
+		entry.Attempts += existing.Attempts
+		entry.FirstFailure = existing.FirstFailure
+	}
+	s.entries[entry.BlockID] = entry
+
+	return s.save()
+}
+
+// Remove removes the entry for the given block.
+func (s *Service) Remove(_ context.Context, blockID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[blockID]; !exists {
+		return nil
+	}
+	delete(s.entries, blockID)
+
+	return s.save()
+}
+
+// Entries returns all entries, ordered by slot.
+func (s *Service) Entries(_ context.Context) ([]*deadletter.Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sortedEntries(), nil
+}
+
+func (s *Service) sortedEntries() []*deadletter.Entry {
+	entries := make([]*deadletter.Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Slot != entries[j].Slot {
+			return entries[i].Slot < entries[j].Slot
+		}
+
+		return entries[i].BlockID < entries[j].BlockID
+	})
+
+	return entries
+}
+
+// save writes the entries to disk.  It must be called with the lock held.
+func (s *Service) save() error {
+	data, err := json.MarshalIndent(s.sortedEntries(), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal entries")
+	}
+
+	if err := util.WriteFileAtomic(s.path, data, 0o600); err != nil {
+		return errors.Wrap(err, "failed to write dead-letter file")
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/attestantio/esd/services/deadletter"
+	"github.com/attestantio/esd/services/deadletter/file"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "failed-blocks.json")
+
+	_, err := file.New(ctx)
+	require.EqualError(t, err, "problem with parameters: no path specified")
+
+	// A missing file has no entries.
+	s, err := file.New(ctx, file.WithPath(path))
+	require.NoError(t, err)
+	entries, err := s.Entries(ctx)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	firstFailure := time.Unix(1700000000, 0).UTC()
+	require.NoError(t, s.Add(ctx, &deadletter.Entry{
+		BlockID:      "200",
+		Slot:         200,
+		Attempts:     6,
+		FirstFailure: firstFailure,
+		LastFailure:  firstFailure.Add(time.Minute),
+		Error:        "not found",
+	}))
+	require.NoError(t, s.Add(ctx, &deadletter.Entry{
+		BlockID:      "0x01",
+		Slot:         100,
+		Attempts:     6,
+		FirstFailure: firstFailure,
+		LastFailure:  firstFailure.Add(time.Minute),
+		Error:        "timeout",
+	}))
+
+	// Adding an existing block adds to its attempts and keeps its first failure.
+	require.NoError(t, s.Add(ctx, &deadletter.Entry{
+		BlockID:     "200",
+		Slot:        200,
+		Attempts:    1,
+		LastFailure: firstFailure.Add(time.Hour),
+		Error:       "still not found",
+	}))
+
+	entries, err = s.Entries(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "0x01", entries[0].BlockID)
+	require.Equal(t, "200", entries[1].BlockID)
+	require.Equal(t, 7, entries[1].Attempts)
+	require.Equal(t, firstFailure, entries[1].FirstFailure)
+	require.Equal(t, firstFailure.Add(time.Hour), entries[1].LastFailure)
+	require.Equal(t, "still not found", entries[1].Error)
+
+	// Entries persist across restarts.
+	s, err = file.New(ctx, file.WithPath(path))
+	require.NoError(t, err)
+	reloaded, err := s.Entries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, entries, reloaded)
+
+	// Removing an unknown block is not an error.
+	require.NoError(t, s.Remove(ctx, "300"))
+	require.NoError(t, s.Remove(ctx, "0x01"))
+	entries, err = s.Entries(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "200", entries[0].BlockID)
+
+	s, err = file.New(ctx, file.WithPath(path))
+	require.NoError(t, err)
+	entries, err = s.Entries(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestServiceBadFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "failed-blocks.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+
+	_, err := file.New(ctx, file.WithPath(path))
+	require.ErrorContains(t, err, "failed to parse dead-letter file")
+}
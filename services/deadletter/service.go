@@ -0,0 +1,50 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package deadletter records blocks that could not be processed.
+package deadletter
+
+import (
+	"context"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// Entry is a block that could not be processed.
+type Entry struct {
+	// BlockID is the identifier of the block, either a root or a slot.
+	BlockID string `json:"block_id"`
+	// Slot is the slot of the block.
+	Slot phase0.Slot `json:"slot"`
+	// Attempts is the number of attempts made to fetch the block.
+	Attempts int `json:"attempts"`
+	// FirstFailure is the time of the first failure to fetch the block.
+	FirstFailure time.Time `json:"first_failure"`
+	// LastFailure is the time of the most recent failure to fetch the block.
+	LastFailure time.Time `json:"last_failure"`
+	// Error is the most recent error obtained when fetching the block.
+	Error string `json:"error"`
+}
+
+// Service is the dead-letter service.
+type Service interface {
+	// Add adds or updates an entry.
+	Add(ctx context.Context, entry *Entry) error
+
+	// Remove removes the entry for the given block.
+	Remove(ctx context.Context, blockID string) error
+
+	// Entries returns all entries, ordered by slot.
+	Entries(ctx context.Context) ([]*Entry, error)
+}
@@ -0,0 +1,46 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package health reports the readiness and liveness of esd.
+package health
+
+import (
+	"context"
+)
+
+// Check is the result of a single health check.
+type Check struct {
+	// Name is the name of the check.
+	Name string `json:"name"`
+	// OK is true if the check passed.
+	OK bool `json:"ok"`
+	// Detail explains the result of the check.
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the result of a set of health checks.
+type Report struct {
+	// OK is true if all of the checks passed.
+	OK bool `json:"ok"`
+	// Checks are the results of the individual checks.
+	Checks []*Check `json:"checks"`
+}
+
+// Service is the health service.
+type Service interface {
+	// Readiness reports if esd is able to detect slashings as they happen.
+	Readiness(ctx context.Context) *Report
+
+	// Liveness reports if esd is making progress, so does not need to be restarted.
+	Liveness(ctx context.Context) *Report
+}
@@ -0,0 +1,83 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"errors"
+	"time"
+
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/rs/zerolog"
+)
+
+type parameters struct {
+	logLevel        zerolog.Level
+	status          slashings.StatusProvider
+	livenessTimeout time.Duration
+}
+
+// Parameter is the interface for service parameters.
+type Parameter interface {
+	apply(p *parameters)
+}
+
+type parameterFunc func(*parameters)
+
+func (f parameterFunc) apply(p *parameters) {
+	f(p)
+}
+
+// WithLogLevel sets the log level for the module.
+func WithLogLevel(logLevel zerolog.Level) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.logLevel = logLevel
+	})
+}
+
+// WithStatusProvider sets the provider of the status of the slashings service.
+func WithStatusProvider(provider slashings.StatusProvider) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.status = provider
+	})
+}
+
+// WithLivenessTimeout sets the time for which the processing pipeline can hold
+// blocks without handling any before it is considered to have stalled.
+func WithLivenessTimeout(timeout time.Duration) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.livenessTimeout = timeout
+	})
+}
+
+// parseAndCheckParameters parses and checks parameters to ensure that mandatory parameters are present and correct.
+func parseAndCheckParameters(params ...Parameter) (*parameters, error) {
+	parameters := parameters{
+		logLevel:        zerolog.GlobalLevel(),
+		livenessTimeout: 5 * time.Minute,
+	}
+	for _, p := range params {
+		if params != nil {
+			p.apply(&parameters)
+		}
+	}
+
+	if parameters.status == nil {
+		return nil, errors.New("no status provider specified")
+	}
+	if parameters.livenessTimeout <= 0 {
+		return nil, errors.New("liveness timeout must be greater than 0")
+	}
+
+	return &parameters, nil
+}
@@ -0,0 +1,164 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package standard provides health checks based on the status of the slashings service.
+package standard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/attestantio/esd/services/health"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/esd/util"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+)
+
+// Service provides health checks based on the status of the slashings service.
+type Service struct {
+	log             zerolog.Logger
+	logLevel        *util.LevelFilter
+	status          slashings.StatusProvider
+	livenessTimeout time.Duration
+}
+
+// New creates a new health service.
+func New(_ context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.  The level is applied by a filter so that it can be reconfigured.
+	logLevel := util.NewLevelFilter(parameters.logLevel)
+	log := zerologger.With().Str("service", "health").Str("impl", "standard").Logger().Level(zerolog.TraceLevel).Hook(logLevel)
+
+	return &Service{
+		log:             log,
+		logLevel:        logLevel,
+		status:          parameters.status,
+		livenessTimeout: parameters.livenessTimeout,
+	}, nil
+}
+
+// SetLogLevel changes the log level of the running service.
+func (s *Service) SetLogLevel(level zerolog.Level) {
+	s.logLevel.SetLevel(level)
+	s.log.Info().Str("log_level", level.String()).Msg("Reconfigured")
+}
+
+// Readiness reports if esd is able to detect slashings as they happen: its beacon
+// node is reachable and synced, head events are arriving, and any backfill is complete.
+func (s *Service) Readiness(ctx context.Context) *health.Report {
+	status := s.status.Status(ctx)
+
+	checks := []*health.Check{
+		{
+			Name:   "beacon_node",
+			OK:     status.Connected,
+			Detail: detail(status.Connected, "connected", "no response to the last status check"),
+		},
+		syncCheck(status),
+		{
+			Name: "event_stream",
+			OK:   !status.StreamStale,
+			Detail: detail(!status.StreamStale,
+				fmt.Sprintf("last head event at slot %d", status.LastEventSlot),
+				fmt.Sprintf("no head events since slot %d", status.LastEventSlot)),
+		},
+		{
+			Name: "backfill",
+			OK:   !status.Backfilling,
+			Detail: detail(!status.Backfilling,
+				"complete",
+				fmt.Sprintf("in progress with %d blocks pending", status.PendingBlocks)),
+		},
+	}
+
+	return s.report("readiness", checks)
+}
+
+// Liveness reports if the processing pipeline is making progress.  A pipeline with no
+// blocks to process is live, even if its beacon node is not sending head events, as
+// restarting would not help.
+func (s *Service) Liveness(ctx context.Context) *health.Report {
+	status := s.status.Status(ctx)
+
+	sinceProgress := time.Since(status.LastProgress).Truncate(time.Second)
+	stalled := status.PendingBlocks > 0 && sinceProgress > s.livenessTimeout
+	checks := []*health.Check{
+		{
+			Name: "pipeline",
+			OK:   !stalled,
+			Detail: detail(!stalled,
+				fmt.Sprintf("%d blocks pending; last processed slot %d", status.PendingBlocks, status.LastProcessedSlot),
+				fmt.Sprintf("%d blocks pending but none handled for %s", status.PendingBlocks, sinceProgress)),
+		},
+	}
+
+	return s.report("liveness", checks)
+}
+
+// syncCheck checks that the beacon node can be trusted to provide valid blocks.
+func syncCheck(status *slashings.Status) *health.Check {
+	problems := make([]string, 0)
+	if status.Syncing {
+		problems = append(problems, "syncing")
+	}
+	if status.Optimistic {
+		problems = append(problems, "optimistic")
+	}
+	if status.ELOffline != nil && *status.ELOffline {
+		problems = append(problems, "execution client offline")
+	}
+
+	synced := fmt.Sprintf("synced to slot %d", status.HeadSlot)
+	if status.ELOffline == nil {
+		synced += "; execution client status unknown"
+	}
+
+	return &health.Check{
+		Name:   "sync",
+		OK:     len(problems) == 0,
+		Detail: detail(len(problems) == 0, synced, strings.Join(problems, ", ")),
+	}
+}
+
+// report builds a report from the checks, logging any that failed.
+func (s *Service) report(kind string, checks []*health.Check) *health.Report {
+	report := &health.Report{
+		OK:     true,
+		Checks: checks,
+	}
+	for _, check := range checks {
+		if !check.OK {
+			report.OK = false
+			s.log.Debug().Str("kind", kind).Str("check", check.Name).Str("detail", check.Detail).Msg("Health check failed")
+		}
+	}
+
+	return report
+}
+
+// detail returns the detail for a check depending on its result.
+func detail(ok bool, okDetail string, failedDetail string) string {
+	if ok {
+		return okDetail
+	}
+
+	return failedDetail
+}
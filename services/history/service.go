@@ -0,0 +1,128 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package history stores the history of detected slashings.
+package history
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// ErrNotFound is returned when a requested slashing does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ActionResult is the outcome of running an action for a slashing.
+type ActionResult struct {
+	// Action is the name of the action.
+	Action string `json:"action"`
+	// Started is the time at which the action started.
+	Started time.Time `json:"started"`
+	// Duration is the time taken to run the action.
+	Duration time.Duration `json:"duration"`
+	// Success is true if the action succeeded.
+	Success bool `json:"success"`
+	// Output is the output of the action.
+	Output string `json:"output,omitempty"`
+	// Error is the error returned by the action, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Slashing is the record of a slashing event.
+type Slashing struct {
+	slashings.SlashingEvent
+	// FirstSeen is the time at which the slashing was first seen.
+	FirstSeen time.Time `json:"first_seen"`
+	// Actions are the results of the actions run for the slashing.
+	Actions []*ActionResult `json:"actions"`
+}
+
+// ID returns the unique identifier of the slashing.
+func (s *Slashing) ID() string {
+	return SlashingID(s.Slot, s.BlockRoot, s.ValidatorIndex)
+}
+
+// SlashingID returns the unique identifier of the slashing of a validator in a block.
+func SlashingID(slot phase0.Slot, root phase0.Root, index phase0.ValidatorIndex) string {
+	return fmt.Sprintf("%d-%x-%d", slot, root[:], index)
+}
+
+// ParseID parses a slashing identifier into its components.
+func ParseID(id string) (phase0.Slot, phase0.Root, phase0.ValidatorIndex, error) {
+	parts := strings.Split(id, "-")
+	if len(parts) != 3 {
+		return 0, phase0.Root{}, 0, errors.New("invalid slashing ID")
+	}
+
+	slot, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, phase0.Root{}, 0, errors.New("invalid slot in slashing ID")
+	}
+	rootBytes, err := hex.DecodeString(parts[1])
+	if err != nil || len(rootBytes) != phase0.RootLength {
+		return 0, phase0.Root{}, 0, errors.New("invalid block root in slashing ID")
+	}
+	var root phase0.Root
+	copy(root[:], rootBytes)
+	index, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return 0, phase0.Root{}, 0, errors.New("invalid validator index in slashing ID")
+	}
+
+	return phase0.Slot(slot), root, phase0.ValidatorIndex(index), nil
+}
+
+// Filter selects slashings.
+type Filter struct {
+	// ValidatorIndices restricts results to the given validators, if supplied.
+	ValidatorIndices []phase0.ValidatorIndex
+	// Type restricts results to slashings with an offence of the given type, if supplied.
+	Type slashings.Type
+	// Kind restricts results to slashings with an offence of the given kind, if supplied.
+	Kind slashings.OffenceKind
+	// Status restricts results to the given status, if supplied.
+	Status slashings.FinalityStatus
+	// FromSlot restricts results to slashings included at or after this slot, if supplied.
+	FromSlot *phase0.Slot
+	// ToSlot restricts results to slashings included at or before this slot, if supplied.
+	ToSlot *phase0.Slot
+	// Limit is the maximum number of results to return; 0 for no limit.
+	Limit int
+}
+
+// Service is the slashing history service.
+type Service interface {
+	// SetSlashing stores a slashing, replacing any existing record for the same slashing.
+	SetSlashing(ctx context.Context, slashing *Slashing) error
+
+	// Slashing fetches a single slashing by its ID.
+	// Returns ErrNotFound if the slashing does not exist.
+	Slashing(ctx context.Context, id string) (*Slashing, error)
+
+	// Slashings fetches slashings matching the filter, most recent first.
+	Slashings(ctx context.Context, filter *Filter) ([]*Slashing, error)
+
+	// SetStatus sets the finality status of a slashing.
+	SetStatus(ctx context.Context, id string, status slashings.FinalityStatus) error
+
+	// AddActionResult adds the result of an action to a slashing.
+	AddActionResult(ctx context.Context, id string, result *ActionResult) error
+}
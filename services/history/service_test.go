@@ -0,0 +1,104 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history_test
+
+import (
+	"testing"
+
+	"github.com/attestantio/esd/services/history"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseID(t *testing.T) {
+	root := phase0.Root{0x01, 0x02, 0x03}
+
+	tests := []struct {
+		name  string
+		id    string
+		slot  phase0.Slot
+		root  phase0.Root
+		index phase0.ValidatorIndex
+		err   string
+	}{
+		{
+			name:  "Good",
+			id:    history.SlashingID(123, root, 456),
+			slot:  123,
+			root:  root,
+			index: 456,
+		},
+		{
+			name:  "Zero",
+			id:    history.SlashingID(0, phase0.Root{}, 0),
+			slot:  0,
+			root:  phase0.Root{},
+			index: 0,
+		},
+		{
+			name: "Empty",
+			id:   "",
+			err:  "invalid slashing ID",
+		},
+		{
+			name: "TooFewParts",
+			id:   "123-0102",
+			err:  "invalid slashing ID",
+		},
+		{
+			name: "TooManyParts",
+			id:   history.SlashingID(123, root, 456) + "-7",
+			err:  "invalid slashing ID",
+		},
+		{
+			name: "SlotInvalid",
+			id:   "abc-0000000000000000000000000000000000000000000000000000000000000000-456",
+			err:  "invalid slot in slashing ID",
+		},
+		{
+			name: "RootPrefixed",
+			id:   "123-0x00000000000000000000000000000000000000000000000000000000000000-456",
+			err:  "invalid block root in slashing ID",
+		},
+		{
+			name: "RootShort",
+			id:   "123-0102-456",
+			err:  "invalid block root in slashing ID",
+		},
+		{
+			name: "IndexInvalid",
+			id:   "123-0000000000000000000000000000000000000000000000000000000000000000--1",
+			err:  "invalid slashing ID",
+		},
+		{
+			name: "IndexNotNumeric",
+			id:   "123-0000000000000000000000000000000000000000000000000000000000000000-abc",
+			err:  "invalid validator index in slashing ID",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			slot, root, index, err := history.ParseID(test.id)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.slot, slot)
+			require.Equal(t, test.root, root)
+			require.Equal(t, test.index, index)
+		})
+	}
+}
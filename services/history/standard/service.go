@@ -0,0 +1,101 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"time"
+
+	"github.com/attestantio/esd/util"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+var slashingsBucket = []byte("slashings")
+
+// ErrLocked is returned if the database is held by another process, such as a running esd.
+var ErrLocked = errors.New("history database is locked by another process")
+
+// Service is a slashing history service backed by an embedded database.
+type Service struct {
+	log      zerolog.Logger
+	logLevel *util.LevelFilter
+	db       *bolt.DB
+}
+
+// New creates a new slashing history service.
+func New(ctx context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.  The level is applied by a filter so that it can be reconfigured.
+	logLevel := util.NewLevelFilter(parameters.logLevel)
+	log := zerologger.With().Str("service", "history").Str("impl", "standard").Logger().Level(zerolog.TraceLevel).Hook(logLevel)
+
+	db, err := bolt.Open(parameters.path, 0o600, &bolt.Options{
+		// Fail rather than wait if another process holds the database.
+		Timeout:  time.Second,
+		ReadOnly: parameters.readOnly,
+	})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, ErrLocked
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open history database")
+	}
+
+	if parameters.readOnly {
+		err = db.View(func(tx *bolt.Tx) error {
+			if tx.Bucket(slashingsBucket) == nil {
+				return errors.New("history database is not initialised")
+			}
+
+			return nil
+		})
+	} else {
+		err = db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(slashingsBucket)
+			return err
+		})
+	}
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "failed to initialise history database")
+	}
+
+	go func(ctx context.Context, db *bolt.DB) {
+		<-ctx.Done()
+		if err := db.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed to close history database")
+		}
+	}(ctx, db)
+
+	log.Trace().Str("path", parameters.path).Msg("Opened history database")
+
+	return &Service{
+		log:      log,
+		logLevel: logLevel,
+		db:       db,
+	}, nil
+}
+
+// SetLogLevel changes the log level of the running service.
+func (s *Service) SetLogLevel(level zerolog.Level) {
+	s.logLevel.SetLevel(level)
+	s.log.Info().Str("log_level", level.String()).Msg("Reconfigured")
+}
@@ -0,0 +1,190 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/attestantio/esd/services/history"
+	"github.com/attestantio/esd/services/history/standard"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/stretchr/testify/require"
+)
+
+func testSlashing(slot phase0.Slot, index phase0.ValidatorIndex, kind slashings.OffenceKind) *history.Slashing {
+	return &history.Slashing{
+		SlashingEvent: slashings.SlashingEvent{
+			ValidatorIndex: index,
+			Offences: []*slashings.Offence{
+				{
+					Kind:         kind,
+					EvidenceHash: phase0.Root{byte(index)},
+				},
+			},
+			Slot:      slot,
+			BlockRoot: phase0.Root{byte(slot)},
+			Source:    slashings.SourceHead,
+			Status:    slashings.StatusPending,
+		},
+		FirstSeen: time.Unix(1700000000, 0).UTC(),
+	}
+}
+
+func TestService(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "history.db")
+
+	_, err := standard.New(ctx)
+	require.EqualError(t, err, "problem with parameters: no path specified")
+
+	_, err = standard.New(ctx, standard.WithPath(path), standard.WithReadOnly(true))
+	require.Error(t, err)
+
+	s, err := standard.New(ctx, standard.WithPath(path))
+	require.NoError(t, err)
+
+	// A second process cannot open the database while it is held.
+	_, err = standard.New(ctx, standard.WithPath(path), standard.WithReadOnly(true))
+	require.ErrorIs(t, err, standard.ErrLocked)
+
+	slashing1 := testSlashing(100, 1, slashings.OffenceDoubleVote)
+	slashing2 := testSlashing(200, 2, slashings.OffenceSurroundVote)
+	slashing3 := testSlashing(300, 1, slashings.OffenceDoubleProposal)
+	for _, slashing := range []*history.Slashing{slashing2, slashing3, slashing1} {
+		require.NoError(t, s.SetSlashing(ctx, slashing))
+	}
+
+	// Round trip.
+	res, err := s.Slashing(ctx, slashing2.ID())
+	require.NoError(t, err)
+	require.Equal(t, slashing2, res)
+
+	_, err = s.Slashing(ctx, history.SlashingID(999, phase0.Root{}, 1))
+	require.ErrorIs(t, err, history.ErrNotFound)
+	_, err = s.Slashing(ctx, "bad")
+	require.Error(t, err)
+
+	// Updates.
+	require.NoError(t, s.SetStatus(ctx, slashing1.ID(), slashings.StatusFinalized))
+	result := &history.ActionResult{
+		Action:  "attester-slashed-script",
+		Started: time.Unix(1700000012, 0).UTC(),
+		Success: true,
+	}
+	require.NoError(t, s.AddActionResult(ctx, slashing1.ID(), result))
+	res, err = s.Slashing(ctx, slashing1.ID())
+	require.NoError(t, err)
+	require.Equal(t, slashings.StatusFinalized, res.Status)
+	require.Equal(t, []*history.ActionResult{result}, res.Actions)
+	require.ErrorIs(t, s.SetStatus(ctx, history.SlashingID(999, phase0.Root{}, 1), slashings.StatusFinalized), history.ErrNotFound)
+}
+
+func TestSlashings(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, err := standard.New(ctx, standard.WithPath(filepath.Join(t.TempDir(), "history.db")))
+	require.NoError(t, err)
+
+	slashing1 := testSlashing(100, 1, slashings.OffenceDoubleVote)
+	slashing2 := testSlashing(200, 2, slashings.OffenceSurroundVote)
+	slashing3 := testSlashing(300, 1, slashings.OffenceDoubleProposal)
+	slashing3.Status = slashings.StatusFinalized
+	for _, slashing := range []*history.Slashing{slashing2, slashing3, slashing1} {
+		require.NoError(t, s.SetSlashing(ctx, slashing))
+	}
+
+	slot := func(slot phase0.Slot) *phase0.Slot {
+		return &slot
+	}
+
+	tests := []struct {
+		name     string
+		filter   *history.Filter
+		expected []*history.Slashing
+	}{
+		{
+			name:     "Nil",
+			expected: []*history.Slashing{slashing3, slashing2, slashing1},
+		},
+		{
+			name:     "Empty",
+			filter:   &history.Filter{},
+			expected: []*history.Slashing{slashing3, slashing2, slashing1},
+		},
+		{
+			name:     "Validator",
+			filter:   &history.Filter{ValidatorIndices: []phase0.ValidatorIndex{1}},
+			expected: []*history.Slashing{slashing3, slashing1},
+		},
+		{
+			name:     "Type",
+			filter:   &history.Filter{Type: slashings.TypeAttester},
+			expected: []*history.Slashing{slashing2, slashing1},
+		},
+		{
+			name:     "Kind",
+			filter:   &history.Filter{Kind: slashings.OffenceSurroundVote},
+			expected: []*history.Slashing{slashing2},
+		},
+		{
+			name:     "Status",
+			filter:   &history.Filter{Status: slashings.StatusFinalized},
+			expected: []*history.Slashing{slashing3},
+		},
+		{
+			name:     "FromSlot",
+			filter:   &history.Filter{FromSlot: slot(200)},
+			expected: []*history.Slashing{slashing3, slashing2},
+		},
+		{
+			name:     "ToSlot",
+			filter:   &history.Filter{ToSlot: slot(200)},
+			expected: []*history.Slashing{slashing2, slashing1},
+		},
+		{
+			name:     "ToSlotMax",
+			filter:   &history.Filter{ToSlot: slot(^phase0.Slot(0))},
+			expected: []*history.Slashing{slashing3, slashing2, slashing1},
+		},
+		{
+			name:     "SlotRange",
+			filter:   &history.Filter{FromSlot: slot(150), ToSlot: slot(250)},
+			expected: []*history.Slashing{slashing2},
+		},
+		{
+			name:     "EmptyRange",
+			filter:   &history.Filter{FromSlot: slot(101), ToSlot: slot(199)},
+			expected: []*history.Slashing{},
+		},
+		{
+			name:     "Limit",
+			filter:   &history.Filter{Limit: 2},
+			expected: []*history.Slashing{slashing3, slashing2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := s.Slashings(ctx, test.filter)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, res)
+		})
+	}
+}
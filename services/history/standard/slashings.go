@@ -0,0 +1,194 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/attestantio/esd/services/history"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// SetSlashing stores a slashing, replacing any existing record for the same slashing.
+func (s *Service) SetSlashing(_ context.Context, slashing *history.Slashing) error {
+	data, err := json.Marshal(slashing)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal slashing")
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(slashingsBucket).Put(slashingKey(slashing.Slot, slashing.BlockRoot, slashing.ValidatorIndex), data)
+	})
+}
+
+// Slashing fetches a single slashing by its ID.
+func (s *Service) Slashing(_ context.Context, id string) (*history.Slashing, error) {
+	slot, root, index, err := history.ParseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var slashing *history.Slashing
+	err = s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(slashingsBucket).Get(slashingKey(slot, root, index))
+		if data == nil {
+			return history.ErrNotFound
+		}
+		slashing = &history.Slashing{}
+
+		return json.Unmarshal(data, slashing)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return slashing, nil
+}
+
+// Slashings fetches slashings matching the filter, most recent first.
+func (s *Service) Slashings(_ context.Context, filter *history.Filter) ([]*history.Slashing, error) {
+	if filter == nil {
+		filter = &history.Filter{}
+	}
+	indices := make(map[phase0.ValidatorIndex]struct{}, len(filter.ValidatorIndices))
+	for _, index := range filter.ValidatorIndices {
+		indices[index] = struct{}{}
+	}
+
+	res := make([]*history.Slashing, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(slashingsBucket).Cursor()
+
+		// Position the cursor at the last key within the range.
+		var k, v []byte
+		if filter.ToSlot != nil && uint64(*filter.ToSlot) < ^uint64(0) {
+			k, _ = cursor.Seek(slotPrefix(*filter.ToSlot + 1))
+			if k == nil {
+				k, v = cursor.Last()
+			} else {
+				k, v = cursor.Prev()
+			}
+		} else {
+			k, v = cursor.Last()
+		}
+
+		for ; k != nil; k, v = cursor.Prev() {
+			if filter.FromSlot != nil && bytes.Compare(k[:8], slotPrefix(*filter.FromSlot)) < 0 {
+				break
+			}
+			slashing := &history.Slashing{}
+			if err := json.Unmarshal(v, slashing); err != nil {
+				return errors.Wrap(err, "failed to unmarshal slashing")
+			}
+			if !matches(slashing, filter, indices) {
+				continue
+			}
+			res = append(res, slashing)
+			if filter.Limit > 0 && len(res) == filter.Limit {
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// SetStatus sets the finality status of a slashing.
+func (s *Service) SetStatus(_ context.Context, id string, status slashings.FinalityStatus) error {
+	return s.updateSlashing(id, func(slashing *history.Slashing) {
+		slashing.Status = status
+	})
+}
+
+// AddActionResult adds the result of an action to a slashing.
+func (s *Service) AddActionResult(_ context.Context, id string, result *history.ActionResult) error {
+	return s.updateSlashing(id, func(slashing *history.Slashing) {
+		slashing.Actions = append(slashing.Actions, result)
+	})
+}
+
+// updateSlashing atomically updates a stored slashing.
+func (s *Service) updateSlashing(id string, update func(slashing *history.Slashing)) error {
+	slot, root, index, err := history.ParseID(id)
+	if err != nil {
+		return err
+	}
+	key := slashingKey(slot, root, index)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(slashingsBucket)
+		data := bucket.Get(key)
+		if data == nil {
+			return history.ErrNotFound
+		}
+		slashing := &history.Slashing{}
+		if err := json.Unmarshal(data, slashing); err != nil {
+			return errors.Wrap(err, "failed to unmarshal slashing")
+		}
+		update(slashing)
+		data, err := json.Marshal(slashing)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal slashing")
+		}
+
+		return bucket.Put(key, data)
+	})
+}
+
+func matches(slashing *history.Slashing, filter *history.Filter, indices map[phase0.ValidatorIndex]struct{}) bool {
+	if len(indices) > 0 {
+		if _, exists := indices[slashing.ValidatorIndex]; !exists {
+			return false
+		}
+	}
+	if filter.Type != "" && !slashing.HasType(filter.Type) {
+		return false
+	}
+	if filter.Kind != "" && !slashing.HasKind(filter.Kind) {
+		return false
+	}
+	if filter.Status != "" && slashing.Status != filter.Status {
+		return false
+	}
+
+	return true
+}
+
+// slashingKey returns the database key for a slashing.  Keys are ordered by slot.
+func slashingKey(slot phase0.Slot, root phase0.Root, index phase0.ValidatorIndex) []byte {
+	key := make([]byte, 8+phase0.RootLength+8)
+	binary.BigEndian.PutUint64(key[0:8], uint64(slot))
+	copy(key[8:8+phase0.RootLength], root[:])
+	binary.BigEndian.PutUint64(key[8+phase0.RootLength:], uint64(index))
+
+	return key
+}
+
+func slotPrefix(slot phase0.Slot) []byte {
+	prefix := make([]byte, 8)
+	binary.BigEndian.PutUint64(prefix, uint64(slot))
+
+	return prefix
+}
@@ -0,0 +1,84 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ledger records the actions run for slashings, so that each action
+// runs to success at most once.
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// State is the state of an action.
+type State string
+
+const (
+	// StateQueued is an action that was queued but had not started when esd stopped.
+	StateQueued State = "queued"
+	// StateStarted is an action that has started but not completed.
+	StateStarted State = "started"
+	// StateSucceeded is an action that completed successfully.
+	StateSucceeded State = "succeeded"
+	// StateFailed is an action that completed unsuccessfully.
+	StateFailed State = "failed"
+)
+
+// Key identifies an action for a slashing.
+type Key struct {
+	// ValidatorIndex is the index of the slashed validator.
+	ValidatorIndex phase0.ValidatorIndex `json:"validator_index"`
+	// Proposer is true for a proposer slashing, false for an attester slashing.
+	Proposer bool `json:"proposer"`
+	// EvidenceHash is the hash tree root of the slashing evidence.
+	EvidenceHash phase0.Root `json:"evidence_hash"`
+	// Action is the name of the action.
+	Action string `json:"action"`
+}
+
+// Entry is the record of an action.
+type Entry struct {
+	Key
+	// Slot is the slot of the block in which the slashing was found.
+	Slot phase0.Slot `json:"slot"`
+	// BlockRoot is the root of the block in which the slashing was found.
+	BlockRoot phase0.Root `json:"block_root"`
+	// State is the state of the action.
+	State State `json:"state"`
+	// Attempts is the number of times the action has been started.
+	Attempts int `json:"attempts"`
+	// Updated is the time at which the entry was last updated.
+	Updated time.Time `json:"updated"`
+	// Data is information specific to the action that is required to resume it.
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Service is the action ledger service.
+type Service interface {
+	// Begin records that an action is about to start, before it runs.
+	// Returns false if the action has already succeeded, in which case it must not run.
+	Begin(ctx context.Context, entry *Entry) (bool, error)
+
+	// Complete records the completion of an action.
+	Complete(ctx context.Context, key *Key, success bool) error
+
+	// Queue records that an action is queued to run, so that it is resumed if esd stops
+	// before it starts.  An action that has already started or succeeded is unchanged.
+	Queue(ctx context.Context, entry *Entry) error
+
+	// Unfinished returns the actions that were queued or started but did not complete.
+	Unfinished(ctx context.Context) ([]*Entry, error)
+}
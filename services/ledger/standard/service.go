@@ -0,0 +1,223 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/attestantio/esd/services/ledger"
+	"github.com/attestantio/esd/util"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+var actionsBucket = []byte("actions")
+
+// ErrLocked is returned if the database is held by another process, such as a running esd.
+var ErrLocked = errors.New("ledger database is locked by another process")
+
+// Service is an action ledger backed by an embedded database.
+type Service struct {
+	log      zerolog.Logger
+	logLevel *util.LevelFilter
+	db       *bolt.DB
+}
+
+// New creates a new action ledger service.
+func New(ctx context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.  The level is applied by a filter so that it can be reconfigured.
+	logLevel := util.NewLevelFilter(parameters.logLevel)
+	log := zerologger.With().Str("service", "ledger").Str("impl", "standard").Logger().Level(zerolog.TraceLevel).Hook(logLevel)
+
+	db, err := bolt.Open(parameters.path, 0o600, &bolt.Options{
+		// Fail rather than wait if another process holds the database.
+		Timeout: time.Second,
+	})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, ErrLocked
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open ledger database")
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(actionsBucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "failed to initialise ledger database")
+	}
+
+	go func(ctx context.Context, db *bolt.DB) {
+		<-ctx.Done()
+		if err := db.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed to close ledger database")
+		}
+	}(ctx, db)
+
+	log.Trace().Str("path", parameters.path).Msg("Opened ledger database")
+
+	return &Service{
+		log:      log,
+		logLevel: logLevel,
+		db:       db,
+	}, nil
+}
+
+// SetLogLevel changes the log level of the running service.
+func (s *Service) SetLogLevel(level zerolog.Level) {
+	s.logLevel.SetLevel(level)
+	s.log.Info().Str("log_level", level.String()).Msg("Reconfigured")
+}
+
+// Begin records that an action is about to start, before it runs.
+// Returns false if the action has already succeeded, in which case it must not run.
+func (s *Service) Begin(_ context.Context, entry *ledger.Entry) (bool, error) {
+	key := actionKey(&entry.Key)
+	begin := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(actionsBucket)
+		existing := &ledger.Entry{}
+		if data := bucket.Get(key); data != nil {
+			if err := json.Unmarshal(data, existing); err != nil {
+				return errors.Wrap(err, "failed to unmarshal entry")
+			}
+			if existing.State == ledger.StateSucceeded {
+				return nil
+			}
+		}
+
+		entry.State = ledger.StateStarted
+		entry.Attempts = existing.Attempts + 1
+		entry.Updated = time.Now()
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal entry")
+		}
+		if err := bucket.Put(key, data); err != nil {
+			return err
+		}
+		begin = true
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return begin, nil
+}
+
+// Queue records that an action is queued to run, so that it is resumed if esd stops
+// before it starts.  An action that has already started or succeeded is unchanged.
+func (s *Service) Queue(_ context.Context, entry *ledger.Entry) error {
+	key := actionKey(&entry.Key)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(actionsBucket)
+		existing := &ledger.Entry{}
+		if data := bucket.Get(key); data != nil {
+			if err := json.Unmarshal(data, existing); err != nil {
+				return errors.Wrap(err, "failed to unmarshal entry")
+			}
+			if existing.State == ledger.StateStarted || existing.State == ledger.StateSucceeded {
+				return nil
+			}
+		}
+
+		entry.State = ledger.StateQueued
+		entry.Attempts = existing.Attempts
+		entry.Updated = time.Now()
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal entry")
+		}
+
+		return bucket.Put(key, data)
+	})
+}
+
+// Complete records the completion of an action.
+func (s *Service) Complete(_ context.Context, key *ledger.Key, success bool) error {
+	dbKey := actionKey(key)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(actionsBucket)
+		data := bucket.Get(dbKey)
+		if data == nil {
+			return errors.New("action has not started")
+		}
+		entry := &ledger.Entry{}
+		if err := json.Unmarshal(data, entry); err != nil {
+			return errors.Wrap(err, "failed to unmarshal entry")
+		}
+		entry.State = ledger.StateFailed
+		if success {
+			entry.State = ledger.StateSucceeded
+		}
+		entry.Updated = time.Now()
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal entry")
+		}
+
+		return bucket.Put(dbKey, data)
+	})
+}
+
+// Unfinished returns the actions that were queued or started but did not complete.
+func (s *Service) Unfinished(_ context.Context) ([]*ledger.Entry, error) {
+	entries := make([]*ledger.Entry, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(actionsBucket).ForEach(func(_ []byte, data []byte) error {
+			entry := &ledger.Entry{}
+			if err := json.Unmarshal(data, entry); err != nil {
+				return errors.Wrap(err, "failed to unmarshal entry")
+			}
+			if entry.State == ledger.StateQueued || entry.State == ledger.StateStarted {
+				entries = append(entries, entry)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// actionKey returns the database key for an action.
+func actionKey(key *ledger.Key) []byte {
+	dbKey := make([]byte, 8+1+phase0.RootLength+len(key.Action))
+	binary.BigEndian.PutUint64(dbKey[0:8], uint64(key.ValidatorIndex))
+	if key.Proposer {
+		dbKey[8] = 1
+	}
+	copy(dbKey[9:9+phase0.RootLength], key.EvidenceHash[:])
+	copy(dbKey[9+phase0.RootLength:], key.Action)
+
+	return dbKey
+}
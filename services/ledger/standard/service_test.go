@@ -0,0 +1,161 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard_test
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/attestantio/esd/services/ledger"
+	"github.com/attestantio/esd/services/ledger/standard"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/stretchr/testify/require"
+)
+
+func testEntry(index phase0.ValidatorIndex, action string) *ledger.Entry {
+	return &ledger.Entry{
+		Key: ledger.Key{
+			ValidatorIndex: index,
+			EvidenceHash:   phase0.Root{0x01},
+			Action:         action,
+		},
+		Slot:      100,
+		BlockRoot: phase0.Root{0x02},
+	}
+}
+
+func TestBeginComplete(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := standard.New(ctx)
+	require.EqualError(t, err, "problem with parameters: no path specified")
+
+	path := filepath.Join(t.TempDir(), "ledger.db")
+	s, err := standard.New(ctx, standard.WithPath(path))
+	require.NoError(t, err)
+
+	// A second process cannot open the database while it is held.
+	_, err = standard.New(ctx, standard.WithPath(path))
+	require.ErrorIs(t, err, standard.ErrLocked)
+
+	entry := testEntry(1, "attester-slashed-script")
+
+	// Cannot complete an action that has not started.
+	require.Error(t, s.Complete(ctx, &entry.Key, true))
+
+	// First attempt fails.
+	begin, err := s.Begin(ctx, testEntry(1, "attester-slashed-script"))
+	require.NoError(t, err)
+	require.True(t, begin)
+	require.NoError(t, s.Complete(ctx, &entry.Key, false))
+
+	// A failed action can be run again.
+	begin, err = s.Begin(ctx, testEntry(1, "attester-slashed-script"))
+	require.NoError(t, err)
+	require.True(t, begin)
+	require.NoError(t, s.Complete(ctx, &entry.Key, true))
+
+	// A succeeded action cannot.
+	begin, err = s.Begin(ctx, testEntry(1, "attester-slashed-script"))
+	require.NoError(t, err)
+	require.False(t, begin)
+
+	// The same slashing with a different action or validator is separate.
+	begin, err = s.Begin(ctx, testEntry(1, "batch-script"))
+	require.NoError(t, err)
+	require.True(t, begin)
+	proposer := testEntry(1, "attester-slashed-script")
+	proposer.Proposer = true
+	begin, err = s.Begin(ctx, proposer)
+	require.NoError(t, err)
+	require.True(t, begin)
+	begin, err = s.Begin(ctx, testEntry(2, "attester-slashed-script"))
+	require.NoError(t, err)
+	require.True(t, begin)
+
+	// Queuing does not change an action that has started or succeeded.
+	require.NoError(t, s.Queue(ctx, testEntry(1, "attester-slashed-script")))
+	require.NoError(t, s.Queue(ctx, testEntry(2, "attester-slashed-script")))
+	unfinished, err := s.Unfinished(ctx)
+	require.NoError(t, err)
+	require.Len(t, unfinished, 3)
+	for _, entry := range unfinished {
+		require.Equal(t, ledger.StateStarted, entry.State)
+		require.Equal(t, 1, entry.Attempts)
+	}
+}
+
+func TestResume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	path := filepath.Join(t.TempDir(), "ledger.db")
+
+	s, err := standard.New(ctx, standard.WithPath(path))
+	require.NoError(t, err)
+
+	// Action 1 starts and succeeds, action 2 starts but does not complete, action 3 is
+	// queued and action 4 starts and fails.
+	for i := phase0.ValidatorIndex(1); i <= 4; i++ {
+		if i == 3 {
+			require.NoError(t, s.Queue(ctx, testEntry(i, "attester-slashed-script")))
+			continue
+		}
+		begin, err := s.Begin(ctx, testEntry(i, "attester-slashed-script"))
+		require.NoError(t, err)
+		require.True(t, begin)
+	}
+	require.NoError(t, s.Complete(ctx, &testEntry(1, "attester-slashed-script").Key, true))
+	require.NoError(t, s.Complete(ctx, &testEntry(4, "attester-slashed-script").Key, false))
+
+	// Restart.
+	cancel()
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	s, err = standard.New(ctx, standard.WithPath(path))
+	require.NoError(t, err)
+
+	unfinished, err := s.Unfinished(ctx)
+	require.NoError(t, err)
+	sort.Slice(unfinished, func(i, j int) bool { return unfinished[i].ValidatorIndex < unfinished[j].ValidatorIndex })
+	require.Len(t, unfinished, 2)
+	require.Equal(t, phase0.ValidatorIndex(2), unfinished[0].ValidatorIndex)
+	require.Equal(t, ledger.StateStarted, unfinished[0].State)
+	require.Equal(t, phase0.Slot(100), unfinished[0].Slot)
+	require.Equal(t, phase0.Root{0x02}, unfinished[0].BlockRoot)
+	require.Equal(t, phase0.ValidatorIndex(3), unfinished[1].ValidatorIndex)
+	require.Equal(t, ledger.StateQueued, unfinished[1].State)
+	require.Equal(t, 0, unfinished[1].Attempts)
+
+	// Resuming the started action counts a second attempt, and completing it removes
+	// it from the unfinished actions.
+	begin, err := s.Begin(ctx, testEntry(2, "attester-slashed-script"))
+	require.NoError(t, err)
+	require.True(t, begin)
+	require.NoError(t, s.Complete(ctx, &testEntry(2, "attester-slashed-script").Key, true))
+	begin, err = s.Begin(ctx, testEntry(3, "attester-slashed-script"))
+	require.NoError(t, err)
+	require.True(t, begin)
+	unfinished, err = s.Unfinished(ctx)
+	require.NoError(t, err)
+	require.Len(t, unfinished, 1)
+	require.Equal(t, phase0.ValidatorIndex(3), unfinished[0].ValidatorIndex)
+	require.Equal(t, 1, unfinished[0].Attempts)
+
+	// The action that succeeded before the restart is not run again.
+	begin, err = s.Begin(ctx, testEntry(1, "attester-slashed-script"))
+	require.NoError(t, err)
+	require.False(t, begin)
+}
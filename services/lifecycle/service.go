@@ -0,0 +1,113 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lifecycle follows slashed validators from their slashing until their funds are withdrawn.
+package lifecycle
+
+import (
+	"context"
+
+	"github.com/attestantio/esd/services/slashings"
+	spec "github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// Milestone is a point in the lifecycle of a slashed validator.
+type Milestone string
+
+const (
+	// MilestoneExited is when the validator exits the active set.
+	MilestoneExited Milestone = "exited"
+	// MilestoneCorrelationPenalty is when the correlation penalty is applied to the validator.
+	MilestoneCorrelationPenalty Milestone = "correlation_penalty"
+	// MilestoneWithdrawable is when the validator's funds become withdrawable.
+	MilestoneWithdrawable Milestone = "withdrawable"
+	// MilestoneWithdrawn is when the validator's funds have been withdrawn.
+	MilestoneWithdrawn Milestone = "withdrawn"
+)
+
+// Milestones are the milestones in the order in which they are usually reached.  If the
+// exit queue is long then a validator can incur the correlation penalty before it exits.
+var Milestones = []Milestone{
+	MilestoneExited,
+	MilestoneCorrelationPenalty,
+	MilestoneWithdrawable,
+	MilestoneWithdrawn,
+}
+
+// Validator is a slashed validator being tracked through its lifecycle.
+type Validator struct {
+	// Index is the index of the validator.
+	Index spec.ValidatorIndex `json:"index"`
+	// PubKey is the public key of the validator, if known.
+	PubKey spec.BLSPubKey `json:"pubkey"`
+	// SlashedSlot is the slot of the block that included the slashing.
+	SlashedSlot spec.Slot `json:"slashed_slot"`
+	// ExitEpoch is the epoch at which the validator exits, once known.
+	ExitEpoch spec.Epoch `json:"exit_epoch,omitempty"`
+	// CorrelationEpoch is the epoch at whose end the correlation penalty is applied, once known.
+	CorrelationEpoch spec.Epoch `json:"correlation_epoch,omitempty"`
+	// WithdrawableEpoch is the epoch at which the validator's funds become withdrawable, once known.
+	WithdrawableEpoch spec.Epoch `json:"withdrawable_epoch,omitempty"`
+	// Balance is the most recently observed balance of the validator.
+	Balance spec.Gwei `json:"balance"`
+	// Reached are the milestones that the validator has reached.
+	Reached []Milestone `json:"reached"`
+}
+
+// HasReached returns true if the validator has reached the given milestone.
+func (v *Validator) HasReached(milestone Milestone) bool {
+	for _, reached := range v.Reached {
+		if reached == milestone {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Event is a slashed validator reaching a milestone.
+type Event struct {
+	// ValidatorIndex is the index of the validator.
+	ValidatorIndex spec.ValidatorIndex `json:"validator_index"`
+	// PubKey is the public key of the validator, if known.
+	PubKey spec.BLSPubKey `json:"pubkey"`
+	// Milestone is the milestone reached.
+	Milestone Milestone `json:"milestone"`
+	// Epoch is the epoch at which the milestone was observed.
+	Epoch spec.Epoch `json:"epoch"`
+	// Balance is the balance of the validator observed at the milestone.
+	Balance spec.Gwei `json:"balance"`
+	// ExitEpoch is the epoch at which the validator exits.
+	ExitEpoch spec.Epoch `json:"exit_epoch"`
+	// CorrelationEpoch is the epoch at whose end the correlation penalty is applied.
+	CorrelationEpoch spec.Epoch `json:"correlation_epoch"`
+	// WithdrawableEpoch is the epoch at which the validator's funds become withdrawable.
+	WithdrawableEpoch spec.Epoch `json:"withdrawable_epoch"`
+	// Drill is true if the event is synthetic, created for a drill.
+	Drill bool `json:"drill,omitempty"`
+}
+
+// Handler is the interface for consumers of lifecycle events.
+type Handler interface {
+	// OnLifecycle handles a lifecycle event.
+	OnLifecycle(ctx context.Context, event *Event) error
+}
+
+// Service is the lifecycle service.  It is informed of slashings as a slashings handler,
+// and starts tracking the watched validators that they slash.
+type Service interface {
+	slashings.Handler
+
+	// Tracked returns the validators being tracked, ordered by index.
+	Tracked(ctx context.Context) ([]*Validator, error)
+}
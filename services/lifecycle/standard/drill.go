@@ -0,0 +1,102 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/attestantio/esd/services/lifecycle"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+)
+
+// drillEnv is the environment variable that tells scripts they are being run for a drill.
+const drillEnv = "ESD_DRILL=1"
+
+// errDrillSkipped is returned in place of running a script for a drill that does not
+// run scripts.
+var errDrillSkipped = errors.New("skipped for drill")
+
+// Drill passes the validator of a synthetic slashing event through each milestone in
+// turn, informing the handlers and running the milestone scripts, and returns the result
+// of each.  The epochs of the milestones are estimated as if the validator were slashed
+// in the current epoch.  Scripts are skipped unless the service was created to run
+// them, in which case they are run with ESD_DRILL=1.  The validator is not tracked.
+func (s *Service) Drill(ctx context.Context, event *slashings.SlashingEvent) []*slashings.DrillResult {
+	s.drillMu.Lock()
+	s.drillResults = make([]*slashings.DrillResult, 0)
+	s.drillMu.Unlock()
+
+	epoch := s.chainTime.CurrentEpoch()
+	withdrawableEpoch := epoch + phase0.Epoch(s.epochsPerSlashingsVector)
+	for _, milestone := range lifecycle.Milestones {
+		s.handleEvent(ctx, &lifecycle.Event{
+			ValidatorIndex:    event.ValidatorIndex,
+			PubKey:            event.PubKey,
+			Milestone:         milestone,
+			Epoch:             epoch,
+			Balance:           event.EffectiveBalance,
+			ExitEpoch:         epoch,
+			CorrelationEpoch:  withdrawableEpoch - phase0.Epoch(s.epochsPerSlashingsVector/2),
+			WithdrawableEpoch: withdrawableEpoch,
+			Drill:             true,
+		})
+	}
+
+	s.drillMu.Lock()
+	defer s.drillMu.Unlock()
+
+	return s.drillResults
+}
+
+// notifyAction returns the name of the action that informs the handlers of a milestone,
+// as reported by drills.
+func notifyAction(milestone lifecycle.Milestone) string {
+	return fmt.Sprintf("notify-lifecycle-%s", strings.ReplaceAll(string(milestone), "_", "-"))
+}
+
+// recordDrillResult records the outcome of an action for a drill.  It does nothing if
+// the service is not running drills.
+func (s *Service) recordDrillResult(action string, target string, output string, err error) {
+	if !s.drill {
+		return
+	}
+
+	result := &slashings.DrillResult{
+		Action:  action,
+		Target:  target,
+		Success: err == nil,
+		Skipped: errors.Is(err, errDrillSkipped),
+		Output:  output,
+	}
+	if err != nil && !result.Skipped {
+		result.Error = err.Error()
+	}
+
+	s.drillMu.Lock()
+	s.drillResults = append(s.drillResults, result)
+	s.drillMu.Unlock()
+}
+
+// handlerTarget returns a description of a handler for a drill.
+func handlerTarget(handler any) string {
+	if stringer, isStringer := handler.(fmt.Stringer); isStringer {
+		return stringer.String()
+	}
+
+	return fmt.Sprintf("%T", handler)
+}
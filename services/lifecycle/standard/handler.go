@@ -0,0 +1,98 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/attestantio/esd/services/lifecycle"
+	"github.com/attestantio/esd/util"
+	"github.com/pkg/errors"
+)
+
+// Action returns the name of the action that runs the script for a milestone,
+// as used by the operator controls.
+func Action(milestone lifecycle.Milestone) string {
+	return fmt.Sprintf("lifecycle-%s-script", strings.ReplaceAll(string(milestone), "_", "-"))
+}
+
+// handleEvent reports a validator reaching a milestone, and runs the script for the milestone.
+func (s *Service) handleEvent(ctx context.Context, event *lifecycle.Event) {
+	s.log.Info().
+		Uint64("validator_index", uint64(event.ValidatorIndex)).
+		Str("milestone", string(event.Milestone)).
+		Uint64("epoch", uint64(event.Epoch)).
+		Uint64("balance", uint64(event.Balance)).
+		Msg("Slashed validator reached milestone")
+	milestoneReached(ctx, event.Milestone)
+
+	cfg := s.cfg.Load()
+	for _, handler := range cfg.handlers {
+		err := handler.OnLifecycle(ctx, event)
+		if err != nil {
+			s.log.Error().Uint64("validator_index", uint64(event.ValidatorIndex)).Err(err).Msg("Handler failed to handle lifecycle event")
+		}
+		s.recordDrillResult(notifyAction(event.Milestone), handlerTarget(handler), "", err)
+	}
+
+	script := cfg.scripts[event.Milestone]
+	if script == "" {
+		return
+	}
+	action := Action(event.Milestone)
+	log := s.log.With().Uint64("validator_index", uint64(event.ValidatorIndex)).Str("milestone", string(event.Milestone)).Logger()
+	if s.controls != nil {
+		if reason := s.controls.Blocked(action, event.ValidatorIndex); reason != "" {
+			log.Warn().Str("reason", reason).Msg("Action blocked by controls; not running")
+			s.recordDrillResult(action, script, "", fmt.Errorf("not run: %s", reason))
+			return
+		}
+	}
+	log.Trace().Str("script", script).Msg("Calling script for milestone")
+	output, err := s.runScript(ctx, cfg.scriptTimeout, script, event)
+	s.recordDrillResult(action, script, output, err)
+	switch {
+	case errors.Is(err, errDrillSkipped):
+		log.Debug().Msg("Milestone script skipped for drill")
+	case err != nil:
+		log.Error().Str("output", output).Err(err).Msg("Milestone script failed")
+	default:
+		log.Debug().Str("output", output).Msg("Milestone script succeeded")
+	}
+}
+
+// runScript runs the script for a lifecycle event, returning its combined output.
+// For a drill the script is skipped unless drill scripts are run, when it is told that
+// it is being run for a drill.
+func (s *Service) runScript(ctx context.Context, timeout time.Duration, script string, event *lifecycle.Event) (string, error) {
+	env := []string{
+		fmt.Sprintf("ESD_MILESTONE=%s", event.Milestone),
+		fmt.Sprintf("ESD_EPOCH=%d", event.Epoch),
+		fmt.Sprintf("ESD_BALANCE=%d", event.Balance),
+		fmt.Sprintf("ESD_EXIT_EPOCH=%d", event.ExitEpoch),
+		fmt.Sprintf("ESD_CORRELATION_EPOCH=%d", event.CorrelationEpoch),
+		fmt.Sprintf("ESD_WITHDRAWABLE_EPOCH=%d", event.WithdrawableEpoch),
+	}
+	if s.drill {
+		if !s.drillScripts {
+			return "", errDrillSkipped
+		}
+		env = append(env, drillEnv)
+	}
+
+	return util.RunScript(ctx, timeout, script, []string{fmt.Sprintf("%d", event.ValidatorIndex)}, env, nil)
+}
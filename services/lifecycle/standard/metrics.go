@@ -0,0 +1,82 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+
+	"github.com/attestantio/esd/services/lifecycle"
+	"github.com/attestantio/esd/services/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var metricsNamespace = "esd"
+
+var (
+	trackedValidators prometheus.Gauge
+	milestones        *prometheus.CounterVec
+)
+
+func registerMetrics(ctx context.Context, monitor metrics.Service) error {
+	if trackedValidators != nil {
+		// Already registered.
+		return nil
+	}
+	if monitor == nil {
+		// No monitor.
+		return nil
+	}
+	if monitor.Presenter() == "prometheus" {
+		return registerPrometheusMetrics(ctx)
+	}
+
+	return nil
+}
+
+func registerPrometheusMetrics(_ context.Context) error {
+	trackedValidators = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "lifecycle",
+		Name:      "tracked_validators",
+		Help:      "Number of slashed validators being tracked through their lifecycle",
+	})
+	if err := prometheus.Register(trackedValidators); err != nil {
+		return errors.Wrap(err, "failed to register tracked_validators")
+	}
+
+	milestones = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "lifecycle",
+		Name:      "milestones_total",
+		Help:      "Total number of milestones reached by slashed validators",
+	}, []string{"milestone"})
+	if err := prometheus.Register(milestones); err != nil {
+		return errors.Wrap(err, "failed to register milestones_total")
+	}
+
+	return nil
+}
+
+func setTrackedValidators(_ context.Context, validators int) {
+	if trackedValidators != nil {
+		trackedValidators.Set(float64(validators))
+	}
+}
+
+func milestoneReached(_ context.Context, milestone lifecycle.Milestone) {
+	if milestones != nil {
+		milestones.WithLabelValues(string(milestone)).Inc()
+	}
+}
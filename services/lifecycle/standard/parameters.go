@@ -0,0 +1,184 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"errors"
+	"time"
+
+	"github.com/attestantio/esd/services/chaintime"
+	"github.com/attestantio/esd/services/controls"
+	"github.com/attestantio/esd/services/lifecycle"
+	"github.com/attestantio/esd/services/metrics"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/esd/services/watchlist"
+	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/rs/zerolog"
+)
+
+type parameters struct {
+	logLevel           zerolog.Level
+	monitor            metrics.Service
+	specProvider       eth2client.SpecProvider
+	validatorsProvider eth2client.ValidatorsProvider
+	chainTime          chaintime.Service
+	watchlist          watchlist.Service
+	controls           controls.Service
+	path               string
+	scripts            map[lifecycle.Milestone]string
+	scriptTimeout      time.Duration
+	handlers           []slashings.Handler
+	drill              bool
+	drillScripts       bool
+}
+
+// Parameter is the interface for service parameters.
+type Parameter interface {
+	apply(p *parameters)
+}
+
+type parameterFunc func(*parameters)
+
+func (f parameterFunc) apply(p *parameters) {
+	f(p)
+}
+
+// WithLogLevel sets the log level for the module.
+func WithLogLevel(logLevel zerolog.Level) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.logLevel = logLevel
+	})
+}
+
+// WithMonitor sets the monitor for this module.
+func WithMonitor(monitor metrics.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.monitor = monitor
+	})
+}
+
+// WithSpecProvider sets the spec provider.
+func WithSpecProvider(provider eth2client.SpecProvider) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.specProvider = provider
+	})
+}
+
+// WithValidatorsProvider sets the validators provider.
+func WithValidatorsProvider(provider eth2client.ValidatorsProvider) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.validatorsProvider = provider
+	})
+}
+
+// WithChainTime sets the chain time service for this module.
+func WithChainTime(chainTime chaintime.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.chainTime = chainTime
+	})
+}
+
+// WithWatchlist sets the watchlist of validators to track.
+func WithWatchlist(watchlist watchlist.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.watchlist = watchlist
+	})
+}
+
+// WithControls sets the operator controls that can stop scripts from running.
+func WithControls(controls controls.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.controls = controls
+	})
+}
+
+// WithPath sets the path of the file holding the tracked validators.
+func WithPath(path string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.path = path
+	})
+}
+
+// WithScripts sets the scripts to run when validators reach milestones.
+func WithScripts(scripts map[lifecycle.Milestone]string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.scripts = scripts
+	})
+}
+
+// WithScriptTimeout sets the time after which a script that has not finished is killed.
+// 0 means no timeout.
+func WithScriptTimeout(timeout time.Duration) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.scriptTimeout = timeout
+	})
+}
+
+// WithHandlers sets the handlers, such as notifiers, for lifecycle events.  Handlers
+// that do not implement lifecycle.Handler are ignored.
+func WithHandlers(handlers []slashings.Handler) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.handlers = handlers
+	})
+}
+
+// WithDrill creates the service for running drills only, without tracking validators.
+func WithDrill(drill bool) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.drill = drill
+	})
+}
+
+// WithDrillScripts runs the scripts for drills.  If not set, each script is reported as
+// skipped rather than run.
+func WithDrillScripts(run bool) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.drillScripts = run
+	})
+}
+
+// parseAndCheckParameters parses and checks parameters to ensure that mandatory parameters are present and correct.
+func parseAndCheckParameters(params ...Parameter) (*parameters, error) {
+	parameters := parameters{
+		logLevel:      zerolog.GlobalLevel(),
+		scripts:       make(map[lifecycle.Milestone]string),
+		scriptTimeout: 5 * time.Minute,
+	}
+	for _, p := range params {
+		if params != nil {
+			p.apply(&parameters)
+		}
+	}
+
+	if parameters.specProvider == nil {
+		return nil, errors.New("no spec provider specified")
+	}
+	if parameters.validatorsProvider == nil {
+		return nil, errors.New("no validators provider specified")
+	}
+	if parameters.chainTime == nil {
+		return nil, errors.New("no chain time specified")
+	}
+	if parameters.watchlist == nil {
+		return nil, errors.New("no watchlist specified")
+	}
+	if parameters.path == "" {
+		return nil, errors.New("no path specified")
+	}
+	if parameters.scriptTimeout < 0 {
+		return nil, errors.New("script timeout cannot be negative")
+	}
+
+	return &parameters, nil
+}
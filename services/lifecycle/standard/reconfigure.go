@@ -0,0 +1,69 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"time"
+
+	"github.com/attestantio/esd/services/lifecycle"
+	"github.com/attestantio/esd/services/slashings"
+)
+
+// config holds the settings of the service that can be changed while it is running.
+type config struct {
+	scripts       map[lifecycle.Milestone]string
+	scriptTimeout time.Duration
+	handlers      []lifecycle.Handler
+}
+
+// Reconfigure changes the log level, scripts, script timeout and handlers of the running service.
+// Other parameters are ignored.
+func (s *Service) Reconfigure(_ context.Context, params ...Parameter) {
+	current := s.cfg.Load()
+	parameters := parameters{
+		logLevel:      s.logLevel.Level(),
+		scripts:       current.scripts,
+		scriptTimeout: current.scriptTimeout,
+	}
+	for _, p := range params {
+		if params != nil {
+			p.apply(&parameters)
+		}
+	}
+
+	cfg := &config{
+		scripts:       parameters.scripts,
+		scriptTimeout: parameters.scriptTimeout,
+		handlers:      current.handlers,
+	}
+	if parameters.handlers != nil {
+		cfg.handlers = lifecycleHandlers(parameters.handlers)
+	}
+	s.cfg.Store(cfg)
+	s.logLevel.SetLevel(parameters.logLevel)
+	s.log.Info().Str("log_level", parameters.logLevel.String()).Int("handlers", len(cfg.handlers)).Msg("Reconfigured")
+}
+
+// lifecycleHandlers returns those handlers that handle lifecycle events.
+func lifecycleHandlers(handlers []slashings.Handler) []lifecycle.Handler {
+	res := make([]lifecycle.Handler, 0, len(handlers))
+	for _, handler := range handlers {
+		if lifecycleHandler, isHandler := handler.(lifecycle.Handler); isHandler {
+			res = append(res, lifecycleHandler)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,378 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package standard tracks slashed validators through their lifecycle, checking the
+// beacon state once per epoch.
+package standard
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/attestantio/esd/services/chaintime"
+	"github.com/attestantio/esd/services/controls"
+	"github.com/attestantio/esd/services/lifecycle"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/esd/services/watchlist"
+	"github.com/attestantio/esd/util"
+	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+)
+
+// Service tracks slashed validators through their lifecycle.
+type Service struct {
+	log                      zerolog.Logger
+	logLevel                 *util.LevelFilter
+	validatorsProvider       eth2client.ValidatorsProvider
+	chainTime                chaintime.Service
+	watchlist                watchlist.Service
+	controls                 controls.Service
+	path                     string
+	epochsPerSlashingsVector uint64
+	drill                    bool
+	drillScripts             bool
+
+	// cfg is the configuration that can be changed while the service is running.
+	cfg atomic.Pointer[config]
+
+	tracked   map[phase0.ValidatorIndex]*lifecycle.Validator
+	trackedMu sync.Mutex
+
+	drillResults []*slashings.DrillResult
+	drillMu      sync.Mutex
+}
+
+// New creates a new lifecycle service.
+func New(ctx context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.  The level is applied by a filter so that it can be reconfigured.
+	logLevel := util.NewLevelFilter(parameters.logLevel)
+	log := zerologger.With().Str("service", "lifecycle").Str("impl", "standard").Logger().Level(zerolog.TraceLevel).Hook(logLevel)
+
+	if err := registerMetrics(ctx, parameters.monitor); err != nil {
+		return nil, errors.Wrap(err, "failed to register metrics")
+	}
+
+	specResponse, err := parameters.specProvider.Spec(ctx, &api.SpecOpts{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain spec")
+	}
+	tmp, exists := specResponse.Data["EPOCHS_PER_SLASHINGS_VECTOR"]
+	if !exists {
+		return nil, errors.New("EPOCHS_PER_SLASHINGS_VECTOR not found in spec")
+	}
+	epochsPerSlashingsVector, isUint := tmp.(uint64)
+	if !isUint {
+		return nil, errors.New("EPOCHS_PER_SLASHINGS_VECTOR of unexpected type")
+	}
+
+	s := &Service{
+		log:                      log,
+		logLevel:                 logLevel,
+		validatorsProvider:       parameters.validatorsProvider,
+		chainTime:                parameters.chainTime,
+		watchlist:                parameters.watchlist,
+		controls:                 parameters.controls,
+		path:                     parameters.path,
+		epochsPerSlashingsVector: epochsPerSlashingsVector,
+		drill:                    parameters.drill,
+		drillScripts:             parameters.drillScripts,
+		tracked:                  make(map[phase0.ValidatorIndex]*lifecycle.Validator),
+	}
+	s.cfg.Store(&config{
+		scripts:       parameters.scripts,
+		scriptTimeout: parameters.scriptTimeout,
+		handlers:      lifecycleHandlers(parameters.handlers),
+	})
+
+	if parameters.drill {
+		// Service is only used to run drills, so do not track validators.
+		return s, nil
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	setTrackedValidators(ctx, len(s.tracked))
+
+	go s.run(ctx)
+
+	return s, nil
+}
+
+// unconfirmedEpochs is the number of epochs after a slashing was reported by which it
+// must be in the head state, otherwise its block is taken to have been reorged out.
+const unconfirmedEpochs = 8
+
+// OnSlashing starts tracking a watched validator that has been slashed.
+func (s *Service) OnSlashing(ctx context.Context, event *slashings.SlashingEvent) error {
+	switch {
+	case event.Unverified():
+		// Evidence could not be verified, so the validator may not have been slashed.
+		return nil
+	case event.AlreadySlashed:
+		// Validator is not penalised by this slashing, and is tracked from its first.
+		return nil
+	case event.Optimistic:
+		// Block has not been validated; tracking starts if it is.
+		return nil
+	case event.Status == slashings.StatusOrphaned:
+		return nil
+	}
+	if !s.watchlist.Watched(event.ValidatorIndex, event.PubKey) {
+		return nil
+	}
+
+	s.trackedMu.Lock()
+	defer s.trackedMu.Unlock()
+	if _, exists := s.tracked[event.ValidatorIndex]; exists {
+		return nil
+	}
+	s.tracked[event.ValidatorIndex] = &lifecycle.Validator{
+		Index:       event.ValidatorIndex,
+		PubKey:      event.PubKey,
+		SlashedSlot: event.Slot,
+		Reached:     make([]lifecycle.Milestone, 0),
+	}
+	s.log.Info().Uint64("validator_index", uint64(event.ValidatorIndex)).Msg("Tracking slashed validator")
+	setTrackedValidators(ctx, len(s.tracked))
+
+	return s.save()
+}
+
+// OnStatus starts tracking a watched validator whose slashing was held in an optimistic
+// block once the block is validated, and stops tracking a validator whose slashing
+// has been orphaned.
+func (s *Service) OnStatus(ctx context.Context, event *slashings.SlashingEvent) error {
+	if event.Status != slashings.StatusOrphaned {
+		return s.OnSlashing(ctx, event)
+	}
+
+	s.trackedMu.Lock()
+	defer s.trackedMu.Unlock()
+	tracked, exists := s.tracked[event.ValidatorIndex]
+	if !exists || tracked.SlashedSlot != event.Slot || len(tracked.Reached) > 0 {
+		// Not tracked for this slashing, or the slashing is in the chain regardless.
+		return nil
+	}
+	s.log.Info().Uint64("validator_index", uint64(event.ValidatorIndex)).Msg("Slashing orphaned; no longer tracking")
+	delete(s.tracked, event.ValidatorIndex)
+	setTrackedValidators(ctx, len(s.tracked))
+
+	return s.save()
+}
+
+// Tracked returns the validators being tracked, ordered by index.
+func (s *Service) Tracked(_ context.Context) ([]*lifecycle.Validator, error) {
+	s.trackedMu.Lock()
+	defer s.trackedMu.Unlock()
+
+	return s.sortedTracked(), nil
+}
+
+// run checks the tracked validators once per epoch, shortly after the start of the epoch
+// to allow the beacon node to carry out the epoch transition.
+func (s *Service) run(ctx context.Context) {
+	s.check(ctx)
+	for {
+		nextEpoch := s.chainTime.CurrentEpoch() + 1
+		nextCheck := s.chainTime.StartOfSlot(s.chainTime.FirstSlotOfEpoch(nextEpoch) + 1)
+		select {
+		case <-ctx.Done():
+			s.log.Trace().Msg("Context done; stopping lifecycle tracking")
+			return
+		case <-time.After(time.Until(nextCheck)):
+			s.check(ctx)
+		}
+	}
+}
+
+// check obtains the current state of the tracked validators, and raises events for any
+// milestones that they have reached.
+func (s *Service) check(ctx context.Context) {
+	s.trackedMu.Lock()
+	indices := make([]phase0.ValidatorIndex, 0, len(s.tracked))
+	for index := range s.tracked {
+		indices = append(indices, index)
+	}
+	s.trackedMu.Unlock()
+	if len(indices) == 0 {
+		return
+	}
+
+	epoch := s.chainTime.CurrentEpoch()
+	response, err := s.validatorsProvider.Validators(ctx, &api.ValidatorsOpts{
+		State:   "head",
+		Indices: indices,
+	})
+	if err != nil {
+		s.log.Warn().Uint64("epoch", uint64(epoch)).Err(err).Msg("Failed to obtain tracked validators; will retry next epoch")
+		return
+	}
+
+	events := make([]*lifecycle.Event, 0)
+	s.trackedMu.Lock()
+	for _, index := range indices {
+		tracked, exists := s.tracked[index]
+		if !exists {
+			continue
+		}
+		validator, exists := response.Data[index]
+		if !exists || validator.Validator == nil {
+			s.log.Warn().Uint64("validator_index", uint64(index)).Msg("Tracked validator not found in state")
+			continue
+		}
+		if !validator.Validator.Slashed && epoch > s.chainTime.SlotToEpoch(tracked.SlashedSlot)+unconfirmedEpochs {
+			s.log.Warn().Uint64("validator_index", uint64(index)).Uint64("slashed_slot", uint64(tracked.SlashedSlot)).Msg("Slashing not in the chain; no longer tracking")
+			delete(s.tracked, index)
+			continue
+		}
+		events = append(events, s.update(tracked, validator.Validator, validator.Balance, epoch)...)
+		if tracked.HasReached(lifecycle.MilestoneWithdrawn) {
+			s.log.Info().Uint64("validator_index", uint64(index)).Msg("Slashed validator withdrawn; no longer tracking")
+			delete(s.tracked, index)
+		}
+	}
+	setTrackedValidators(ctx, len(s.tracked))
+	if err := s.save(); err != nil {
+		s.log.Error().Err(err).Msg("Failed to save tracked validators")
+	}
+	s.trackedMu.Unlock()
+
+	for _, event := range events {
+		s.handleEvent(ctx, event)
+	}
+}
+
+// update updates a tracked validator from its state, returning events for the milestones
+// that it has newly reached.
+// trackedMu must be held.
+func (s *Service) update(tracked *lifecycle.Validator,
+	validator *phase0.Validator,
+	balance phase0.Gwei,
+	epoch phase0.Epoch,
+) []*lifecycle.Event {
+	tracked.Balance = balance
+	if !validator.Slashed {
+		// The head state does not yet include the slashing.
+		return nil
+	}
+	tracked.ExitEpoch = validator.ExitEpoch
+	tracked.WithdrawableEpoch = validator.WithdrawableEpoch
+	if uint64(validator.WithdrawableEpoch) > s.epochsPerSlashingsVector/2 {
+		tracked.CorrelationEpoch = validator.WithdrawableEpoch - phase0.Epoch(s.epochsPerSlashingsVector/2)
+	}
+
+	events := make([]*lifecycle.Event, 0)
+	for _, milestone := range lifecycle.Milestones {
+		if tracked.HasReached(milestone) {
+			continue
+		}
+		if !reached(tracked, milestone, epoch) {
+			// The correlation penalty can fall before the exit, so each milestone is
+			// checked on its own.
+			continue
+		}
+		tracked.Reached = append(tracked.Reached, milestone)
+		events = append(events, &lifecycle.Event{
+			ValidatorIndex:    tracked.Index,
+			PubKey:            tracked.PubKey,
+			Milestone:         milestone,
+			Epoch:             epoch,
+			Balance:           balance,
+			ExitEpoch:         tracked.ExitEpoch,
+			CorrelationEpoch:  tracked.CorrelationEpoch,
+			WithdrawableEpoch: tracked.WithdrawableEpoch,
+		})
+	}
+
+	return events
+}
+
+// reached returns true if the tracked validator has reached the milestone at the given epoch.
+func reached(tracked *lifecycle.Validator, milestone lifecycle.Milestone, epoch phase0.Epoch) bool {
+	switch milestone {
+	case lifecycle.MilestoneExited:
+		return epoch >= tracked.ExitEpoch
+	case lifecycle.MilestoneCorrelationPenalty:
+		// The penalty is applied in the transition at the end of the correlation epoch.
+		return epoch > tracked.CorrelationEpoch
+	case lifecycle.MilestoneWithdrawable:
+		return epoch >= tracked.WithdrawableEpoch
+	case lifecycle.MilestoneWithdrawn:
+		return epoch >= tracked.WithdrawableEpoch && tracked.Balance == 0
+	default:
+		return false
+	}
+}
+
+// load loads the tracked validators from disk.
+func (s *Service) load() error {
+	data, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		s.log.Trace().Str("path", s.path).Msg("No existing lifecycle file")
+	case err != nil:
+		return errors.Wrap(err, "failed to read lifecycle file")
+	default:
+		validators := make([]*lifecycle.Validator, 0)
+		if err := json.Unmarshal(data, &validators); err != nil {
+			return errors.Wrap(err, "failed to parse lifecycle file")
+		}
+		for _, validator := range validators {
+			s.tracked[validator.Index] = validator
+		}
+		s.log.Trace().Int("validators", len(validators)).Msg("Loaded tracked validators")
+	}
+
+	return nil
+}
+
+func (s *Service) sortedTracked() []*lifecycle.Validator {
+	validators := make([]*lifecycle.Validator, 0, len(s.tracked))
+	for _, validator := range s.tracked {
+		validators = append(validators, validator)
+	}
+	sort.Slice(validators, func(i, j int) bool {
+		return validators[i].Index < validators[j].Index
+	})
+
+	return validators
+}
+
+// save writes the tracked validators to disk.  It must be called with the lock held.
+func (s *Service) save() error {
+	data, err := json.MarshalIndent(s.sortedTracked(), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal tracked validators")
+	}
+
+	if err := util.WriteFileAtomic(s.path, data, 0o600); err != nil {
+		return errors.Wrap(err, "failed to write lifecycle file")
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"testing"
+
+	"github.com/attestantio/esd/services/lifecycle"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/stretchr/testify/require"
+)
+
+// lifecycleStep is the state of a validator at an epoch, and the milestones it is
+// expected to newly reach.
+type lifecycleStep struct {
+	epoch      phase0.Epoch
+	balance    phase0.Gwei
+	milestones []lifecycle.Milestone
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name              string
+		exitEpoch         phase0.Epoch
+		withdrawableEpoch phase0.Epoch
+		steps             []lifecycleStep
+	}{
+		{
+			name:              "ExitFirst",
+			exitEpoch:         100,
+			withdrawableEpoch: 8292,
+			steps: []lifecycleStep{
+				{epoch: 99, balance: 31000000000, milestones: []lifecycle.Milestone{}},
+				{epoch: 100, balance: 31000000000, milestones: []lifecycle.Milestone{lifecycle.MilestoneExited}},
+				{epoch: 4196, balance: 31000000000, milestones: []lifecycle.Milestone{}},
+				{epoch: 4197, balance: 30000000000, milestones: []lifecycle.Milestone{lifecycle.MilestoneCorrelationPenalty}},
+				{epoch: 8292, balance: 30000000000, milestones: []lifecycle.Milestone{lifecycle.MilestoneWithdrawable}},
+				{epoch: 8293, balance: 0, milestones: []lifecycle.Milestone{lifecycle.MilestoneWithdrawn}},
+			},
+		},
+		{
+			name:              "CorrelationBeforeExit",
+			exitEpoch:         5000,
+			withdrawableEpoch: 8292,
+			steps: []lifecycleStep{
+				{epoch: 100, balance: 31000000000, milestones: []lifecycle.Milestone{}},
+				{epoch: 4197, balance: 30000000000, milestones: []lifecycle.Milestone{lifecycle.MilestoneCorrelationPenalty}},
+				{epoch: 4999, balance: 30000000000, milestones: []lifecycle.Milestone{}},
+				{epoch: 5000, balance: 30000000000, milestones: []lifecycle.Milestone{lifecycle.MilestoneExited}},
+				{epoch: 8292, balance: 30000000000, milestones: []lifecycle.Milestone{lifecycle.MilestoneWithdrawable}},
+				{epoch: 8293, balance: 0, milestones: []lifecycle.Milestone{lifecycle.MilestoneWithdrawn}},
+			},
+		},
+		{
+			name:              "AllAtOnce",
+			exitEpoch:         5000,
+			withdrawableEpoch: 8292,
+			steps: []lifecycleStep{
+				{
+					epoch:   9000,
+					balance: 0,
+					milestones: []lifecycle.Milestone{
+						lifecycle.MilestoneExited,
+						lifecycle.MilestoneCorrelationPenalty,
+						lifecycle.MilestoneWithdrawable,
+						lifecycle.MilestoneWithdrawn,
+					},
+				},
+				{epoch: 9001, balance: 0, milestones: []lifecycle.Milestone{}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &Service{
+				epochsPerSlashingsVector: 8192,
+			}
+			tracked := &lifecycle.Validator{
+				Index:   1,
+				Reached: make([]lifecycle.Milestone, 0),
+			}
+			validator := &phase0.Validator{
+				Slashed:           true,
+				ExitEpoch:         test.exitEpoch,
+				WithdrawableEpoch: test.withdrawableEpoch,
+			}
+
+			for _, step := range test.steps {
+				events := s.update(tracked, validator, step.balance, step.epoch)
+				milestones := make([]lifecycle.Milestone, 0, len(events))
+				for _, event := range events {
+					require.Equal(t, step.epoch, event.Epoch)
+					require.Equal(t, test.exitEpoch, event.ExitEpoch)
+					require.Equal(t, phase0.Epoch(4196), event.CorrelationEpoch)
+					milestones = append(milestones, event.Milestone)
+				}
+				require.Equal(t, step.milestones, milestones, "epoch %d", step.epoch)
+			}
+			require.ElementsMatch(t, lifecycle.Milestones, tracked.Reached)
+		})
+	}
+}
+
+func TestUpdateNotSlashed(t *testing.T) {
+	s := &Service{
+		epochsPerSlashingsVector: 8192,
+	}
+	tracked := &lifecycle.Validator{
+		Index:   1,
+		Reached: make([]lifecycle.Milestone, 0),
+	}
+
+	// The head state does not yet include the slashing.
+	events := s.update(tracked, &phase0.Validator{}, 32000000000, 100)
+	require.Empty(t, events)
+	require.Empty(t, tracked.Reached)
+	require.Equal(t, phase0.Gwei(32000000000), tracked.Balance)
+}
@@ -0,0 +1,130 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webhook notifies an HTTP endpoint of slashing events.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/attestantio/esd/services/lifecycle"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+)
+
+// Service is a notifier that posts slashing events to a webhook.
+type Service struct {
+	log     zerolog.Logger
+	url     string
+	timeout time.Duration
+	client  *http.Client
+}
+
+// New creates a new webhook notifier.
+func New(_ context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.
+	log := zerologger.With().Str("service", "notifier").Str("impl", "webhook").Logger()
+	if parameters.logLevel != log.GetLevel() {
+		log = log.Level(parameters.logLevel)
+	}
+
+	return &Service{
+		log:     log,
+		url:     parameters.url,
+		timeout: parameters.timeout,
+		client:  &http.Client{},
+	}, nil
+}
+
+// String returns a description of the webhook that omits the path and any credentials,
+// which can hold secrets.
+func (s *Service) String() string {
+	parsed, err := url.Parse(s.url)
+	if err != nil || parsed.Host == "" {
+		return "webhook"
+	}
+
+	return fmt.Sprintf("webhook %s://%s", parsed.Scheme, parsed.Host)
+}
+
+// OnSlashing posts a slashing event to the webhook.
+func (s *Service) OnSlashing(ctx context.Context, event *slashings.SlashingEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal event")
+	}
+
+	return s.post(ctx, "slashing", data)
+}
+
+// OnMassSlashing posts a mass slashing event to the webhook.
+func (s *Service) OnMassSlashing(ctx context.Context, event *slashings.MassSlashingEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal event")
+	}
+
+	return s.post(ctx, "mass_slashing", data)
+}
+
+// OnLifecycle posts a lifecycle event for a slashed validator to the webhook.
+func (s *Service) OnLifecycle(ctx context.Context, event *lifecycle.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal event")
+	}
+
+	return s.post(ctx, "lifecycle", data)
+}
+
+// post posts data to the webhook, with a header naming the type of event.
+func (s *Service) post(ctx context.Context, eventType string, data []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-ESD-Event", eventType)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to post to webhook")
+	}
+	defer resp.Body.Close()
+	// Drain the body to allow reuse of the connection.
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	s.log.Trace().Int("status", resp.StatusCode).Msg("Posted to webhook")
+
+	return nil
+}
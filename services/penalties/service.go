@@ -0,0 +1,40 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package penalties estimates the penalties and rewards of slashings.
+package penalties
+
+import (
+	"context"
+
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// Service estimates the penalties and rewards of slashings.
+type Service interface {
+	// Estimate estimates the penalties for the given validators from the given state.
+	// Validators that are not slashed in the state are estimated as if they were
+	// slashed in the epoch of the state.
+	Estimate(ctx context.Context,
+		stateID string,
+		indices []phase0.ValidatorIndex,
+	) (
+		map[phase0.ValidatorIndex]*slashings.Penalty,
+		error,
+	)
+
+	// Projection estimates the penalties for a validator with the maximum effective
+	// balance slashed in the epoch of the given state.
+	Projection(ctx context.Context, stateID string) (*slashings.Penalty, error)
+}
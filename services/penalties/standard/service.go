@@ -0,0 +1,353 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/attestantio/esd/services/slashings"
+	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+)
+
+// Altair and later forks pay the proposer a fixed share of the whistleblower reward.
+const (
+	proposerWeight    = 8
+	weightDenominator = 64
+)
+
+// forkParameters are the slashing parameters that change between forks.
+type forkParameters struct {
+	minSlashingPenaltyQuotient     uint64
+	proportionalSlashingMultiplier uint64
+}
+
+// Service estimates penalties from the beacon state.
+type Service struct {
+	log                         zerolog.Logger
+	beaconStateProvider         eth2client.BeaconStateProvider
+	slotsPerEpoch               uint64
+	epochsPerSlashingsVector    uint64
+	effectiveBalanceIncrement   uint64
+	whistleblowerRewardQuotient uint64
+	proposerRewardQuotient      uint64
+	maxEffectiveBalance         uint64
+	phase0Parameters            *forkParameters
+	altairParameters            *forkParameters
+	bellatrixParameters         *forkParameters
+}
+
+// New creates a new penalty estimation service.
+func New(ctx context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.
+	log := zerologger.With().Str("service", "penalties").Str("impl", "standard").Logger()
+	if parameters.logLevel != log.GetLevel() {
+		log = log.Level(parameters.logLevel)
+	}
+
+	specResponse, err := parameters.specProvider.Spec(ctx, &api.SpecOpts{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain spec")
+	}
+
+	s := &Service{
+		log:                 log,
+		beaconStateProvider: parameters.beaconStateProvider,
+	}
+	for name, dest := range map[string]*uint64{
+		"SLOTS_PER_EPOCH":               &s.slotsPerEpoch,
+		"EPOCHS_PER_SLASHINGS_VECTOR":   &s.epochsPerSlashingsVector,
+		"EFFECTIVE_BALANCE_INCREMENT":   &s.effectiveBalanceIncrement,
+		"WHISTLEBLOWER_REWARD_QUOTIENT": &s.whistleblowerRewardQuotient,
+		"PROPOSER_REWARD_QUOTIENT":      &s.proposerRewardQuotient,
+		"MAX_EFFECTIVE_BALANCE":         &s.maxEffectiveBalance,
+	} {
+		if *dest, err = specUint64(specResponse.Data, name); err != nil {
+			return nil, err
+		}
+	}
+
+	s.phase0Parameters = &forkParameters{}
+	if s.phase0Parameters.minSlashingPenaltyQuotient, err = specUint64(specResponse.Data, "MIN_SLASHING_PENALTY_QUOTIENT"); err != nil {
+		return nil, err
+	}
+	if s.phase0Parameters.proportionalSlashingMultiplier, err = specUint64(specResponse.Data, "PROPORTIONAL_SLASHING_MULTIPLIER"); err != nil {
+		return nil, err
+	}
+	// Later forks fall back to the parameters of earlier forks if not supplied.
+	s.altairParameters = forkParametersFromSpec(specResponse.Data, "_ALTAIR", s.phase0Parameters)
+	s.bellatrixParameters = forkParametersFromSpec(specResponse.Data, "_BELLATRIX", s.altairParameters)
+
+	if s.slotsPerEpoch == 0 || s.epochsPerSlashingsVector == 0 || s.effectiveBalanceIncrement == 0 ||
+		s.whistleblowerRewardQuotient == 0 || s.proposerRewardQuotient == 0 ||
+		s.phase0Parameters.minSlashingPenaltyQuotient == 0 {
+		return nil, errors.New("spec contains zero values")
+	}
+
+	return s, nil
+}
+
+// stateInfo is the information from a beacon state required to estimate penalties.
+type stateInfo struct {
+	version            spec.DataVersion
+	epoch              phase0.Epoch
+	validators         []*phase0.Validator
+	slashingsVector    []phase0.Gwei
+	totalActiveBalance uint64
+}
+
+// Estimate estimates the penalties for the given validators from the given state.
+// Validators that are not slashed in the state are estimated as if they were
+// slashed in the epoch of the state.
+func (s *Service) Estimate(ctx context.Context,
+	stateID string,
+	indices []phase0.ValidatorIndex,
+) (
+	map[phase0.ValidatorIndex]*slashings.Penalty,
+	error,
+) {
+	info, err := s.stateInfo(ctx, stateID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[phase0.ValidatorIndex]*slashings.Penalty, len(indices))
+	for _, index := range indices {
+		if uint64(index) >= uint64(len(info.validators)) {
+			return nil, fmt.Errorf("validator %d not found in state", index)
+		}
+		res[index] = s.estimate(info, info.validators[index])
+	}
+
+	return res, nil
+}
+
+// Projection estimates the penalties for a validator with the maximum effective
+// balance slashed in the epoch of the given state.
+func (s *Service) Projection(ctx context.Context, stateID string) (*slashings.Penalty, error) {
+	info, err := s.stateInfo(ctx, stateID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.estimate(info, &phase0.Validator{
+		EffectiveBalance: phase0.Gwei(s.maxEffectiveBalance),
+	}), nil
+}
+
+// stateInfo fetches the given state and obtains the information required to estimate penalties.
+func (s *Service) stateInfo(ctx context.Context, stateID string) (*stateInfo, error) {
+	stateResponse, err := s.beaconStateProvider.BeaconState(ctx, &api.BeaconStateOpts{
+		State: stateID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain state")
+	}
+	state := stateResponse.Data
+
+	slot, err := state.Slot()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain state slot")
+	}
+	validators, err := state.Validators()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain state validators")
+	}
+	slashingsVector, err := stateSlashings(state)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(slashingsVector)) != s.epochsPerSlashingsVector {
+		return nil, fmt.Errorf("state slashings vector has %d entries, expected %d", len(slashingsVector), s.epochsPerSlashingsVector)
+	}
+
+	epoch := phase0.Epoch(uint64(slot) / s.slotsPerEpoch)
+
+	return &stateInfo{
+		version:            state.Version,
+		epoch:              epoch,
+		validators:         validators,
+		slashingsVector:    slashingsVector,
+		totalActiveBalance: s.totalActiveBalance(validators, epoch),
+	}, nil
+}
+
+// estimate estimates the penalties for the validator.
+func (s *Service) estimate(info *stateInfo, validator *phase0.Validator) *slashings.Penalty {
+	forkParameters := s.forkParameters(info.version)
+	effectiveBalance := uint64(validator.EffectiveBalance)
+
+	// The correlation penalty is applied half way through the withdrawability period.
+	withdrawableEpoch := validator.WithdrawableEpoch
+	if !validator.Slashed {
+		withdrawableEpoch = info.epoch + phase0.Epoch(s.epochsPerSlashingsVector)
+	}
+	correlationEpoch := withdrawableEpoch - phase0.Epoch(s.epochsPerSlashingsVector/2)
+
+	// Only slashings in the vector at the correlation epoch contribute; later
+	// slashings are not known, so the projection assumes that there are none.
+	slashedBalance := s.slashedBalance(info.slashingsVector, info.epoch, correlationEpoch)
+	if !validator.Slashed {
+		slashedBalance += effectiveBalance
+	}
+
+	adjustedSlashedBalance := slashedBalance * forkParameters.proportionalSlashingMultiplier
+	if adjustedSlashedBalance > info.totalActiveBalance {
+		adjustedSlashedBalance = info.totalActiveBalance
+	}
+	// Calculated in the same order as the spec to obtain the same rounding.
+	penaltyNumerator := effectiveBalance / s.effectiveBalanceIncrement * adjustedSlashedBalance
+	correlationPenalty := penaltyNumerator / info.totalActiveBalance * s.effectiveBalanceIncrement
+
+	whistleblowerReward := effectiveBalance / s.whistleblowerRewardQuotient
+	proposerReward := whistleblowerReward * proposerWeight / weightDenominator
+	if info.version == spec.DataVersionPhase0 {
+		proposerReward = whistleblowerReward / s.proposerRewardQuotient
+	}
+
+	return &slashings.Penalty{
+		EffectiveBalance:    validator.EffectiveBalance,
+		InitialPenalty:      phase0.Gwei(effectiveBalance / forkParameters.minSlashingPenaltyQuotient),
+		CorrelationEpoch:    correlationEpoch,
+		CorrelationPenalty:  phase0.Gwei(correlationPenalty),
+		SlashedBalance:      phase0.Gwei(slashedBalance),
+		TotalActiveBalance:  phase0.Gwei(info.totalActiveBalance),
+		WhistleblowerReward: phase0.Gwei(whistleblowerReward - proposerReward),
+		ProposerReward:      phase0.Gwei(proposerReward),
+	}
+}
+
+// totalActiveBalance returns the total effective balance of validators active at the
+// given epoch, as per get_total_active_balance.
+func (s *Service) totalActiveBalance(validators []*phase0.Validator, epoch phase0.Epoch) uint64 {
+	total := uint64(0)
+	for _, validator := range validators {
+		if validator.ActivationEpoch <= epoch && epoch < validator.ExitEpoch {
+			total += uint64(validator.EffectiveBalance)
+		}
+	}
+	if total < s.effectiveBalanceIncrement {
+		total = s.effectiveBalanceIncrement
+	}
+
+	return total
+}
+
+// slashedBalance returns the balance slashed in the epochs up to the given epoch that
+// will still be in the slashings vector at the correlation epoch.
+func (s *Service) slashedBalance(slashingsVector []phase0.Gwei, epoch phase0.Epoch, correlationEpoch phase0.Epoch) uint64 {
+	total := uint64(0)
+	for i := uint64(0); i < s.epochsPerSlashingsVector; i++ {
+		if uint64(epoch) < i {
+			break
+		}
+		slashingEpoch := epoch - phase0.Epoch(i)
+		if slashingEpoch+phase0.Epoch(s.epochsPerSlashingsVector) <= correlationEpoch {
+			// No longer in the vector at the correlation epoch.
+			break
+		}
+		total += uint64(slashingsVector[uint64(slashingEpoch)%s.epochsPerSlashingsVector])
+	}
+
+	return total
+}
+
+// forkParameters returns the slashing parameters for the given fork.
+func (s *Service) forkParameters(version spec.DataVersion) *forkParameters {
+	switch version {
+	case spec.DataVersionPhase0:
+		return s.phase0Parameters
+	case spec.DataVersionAltair:
+		return s.altairParameters
+	default:
+		return s.bellatrixParameters
+	}
+}
+
+// stateSlashings returns the slashings vector of the state.
+func stateSlashings(state *spec.VersionedBeaconState) ([]phase0.Gwei, error) {
+	switch state.Version {
+	case spec.DataVersionPhase0:
+		if state.Phase0 == nil {
+			return nil, errors.New("no phase0 state")
+		}
+
+		return state.Phase0.Slashings, nil
+	case spec.DataVersionAltair:
+		if state.Altair == nil {
+			return nil, errors.New("no altair state")
+		}
+
+		return state.Altair.Slashings, nil
+	case spec.DataVersionBellatrix:
+		if state.Bellatrix == nil {
+			return nil, errors.New("no bellatrix state")
+		}
+
+		return state.Bellatrix.Slashings, nil
+	case spec.DataVersionCapella:
+		if state.Capella == nil {
+			return nil, errors.New("no capella state")
+		}
+
+		return state.Capella.Slashings, nil
+	case spec.DataVersionDeneb:
+		if state.Deneb == nil {
+			return nil, errors.New("no deneb state")
+		}
+
+		return state.Deneb.Slashings, nil
+	default:
+		return nil, errors.New("unknown state version")
+	}
+}
+
+// forkParametersFromSpec returns the fork parameters with the given suffix from the
+// spec, using the supplied parameters for any that are not present.
+func forkParametersFromSpec(data map[string]any, suffix string, previous *forkParameters) *forkParameters {
+	res := *previous
+	if val, err := specUint64(data, "MIN_SLASHING_PENALTY_QUOTIENT"+suffix); err == nil && val != 0 {
+		res.minSlashingPenaltyQuotient = val
+	}
+	if val, err := specUint64(data, "PROPORTIONAL_SLASHING_MULTIPLIER"+suffix); err == nil {
+		res.proportionalSlashingMultiplier = val
+	}
+
+	return &res
+}
+
+// specUint64 returns the named value from the spec.
+func specUint64(data map[string]any, name string) (uint64, error) {
+	tmp, exists := data[name]
+	if !exists {
+		return 0, fmt.Errorf("%s not found in spec", name)
+	}
+	val, isUint := tmp.(uint64)
+	if !isUint {
+		return 0, fmt.Errorf("%s of unexpected type", name)
+	}
+
+	return val, nil
+}
@@ -0,0 +1,242 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"testing"
+
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/stretchr/testify/require"
+)
+
+// mainnetService returns a service with the mainnet parameters.
+func mainnetService() *Service {
+	return &Service{
+		slotsPerEpoch:               32,
+		epochsPerSlashingsVector:    8192,
+		effectiveBalanceIncrement:   1_000_000_000,
+		whistleblowerRewardQuotient: 512,
+		proposerRewardQuotient:      8,
+		maxEffectiveBalance:         32_000_000_000,
+		phase0Parameters: &forkParameters{
+			minSlashingPenaltyQuotient:     128,
+			proportionalSlashingMultiplier: 1,
+		},
+		altairParameters: &forkParameters{
+			minSlashingPenaltyQuotient:     64,
+			proportionalSlashingMultiplier: 2,
+		},
+		bellatrixParameters: &forkParameters{
+			minSlashingPenaltyQuotient:     32,
+			proportionalSlashingMultiplier: 3,
+		},
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	s := mainnetService()
+
+	// 100,000 ETH slashed in the epoch of the state, with 1,000,000 ETH active.
+	slashingsVector := make([]phase0.Gwei, s.epochsPerSlashingsVector)
+	slashingsVector[100] = 100_000_000_000_000
+
+	tests := []struct {
+		name               string
+		version            spec.DataVersion
+		totalActiveBalance uint64
+		validator          *phase0.Validator
+		expected           *slashings.Penalty
+	}{
+		{
+			name:               "Phase0",
+			version:            spec.DataVersionPhase0,
+			totalActiveBalance: 1_000_000_000_000_000,
+			validator:          &phase0.Validator{EffectiveBalance: 32_000_000_000},
+			expected: &slashings.Penalty{
+				EffectiveBalance:    32_000_000_000,
+				InitialPenalty:      250_000_000,
+				CorrelationEpoch:    4196,
+				CorrelationPenalty:  3_000_000_000,
+				SlashedBalance:      100_032_000_000_000,
+				TotalActiveBalance:  1_000_000_000_000_000,
+				WhistleblowerReward: 54_687_500,
+				ProposerReward:      7_812_500,
+			},
+		},
+		{
+			name:               "Altair",
+			version:            spec.DataVersionAltair,
+			totalActiveBalance: 1_000_000_000_000_000,
+			validator:          &phase0.Validator{EffectiveBalance: 32_000_000_000},
+			expected: &slashings.Penalty{
+				EffectiveBalance:    32_000_000_000,
+				InitialPenalty:      500_000_000,
+				CorrelationEpoch:    4196,
+				CorrelationPenalty:  6_000_000_000,
+				SlashedBalance:      100_032_000_000_000,
+				TotalActiveBalance:  1_000_000_000_000_000,
+				WhistleblowerReward: 54_687_500,
+				ProposerReward:      7_812_500,
+			},
+		},
+		{
+			name:               "Bellatrix",
+			version:            spec.DataVersionBellatrix,
+			totalActiveBalance: 1_000_000_000_000_000,
+			validator:          &phase0.Validator{EffectiveBalance: 32_000_000_000},
+			expected: &slashings.Penalty{
+				EffectiveBalance:    32_000_000_000,
+				InitialPenalty:      1_000_000_000,
+				CorrelationEpoch:    4196,
+				CorrelationPenalty:  9_000_000_000,
+				SlashedBalance:      100_032_000_000_000,
+				TotalActiveBalance:  1_000_000_000_000_000,
+				WhistleblowerReward: 54_687_500,
+				ProposerReward:      7_812_500,
+			},
+		},
+		{
+			// Later forks use the Bellatrix parameters.
+			name:               "Deneb",
+			version:            spec.DataVersionDeneb,
+			totalActiveBalance: 1_000_000_000_000_000,
+			validator:          &phase0.Validator{EffectiveBalance: 32_000_000_000},
+			expected: &slashings.Penalty{
+				EffectiveBalance:    32_000_000_000,
+				InitialPenalty:      1_000_000_000,
+				CorrelationEpoch:    4196,
+				CorrelationPenalty:  9_000_000_000,
+				SlashedBalance:      100_032_000_000_000,
+				TotalActiveBalance:  1_000_000_000_000_000,
+				WhistleblowerReward: 54_687_500,
+				ProposerReward:      7_812_500,
+			},
+		},
+		{
+			// The adjusted slashed balance is capped at the total active balance, so the
+			// whole effective balance is lost.
+			name:               "Capped",
+			version:            spec.DataVersionDeneb,
+			totalActiveBalance: 64_000_000_000,
+			validator:          &phase0.Validator{EffectiveBalance: 32_000_000_000},
+			expected: &slashings.Penalty{
+				EffectiveBalance:    32_000_000_000,
+				InitialPenalty:      1_000_000_000,
+				CorrelationEpoch:    4196,
+				CorrelationPenalty:  32_000_000_000,
+				SlashedBalance:      100_032_000_000_000,
+				TotalActiveBalance:  64_000_000_000,
+				WhistleblowerReward: 54_687_500,
+				ProposerReward:      7_812_500,
+			},
+		},
+		{
+			// A validator already slashed is included in the slashings vector, and its
+			// correlation epoch is taken from its withdrawable epoch.
+			name:               "AlreadySlashed",
+			version:            spec.DataVersionDeneb,
+			totalActiveBalance: 1_000_000_000_000_000,
+			validator: &phase0.Validator{
+				EffectiveBalance:  31_000_000_000,
+				Slashed:           true,
+				WithdrawableEpoch: 8242,
+			},
+			expected: &slashings.Penalty{
+				EffectiveBalance:    31_000_000_000,
+				InitialPenalty:      968_750_000,
+				CorrelationEpoch:    4146,
+				CorrelationPenalty:  9_000_000_000,
+				SlashedBalance:      100_000_000_000_000,
+				TotalActiveBalance:  1_000_000_000_000_000,
+				WhistleblowerReward: 52_978_516,
+				ProposerReward:      7_568_359,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			penalty := s.estimate(&stateInfo{
+				version:            test.version,
+				epoch:              100,
+				slashingsVector:    slashingsVector,
+				totalActiveBalance: test.totalActiveBalance,
+			}, test.validator)
+			require.Equal(t, test.expected, penalty)
+		})
+	}
+}
+
+func TestSlashedBalance(t *testing.T) {
+	s := mainnetService()
+
+	slashingsVector := make([]phase0.Gwei, s.epochsPerSlashingsVector)
+	// Epoch 5904 leaves the vector at the correlation epoch of a slashing at epoch 10000.
+	slashingsVector[5904] = 1_000_000_000
+	slashingsVector[5905] = 2_000_000_000
+	slashingsVector[10000%s.epochsPerSlashingsVector] = 4_000_000_000
+	// Epoch 8191, which is not reached before the vector wraps.
+	slashingsVector[s.epochsPerSlashingsVector-1] = 8_000_000_000
+
+	tests := []struct {
+		name             string
+		epoch            phase0.Epoch
+		correlationEpoch phase0.Epoch
+		expected         uint64
+	}{
+		{
+			name:             "Window",
+			epoch:            10000,
+			correlationEpoch: 14096,
+			expected:         14_000_000_000,
+		},
+		{
+			name:             "EarlyEpoch",
+			epoch:            5905,
+			correlationEpoch: 10001,
+			expected:         3_000_000_000,
+		},
+		{
+			name:             "Genesis",
+			epoch:            0,
+			correlationEpoch: 4096,
+			expected:         0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, s.slashedBalance(slashingsVector, test.epoch, test.correlationEpoch))
+		})
+	}
+}
+
+func TestForkParametersFromSpec(t *testing.T) {
+	phase0Parameters := &forkParameters{
+		minSlashingPenaltyQuotient:     128,
+		proportionalSlashingMultiplier: 1,
+	}
+
+	altair := forkParametersFromSpec(map[string]any{
+		"MIN_SLASHING_PENALTY_QUOTIENT_ALTAIR":    uint64(64),
+		"PROPORTIONAL_SLASHING_MULTIPLIER_ALTAIR": uint64(2),
+	}, "_ALTAIR", phase0Parameters)
+	require.Equal(t, &forkParameters{minSlashingPenaltyQuotient: 64, proportionalSlashingMultiplier: 2}, altair)
+
+	// Missing parameters fall back to those of the previous fork.
+	bellatrix := forkParametersFromSpec(map[string]any{}, "_BELLATRIX", altair)
+	require.Equal(t, altair, bellatrix)
+}
@@ -0,0 +1,225 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/attestantio/esd/services/slashings"
+	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+)
+
+// batchItem is a batch action queued for all of the slashed validators in a block.
+type batchItem struct {
+	slot  phase0.Slot
+	root  phase0.Root
+	items []*actionItem
+}
+
+// batchDocument is the document passed to the batch script on stdin.
+type batchDocument struct {
+	Slot       phase0.Slot       `json:"slot"`
+	BlockRoot  string            `json:"block_root"`
+	Validators []*batchValidator `json:"validators"`
+}
+
+// batchValidator is a slashed validator in the batch document.
+type batchValidator struct {
+	Index    phase0.ValidatorIndex `json:"index"`
+	PubKey   string                `json:"pubkey,omitempty"`
+	Offences []string              `json:"offences"`
+	Kinds    []string              `json:"kinds"`
+	Details  []string              `json:"details"`
+	Penalty  *slashings.Penalty    `json:"penalty,omitempty"`
+}
+
+// batchItem returns the batch action for the slashings.
+func (b *blockSlashings) batchItem() *batchItem {
+	return &batchItem{
+		slot:  b.slot,
+		root:  b.root,
+		items: b.actionItems(),
+	}
+}
+
+// queueBatch places a batch action on the batch queue.
+func (s *Service) queueBatch(ctx context.Context, batch *batchItem) {
+	if s.cfg.Load().batchScript == "" {
+		return
+	}
+
+	s.pendingActions.Add(1)
+	select {
+	case s.batchQueue <- batch:
+	case <-ctx.Done():
+		s.pendingActions.Add(-1)
+		return
+	}
+	setQueueDepth(ctx, "batches", len(s.batchQueue))
+}
+
+// runBatches runs queued batch actions.
+func (s *Service) runBatches(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case batch := <-s.batchQueue:
+			setQueueDepth(ctx, "batches", len(s.batchQueue))
+			started := time.Now()
+			s.runBatch(ctx, batch)
+			s.pendingActions.Add(-1)
+			actionCompleted(ctx, time.Since(started))
+		}
+	}
+}
+
+// runBatch runs the batch script once for all of the slashed validators in a block.
+func (s *Service) runBatch(ctx context.Context, batch *batchItem) {
+	script := s.cfg.Load().batchScript
+	if script == "" {
+		return
+	}
+	log := s.log.With().Uint64("slot", uint64(batch.slot)).Str("action", actionBatchScript).Logger()
+
+	// Only pass validators for which the batch script has not already succeeded.
+	items := make([]*actionItem, 0, len(batch.items))
+	begun := make(map[*actionItem][]*slashings.Offence, len(batch.items))
+	for _, item := range batch.items {
+		if reason := s.blocked(actionBatchScript, item.event.ValidatorIndex); reason != "" {
+			log.Warn().Uint64("validator_index", uint64(item.event.ValidatorIndex)).Str("reason", reason).Msg("Action blocked by controls; not including validator")
+			actionBlocked(ctx, actionBatchScript)
+			s.recordActionResult(ctx, item.event, actionBatchScript, time.Now(), "", fmt.Errorf("not run: %s", reason))
+			continue
+		}
+		offences, err := s.beginAction(ctx, item.event, actionBatchScript)
+		if err != nil {
+			log.Error().Uint64("validator_index", uint64(item.event.ValidatorIndex)).Err(err).Msg("Failed to record start of action; not including validator")
+			continue
+		}
+		if len(offences) == 0 {
+			log.Debug().Uint64("validator_index", uint64(item.event.ValidatorIndex)).Msg("Action already succeeded; not including validator")
+			continue
+		}
+		items = append(items, item)
+		begun[item] = offences
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	doc, args := s.batchInput(ctx, batch, items)
+	input, err := json.Marshal(doc)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create batch document")
+		for _, item := range items {
+			s.completeAction(ctx, item.event, begun[item], actionBatchScript, false)
+		}
+
+		return
+	}
+
+	log.Trace().Str("script", script).Int("validators", len(items)).Msg("Calling batch script for slashed validators")
+	started := time.Now()
+	output, err := s.runCommand(ctx, script, args, scriptEnv(batch.slot, batch.root, nil), input)
+	if err != nil && ctx.Err() != nil {
+		// Script was stopped by shutdown, so leave the action to be resumed on restart.
+		log.Warn().Str("output", output).Err(err).Msg("Batch script stopped; will run again on restart")
+		return
+	}
+	switch {
+	case errors.Is(err, errDrillSkipped):
+		log.Debug().Msg("Batch script skipped for drill")
+	case err != nil:
+		log.Error().Str("output", output).Err(err).Msg("Failed to run batch script")
+	}
+	for _, item := range items {
+		s.completeAction(ctx, item.event, begun[item], actionBatchScript, err == nil)
+		s.recordActionResult(ctx, item.event, actionBatchScript, started, output, err)
+	}
+}
+
+// batchInput returns the stdin document and arguments for the batch script.
+func (s *Service) batchInput(ctx context.Context, batch *batchItem, items []*actionItem) (*batchDocument, []string) {
+	// Public keys may be missing, for example if the action was resumed after a restart.
+	missing := make([]phase0.ValidatorIndex, 0)
+	for _, item := range items {
+		if item.event.PubKey.IsZero() {
+			missing = append(missing, item.event.ValidatorIndex)
+		}
+	}
+	pubKeys := make(map[phase0.ValidatorIndex]phase0.BLSPubKey)
+	if len(missing) > 0 {
+		var err error
+		pubKeys, err = s.validatorPubKeys(ctx, missing)
+		if err != nil {
+			s.log.Warn().Err(err).Msg("Failed to obtain public keys of slashed validators; continuing without them")
+		}
+	}
+
+	doc := &batchDocument{
+		Slot:       batch.slot,
+		BlockRoot:  fmt.Sprintf("%#x", batch.root),
+		Validators: make([]*batchValidator, 0, len(items)),
+	}
+	args := make([]string, 0, len(items))
+	for _, item := range items {
+		validator := &batchValidator{
+			Index:    item.event.ValidatorIndex,
+			Offences: offenceTypes(item.event.Offences),
+			Kinds:    offenceKinds(item.event.Offences),
+			Details:  offenceDescriptions(item.event.Offences),
+			Penalty:  item.event.Penalty,
+		}
+		pubKey := item.event.PubKey
+		if pubKey.IsZero() {
+			pubKey = pubKeys[item.event.ValidatorIndex]
+		}
+		if !pubKey.IsZero() {
+			validator.PubKey = fmt.Sprintf("%#x", pubKey)
+		}
+		doc.Validators = append(doc.Validators, validator)
+		args = append(args, fmt.Sprintf("%d", item.event.ValidatorIndex))
+	}
+
+	return doc, args
+}
+
+// validatorPubKeys returns the public keys of the given validators.
+func (s *Service) validatorPubKeys(ctx context.Context, indices []phase0.ValidatorIndex) (map[phase0.ValidatorIndex]phase0.BLSPubKey, error) {
+	provider, isProvider := s.eth2Client.(eth2client.ValidatorsProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide validators")
+	}
+	response, err := provider.Validators(ctx, &api.ValidatorsOpts{
+		State:   "head",
+		Indices: indices,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain validators")
+	}
+
+	pubKeys := make(map[phase0.ValidatorIndex]phase0.BLSPubKey, len(response.Data))
+	for index, validator := range response.Data {
+		pubKeys[index] = validator.Validator.PublicKey
+	}
+
+	return pubKeys, nil
+}
@@ -0,0 +1,341 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/attestantio/esd/services/ledger"
+	"github.com/attestantio/esd/util"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+)
+
+// drainPollInterval is the interval at which the pipeline is checked while draining.
+const drainPollInterval = 100 * time.Millisecond
+
+// stopActionsTimeout is the time to wait for running scripts to be killed when a drain
+// does not complete in time.
+const stopActionsTimeout = 10 * time.Second
+
+// checkpoint is the record of the slot up to which blocks have been processed.
+type checkpoint struct {
+	Slot phase0.Slot `json:"slot,string"`
+}
+
+// Drain stops accepting head events and waits, until the context is done, for the
+// blocks that have been queued to be processed and for their actions to finish.  It
+// then records the checkpoint from which to resume, along with any actions that have
+// not started so that they run on restart.  Scripts still running when the context is
+// done are killed, and run again on restart.
+// Returns an error if the pipeline did not drain in time.
+func (s *Service) Drain(ctx context.Context) error {
+	s.headMu.Lock()
+	s.draining = true
+	s.headMu.Unlock()
+
+	s.subscriptionMu.Lock()
+	if s.cancelSubscription != nil {
+		s.cancelSubscription()
+		s.cancelSubscription = nil
+	}
+	s.subscriptionMu.Unlock()
+	s.log.Info().Msg("Stopped accepting head events; draining")
+
+	drained := s.waitForIdle(ctx)
+
+	// The context may be done, but the remaining work must still be recorded.
+	//nolint:contextcheck
+	persistCtx := context.Background()
+	var err error
+	if !drained {
+		running := s.runningActions()
+		s.stopRunningActions(persistCtx)
+		queued := s.persistQueuedActions(persistCtx)
+		s.log.Warn().Int("queued_actions", queued).Int64("running_actions", running).Msg("Pipeline did not drain in time; unfinished actions will resume on restart")
+		err = errors.New("pipeline did not drain in time")
+	}
+
+	slot := s.checkpointSlot()
+	if saveErr := s.saveCheckpoint(slot); saveErr != nil {
+		s.log.Error().Err(saveErr).Msg("Failed to save checkpoint")
+		if err == nil {
+			err = saveErr
+		}
+	} else if s.checkpointFile != "" {
+		s.log.Info().Uint64("slot", uint64(slot)).Msg("Saved checkpoint")
+	}
+	if err == nil {
+		s.log.Info().Msg("Pipeline drained")
+	}
+
+	return err
+}
+
+// stopRunningActions stops the action workers, and waits for the scripts that they are
+// running to be killed.  Actions that have not started are left on their queues.
+func (s *Service) stopRunningActions(ctx context.Context) {
+	if s.stopActions == nil {
+		return
+	}
+	s.stopActions()
+
+	ctx, cancel := context.WithTimeout(ctx, stopActionsTimeout)
+	defer cancel()
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for s.runningActions() > 0 {
+		select {
+		case <-ctx.Done():
+			s.log.Warn().Int64("running_actions", s.runningActions()).Msg("Running actions did not stop in time")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runningActions returns the number of actions, batches and mass slashings that are running.
+func (s *Service) runningActions() int64 {
+	return s.pendingActions.Load() - int64(s.actionQueueDepth()+len(s.batchQueue)+len(s.massQueue))
+}
+
+// isDraining returns true if the service is draining.
+func (s *Service) isDraining() bool {
+	s.headMu.Lock()
+	defer s.headMu.Unlock()
+
+	return s.draining
+}
+
+// waitForIdle waits until the pipeline is idle or the context is done, returning true
+// if the pipeline is idle.
+func (s *Service) waitForIdle(ctx context.Context) bool {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		if s.idle() {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// idle returns true if there are no blocks or actions in the pipeline.
+func (s *Service) idle() bool {
+	s.headMu.Lock()
+	inFlightBlocks := len(s.inFlightBlocks)
+	s.headMu.Unlock()
+
+	return inFlightBlocks == 0 && s.pendingActions.Load() == 0
+}
+
+// persistQueuedActions removes the actions that have not started from their queues and
+// records them in the ledger, so that they are resumed on restart.  Actions that have
+// started are already recorded.  Returns the number of actions recorded.
+func (s *Service) persistQueuedActions(ctx context.Context) int {
+	if s.ledger == nil {
+		return 0
+	}
+
+	queued := 0
+	for _, queue := range s.actionQueues {
+	actions:
+		for {
+			select {
+			case item := <-queue:
+				s.pendingActions.Add(-1)
+				for _, script := range s.actionScripts(item.event) {
+					s.queueInLedger(ctx, item, script.action)
+					queued++
+				}
+			default:
+				break actions
+			}
+		}
+	}
+batches:
+	for {
+		select {
+		case batch := <-s.batchQueue:
+			s.pendingActions.Add(-1)
+			for _, item := range batch.items {
+				s.queueInLedger(ctx, item, actionBatchScript)
+				queued++
+			}
+		default:
+			break batches
+		}
+	}
+mass:
+	for {
+		select {
+		case item := <-s.massQueue:
+			s.pendingActions.Add(-1)
+			if s.queueMassInLedger(ctx, item) {
+				queued++
+			}
+		default:
+			break mass
+		}
+	}
+
+	return queued
+}
+
+// queueInLedger records an action that has not started in the ledger.
+func (s *Service) queueInLedger(ctx context.Context, item *actionItem, action string) {
+	event := item.event
+	for _, offence := range event.Offences {
+		if err := s.ledger.Queue(ctx, &ledger.Entry{
+			Key:       *actionKey(event, offence, action),
+			Slot:      event.Slot,
+			BlockRoot: event.BlockRoot,
+		}); err != nil {
+			s.log.Error().Uint64("validator_index", uint64(event.ValidatorIndex)).Str("action", action).Err(err).Msg("Failed to record queued action")
+		}
+	}
+}
+
+// queueMassInLedger records the script of a mass slashing that has not started in the
+// ledger.  Returns true if it was recorded.
+func (s *Service) queueMassInLedger(ctx context.Context, item *massItem) bool {
+	if s.cfg.Load().massSlashingScript == "" {
+		return false
+	}
+	data, err := json.Marshal(item.event)
+	if err != nil {
+		s.log.Error().Str("action", actionMassSlashingScript).Err(err).Msg("Failed to marshal mass slashing")
+		return false
+	}
+	if err := s.ledger.Queue(ctx, massLedgerEntry(item, data)); err != nil {
+		s.log.Error().Str("action", actionMassSlashingScript).Err(err).Msg("Failed to record queued action")
+		return false
+	}
+
+	return true
+}
+
+// checkpointSlot returns the slot up to which all blocks have been processed: the last
+// slot queued, or the slot before the earliest block that is still in the pipeline or
+// held awaiting validation.
+func (s *Service) checkpointSlot() phase0.Slot {
+	s.headMu.Lock()
+	slot := s.lastQueuedSlot
+	for inFlightSlot := range s.inFlightBlocks {
+		if inFlightSlot <= slot {
+			slot = previousSlot(inFlightSlot)
+		}
+	}
+	s.headMu.Unlock()
+
+	s.heldSlashingsMu.Lock()
+	for _, held := range s.heldSlashings {
+		if held.slot <= slot {
+			slot = previousSlot(held.slot)
+		}
+	}
+	s.heldSlashingsMu.Unlock()
+
+	return slot
+}
+
+// previousSlot returns the slot before the given slot.
+func previousSlot(slot phase0.Slot) phase0.Slot {
+	if slot == 0 {
+		return 0
+	}
+
+	return slot - 1
+}
+
+// saveCheckpoint records the checkpoint, if a checkpoint file is configured.
+func (s *Service) saveCheckpoint(slot phase0.Slot) error {
+	if s.checkpointFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(&checkpoint{Slot: slot})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal checkpoint")
+	}
+
+	if err := util.WriteFileAtomic(s.checkpointFile, data, 0o600); err != nil {
+		return errors.Wrap(err, "failed to write checkpoint file")
+	}
+
+	return nil
+}
+
+// loadCheckpoint loads the checkpoint recorded when the service was last drained, if
+// there is one, by setting the last queued slot to that of the checkpoint.  The
+// checkpoint is kept until resumeFromCheckpoint has queued the blocks after it, unless
+// it cannot be used.
+// Returns true if a checkpoint was loaded.
+func (s *Service) loadCheckpoint(_ context.Context) bool {
+	if s.checkpointFile == "" {
+		return false
+	}
+
+	data, err := os.ReadFile(s.checkpointFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			s.log.Warn().Err(err).Msg("Failed to read checkpoint; starting from head")
+		}
+		return false
+	}
+
+	record := &checkpoint{}
+	if err := json.Unmarshal(data, record); err != nil {
+		s.log.Warn().Err(err).Msg("Invalid checkpoint; starting from head")
+		s.removeCheckpoint()
+		return false
+	}
+	if record.Slot >= s.lastQueuedSlot {
+		s.log.Debug().Uint64("slot", uint64(record.Slot)).Msg("Checkpoint is at or after head; starting from head")
+		s.removeCheckpoint()
+		return false
+	}
+
+	s.log.Info().Uint64("slot", uint64(record.Slot)).Uint64("head_slot", uint64(s.lastQueuedSlot)).Msg("Resuming from checkpoint")
+	s.lastQueuedSlot = record.Slot
+
+	return true
+}
+
+// resumeFromCheckpoint queues the blocks after the checkpoint loaded by loadCheckpoint.
+// The checkpoint is removed once they have been queued, so that an unclean stop later
+// on does not resume from a stale checkpoint; if they could not be queued then it is
+// kept, so that they are resumed on restart.
+func (s *Service) resumeFromCheckpoint(ctx context.Context) {
+	// Nothing else queues blocks until subscribed, so the last queued slot is current.
+	if err := s.backfill(ctx, s.lastQueuedSlot+1); err != nil {
+		s.log.Warn().Err(err).Msg("Failed to resume from checkpoint; keeping it for restart")
+		return
+	}
+	s.removeCheckpoint()
+}
+
+// removeCheckpoint removes the checkpoint.
+func (s *Service) removeCheckpoint() {
+	if err := os.Remove(s.checkpointFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.log.Warn().Err(err).Msg("Failed to remove checkpoint")
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/attestantio/esd/services/ledger"
+	standardledger "github.com/attestantio/esd/services/ledger/standard"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// drainService returns a service with queues but no workers, so that queued work stays
+// in the pipeline.
+func drainService(t *testing.T, ledgerSvc ledger.Service) *Service {
+	t.Helper()
+
+	s := &Service{
+		log:            zerolog.Nop(),
+		ledger:         ledgerSvc,
+		checkpointFile: filepath.Join(t.TempDir(), "checkpoint.json"),
+		lastQueuedSlot: 110,
+		inFlightBlocks: make(map[phase0.Slot]int),
+		heldSlashings:  make(map[phase0.Root]*blockSlashings),
+		actionQueues:   []chan *actionItem{make(chan *actionItem, 4), make(chan *actionItem, 4)},
+		batchQueue:     make(chan *batchItem, 4),
+		massQueue:      make(chan *massItem, 4),
+	}
+	s.cfg.Store(&config{
+		attesterSlashedScript: "/bin/true",
+		batchScript:           "/bin/true",
+	})
+
+	return s
+}
+
+// drainEvent returns an attester slashing of the given validator.
+func drainEvent(validator phase0.ValidatorIndex) *slashings.SlashingEvent {
+	return &slashings.SlashingEvent{
+		Slot:           105,
+		BlockRoot:      phase0.Root{0x01},
+		ValidatorIndex: validator,
+		Offences: []*slashings.Offence{
+			{
+				Kind:         slashings.OffenceDoubleVote,
+				EvidenceHash: phase0.Root{byte(validator)},
+			},
+		},
+	}
+}
+
+// readCheckpoint returns the slot in the checkpoint file.
+func readCheckpoint(t *testing.T, s *Service) phase0.Slot {
+	t.Helper()
+
+	s.lastQueuedSlot = 1000
+	require.True(t, s.loadCheckpoint(context.Background()))
+
+	return s.lastQueuedSlot
+}
+
+func TestDrainIdle(t *testing.T) {
+	s := drainService(t, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, s.Drain(ctx))
+	require.True(t, s.isDraining())
+	require.Equal(t, phase0.Slot(110), readCheckpoint(t, s))
+}
+
+func TestDrainTimeout(t *testing.T) {
+	ctx := context.Background()
+	ledgerSvc, err := standardledger.New(ctx, standardledger.WithPath(filepath.Join(t.TempDir(), "ledger.db")))
+	require.NoError(t, err)
+	s := drainService(t, ledgerSvc)
+
+	// Actions for two validators and a batch for their block are queued, and the block
+	// after it is still being fetched.
+	block := &blockSlashings{
+		slot:   105,
+		root:   phase0.Root{0x01},
+		events: []*slashings.SlashingEvent{drainEvent(1), drainEvent(2)},
+	}
+	for _, item := range block.actionItems() {
+		s.queueAction(ctx, item)
+	}
+	s.queueBatch(ctx, block.batchItem())
+	s.inFlightBlocks[107] = 1
+	require.Equal(t, int64(3), s.pendingActions.Load())
+
+	drainCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	require.EqualError(t, s.Drain(drainCtx), "pipeline did not drain in time")
+
+	// The queued actions are removed from the pipeline and recorded in the ledger.
+	require.Equal(t, int64(0), s.pendingActions.Load())
+	require.Zero(t, s.actionQueueDepth())
+	require.Empty(t, s.batchQueue)
+	unfinished, err := ledgerSvc.Unfinished(ctx)
+	require.NoError(t, err)
+	actions := make(map[phase0.ValidatorIndex][]string)
+	for _, entry := range unfinished {
+		require.Equal(t, ledger.StateQueued, entry.State)
+		actions[entry.ValidatorIndex] = append(actions[entry.ValidatorIndex], entry.Action)
+	}
+	require.Len(t, actions, 2)
+	for _, validator := range []phase0.ValidatorIndex{1, 2} {
+		require.ElementsMatch(t, []string{actionAttesterSlashedScript, actionBatchScript}, actions[validator])
+	}
+
+	// The checkpoint is before the block that is still in the pipeline.
+	require.Equal(t, phase0.Slot(106), readCheckpoint(t, s))
+}
+
+func TestPersistQueuedActionsNoLedger(t *testing.T) {
+	ctx := context.Background()
+	s := drainService(t, nil)
+	s.queueAction(ctx, &actionItem{event: drainEvent(1)})
+
+	require.Zero(t, s.persistQueuedActions(ctx))
+	// Without a ledger the actions are left for the workers.
+	require.Equal(t, 1, s.actionQueueDepth())
+}
+
+func TestCheckpointSlot(t *testing.T) {
+	tests := []struct {
+		name           string
+		lastQueuedSlot phase0.Slot
+		inFlight       []phase0.Slot
+		held           []phase0.Slot
+		slot           phase0.Slot
+	}{
+		{
+			name:           "Idle",
+			lastQueuedSlot: 110,
+			slot:           110,
+		},
+		{
+			name:           "InFlight",
+			lastQueuedSlot: 110,
+			inFlight:       []phase0.Slot{108, 105, 109},
+			slot:           104,
+		},
+		{
+			name:           "Held",
+			lastQueuedSlot: 110,
+			held:           []phase0.Slot{107},
+			slot:           106,
+		},
+		{
+			name:           "HeldBeforeInFlight",
+			lastQueuedSlot: 110,
+			inFlight:       []phase0.Slot{105},
+			held:           []phase0.Slot{103},
+			slot:           102,
+		},
+		{
+			name:           "InFlightBeforeHeld",
+			lastQueuedSlot: 110,
+			inFlight:       []phase0.Slot{103},
+			held:           []phase0.Slot{105},
+			slot:           102,
+		},
+		{
+			name:           "Genesis",
+			lastQueuedSlot: 10,
+			inFlight:       []phase0.Slot{0},
+			slot:           0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := drainService(t, nil)
+			s.lastQueuedSlot = test.lastQueuedSlot
+			for _, slot := range test.inFlight {
+				s.inFlightBlocks[slot]++
+			}
+			for i, slot := range test.held {
+				s.heldSlashings[phase0.Root{byte(i + 1)}] = &blockSlashings{slot: slot}
+			}
+			require.Equal(t, test.slot, s.checkpointSlot())
+		})
+	}
+}
+
+func TestResumeFromCheckpoint(t *testing.T) {
+	tests := []struct {
+		name       string
+		checkpoint string
+		syncingErr error
+		loaded     bool
+		backfill   []phase0.Slot
+		kept       bool
+	}{
+		{
+			name:       "Resumed",
+			checkpoint: `{"slot":"107"}`,
+			loaded:     true,
+			backfill:   []phase0.Slot{108, 109, 110},
+		},
+		{
+			name:       "ResumeFailed",
+			checkpoint: `{"slot":"107"}`,
+			syncingErr: errors.New("unavailable"),
+			loaded:     true,
+			backfill:   []phase0.Slot{},
+			kept:       true,
+		},
+		{
+			name:       "AtHead",
+			checkpoint: `{"slot":"110"}`,
+			backfill:   []phase0.Slot{},
+		},
+		{
+			name:       "Invalid",
+			checkpoint: `{"slot":`,
+			backfill:   []phase0.Slot{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := drainService(t, nil)
+			s.eth2Client = &mockClient{headSlot: 110, syncingErr: test.syncingErr}
+			s.syncClient = &http.Client{}
+			s.syncCheckInterval = time.Second
+			s.blockQueue = make(chan *blockItem, 16)
+			require.NoError(t, os.WriteFile(s.checkpointFile, []byte(test.checkpoint), 0o600))
+
+			loaded := s.loadCheckpoint(ctx)
+			require.Equal(t, test.loaded, loaded)
+			if loaded {
+				// The checkpoint is kept until the blocks after it have been queued.
+				require.FileExists(t, s.checkpointFile)
+				s.resumeFromCheckpoint(ctx)
+			}
+
+			backfill := make([]phase0.Slot, 0)
+			for len(s.blockQueue) > 0 {
+				backfill = append(backfill, (<-s.blockQueue).slot)
+			}
+			require.Equal(t, test.backfill, backfill)
+			if test.kept {
+				require.FileExists(t, s.checkpointFile)
+			} else {
+				require.NoFileExists(t, s.checkpointFile)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+)
+
+// drillEnv is the environment variable that tells scripts they are being run for a drill.
+const drillEnv = "ESD_DRILL=1"
+
+// actionNotifyMassSlashing is the name of the action that informs the handlers of a mass
+// slashing, as reported by drills.
+const actionNotifyMassSlashing = "notify-mass-slashing"
+
+// errDrillSkipped is returned in place of running a script for a drill that does not
+// run scripts.
+var errDrillSkipped = errors.New("skipped for drill")
+
+// Drill passes a synthetic slashing event through the notifiers, the slashed scripts,
+// the batch script and, if mass slashings are detected, a mass slashing of the
+// validator, returning the result of each.  Scripts are skipped unless the service
+// was created to run them, in which case they are run with ESD_DRILL=1 so that they
+// can avoid destructive steps.  Nothing is recorded in the ledger or history.  An
+// action blocked by the controls is a failure, as it would not run for a real slashing
+// either.
+func (s *Service) Drill(ctx context.Context, event *slashings.SlashingEvent) []*slashings.DrillResult {
+	s.drillMu.Lock()
+	s.drillResults = make([]*slashings.DrillResult, 0)
+	s.drillMu.Unlock()
+
+	block := &blockSlashings{
+		slot:   event.Slot,
+		root:   event.BlockRoot,
+		events: []*slashings.SlashingEvent{event},
+	}
+	s.notifySlashings(ctx, block)
+	for _, item := range block.actionItems() {
+		s.runAction(ctx, item)
+	}
+	s.runBatch(ctx, block.batchItem())
+	if s.massSlashingEnabled() {
+		s.handleMassSlashing(ctx, s.drillMassItem(event))
+	}
+
+	s.drillMu.Lock()
+	defer s.drillMu.Unlock()
+
+	return s.drillResults
+}
+
+// drillMassItem returns a mass slashing of the validator in a synthetic event.
+func (s *Service) drillMassItem(event *slashings.SlashingEvent) *massItem {
+	return &massItem{
+		slot: event.Slot,
+		root: event.BlockRoot,
+		event: &slashings.MassSlashingEvent{
+			FromEpoch:        s.massWindowStart(),
+			ToEpoch:          s.chainTime.CurrentEpoch(),
+			Count:            1,
+			Balance:          event.EffectiveBalance,
+			ValidatorIndices: []phase0.ValidatorIndex{event.ValidatorIndex},
+			Drill:            true,
+		},
+	}
+}
+
+// recordDrillResult records the outcome of an action for a drill.  It does nothing if
+// the service is not running drills.
+func (s *Service) recordDrillResult(action string, target string, output string, err error) {
+	if !s.drill {
+		return
+	}
+
+	result := &slashings.DrillResult{
+		Action:  action,
+		Target:  target,
+		Success: err == nil,
+		Skipped: errors.Is(err, errDrillSkipped),
+		Output:  output,
+	}
+	if err != nil && !result.Skipped {
+		result.Error = err.Error()
+	}
+
+	s.drillMu.Lock()
+	s.drillResults = append(s.drillResults, result)
+	s.drillMu.Unlock()
+}
+
+// actionTarget returns the script run by an action.
+func (s *Service) actionTarget(action string) string {
+	cfg := s.cfg.Load()
+	switch action {
+	case actionAttesterSlashedScript:
+		return cfg.attesterSlashedScript
+	case actionProposerSlashedScript:
+		return cfg.proposerSlashedScript
+	case actionBatchScript:
+		return cfg.batchScript
+	case actionMassSlashingScript:
+		return cfg.massSlashingScript
+	default:
+		return ""
+	}
+}
+
+// handlerTarget returns a description of a handler for a drill.
+func handlerTarget(handler any) string {
+	if stringer, isStringer := handler.(fmt.Stringer); isStringer {
+		return stringer.String()
+	}
+
+	return fmt.Sprintf("%T", handler)
+}
@@ -0,0 +1,73 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+
+	eth2client "github.com/attestantio/go-eth2-client"
+	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/pkg/errors"
+)
+
+// subscribe subscribes to head events, replacing any existing subscription.
+func (s *Service) subscribe(ctx context.Context) error {
+	eventsProvider, isEventsProvider := s.eth2Client.(eth2client.EventsProvider)
+	if !isEventsProvider {
+		return errors.New("eth2 client is not an events provider")
+	}
+
+	s.subscriptionMu.Lock()
+	defer s.subscriptionMu.Unlock()
+
+	if s.cancelSubscription != nil {
+		s.log.Trace().Msg("Cancelling existing head event subscription")
+		s.cancelSubscription()
+		s.cancelSubscription = nil
+	}
+
+	// The subscription has its own context so that it can be torn down independently,
+	// but events are processed with the parent context.
+	subscriptionCtx, cancel := context.WithCancel(ctx)
+	if err := eventsProvider.Events(subscriptionCtx, []string{"head"}, func(event *apiv1.Event) {
+		s.onEvent(ctx, event)
+	}); err != nil {
+		cancel()
+		return errors.Wrap(err, "failed to configure head event feed")
+	}
+	s.cancelSubscription = cancel
+
+	return nil
+}
+
+// onEvent handles an event from the beacon node.
+func (s *Service) onEvent(ctx context.Context, event *apiv1.Event) {
+	if event.Data == nil {
+		return
+	}
+
+	eventData, isEventData := event.Data.(*apiv1.HeadEvent)
+	if !isEventData {
+		s.log.Error().Msg("event data is not from a head event; cannot process")
+		return
+	}
+
+	if s.isDraining() {
+		s.log.Trace().Uint64("slot", uint64(eventData.Slot)).Msg("Draining; ignoring head event")
+		return
+	}
+
+	s.headReceived(ctx, eventData.Slot)
+	s.OnHeadUpdated(ctx, eventData.Slot, eventData.Block)
+}
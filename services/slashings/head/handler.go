@@ -17,29 +17,75 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
+	"github.com/attestantio/esd/services/slashings"
 	eth2client "github.com/attestantio/go-eth2-client"
 	"github.com/attestantio/go-eth2-client/api"
-	spec "github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
 )
 
+// blockSlashings are the slashings found in a single block.
+type blockSlashings struct {
+	slot       phase0.Slot
+	root       phase0.Root
+	parentRoot phase0.Root
+	stateRoot  phase0.Root
+	events     []*slashings.SlashingEvent
+	// notified is true if the slashings have already been reported.
+	notified bool
+}
+
 // OnHeadUpdated handles head notifications.
 func (s *Service) OnHeadUpdated(
 	ctx context.Context,
-	_ spec.Slot,
-	blockRoot spec.Root,
+	slot phase0.Slot,
+	blockRoot phase0.Root,
+) {
+	s.enqueueBlock(ctx, slot, blockRoot.String(), false)
+}
+
+// fetchSlashings fetches the given block and returns the slashings it contains, and if the
+// block is optimistic.
+func (s *Service) fetchSlashings(ctx context.Context,
+	blockID string,
+	source slashings.Source,
+) (
+	*blockSlashings,
+	bool,
+	error,
 ) {
-	// Fetch the block.
 	blockResponse, err := s.eth2Client.(eth2client.SignedBeaconBlockProvider).SignedBeaconBlock(ctx, &api.SignedBeaconBlockOpts{
-		Block: blockRoot.String(),
+		Block: blockID,
 	})
 	if err != nil {
-		s.log.Error().Err(err).Msg("Failed to obtain block")
-		return
+		return nil, false, errors.Wrap(err, "failed to obtain block")
 	}
 	block := blockResponse.Data
-	s.log.Trace().Str("block_root", fmt.Sprintf("%#x", blockRoot)).Msg("Obtained block")
+	s.log.Trace().Str("block", blockID).Msg("Obtained block")
+
+	res, err := s.slashingsFromBlock(block, source)
+	if err != nil {
+		return nil, false, err
+	}
+	if res != nil {
+		s.enrichSlashings(ctx, res)
+	}
+
+	optimistic := executionOptimistic(blockResponse.Metadata) || s.nodeOptimistic()
 
+	return res, optimistic, nil
+}
+
+// slashingsFromBlock returns the slashings in the block, or nil if there are none.
+func (s *Service) slashingsFromBlock(block *spec.VersionedSignedBeaconBlock,
+	source slashings.Source,
+) (
+	*blockSlashings,
+	error,
+) {
 	attesterSlashings, err := block.AttesterSlashings()
 	if err != nil {
 		s.log.Error().Err(err).Msg("Failed to obtain attester slashings")
@@ -51,45 +97,354 @@ func (s *Service) OnHeadUpdated(
 
 	if len(attesterSlashings) == 0 &&
 		len(proposerSlashings) == 0 {
-		s.log.Trace().Msg("No slashings")
+		return nil, nil
+	}
+
+	slot, err := block.Slot()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain slot")
+	}
+	root, err := block.Root()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain root")
+	}
+	parentRoot, err := block.ParentRoot()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain parent root")
+	}
+	stateRoot, err := block.StateRoot()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain state root")
+	}
+	proposerIndex, err := block.ProposerIndex()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain proposer index")
+	}
+
+	res := &blockSlashings{
+		slot:       slot,
+		root:       root,
+		parentRoot: parentRoot,
+		stateRoot:  stateRoot,
+		events:     make([]*slashings.SlashingEvent, 0, len(proposerSlashings)),
+	}
+	events := make(map[phase0.ValidatorIndex]*slashings.SlashingEvent)
+	addOffence := func(validatorIndex phase0.ValidatorIndex, offence *slashings.Offence) {
+		event, exists := events[validatorIndex]
+		if !exists {
+			event = &slashings.SlashingEvent{
+				ValidatorIndex: validatorIndex,
+				Offences:       make([]*slashings.Offence, 0, 1),
+				Slot:           slot,
+				BlockRoot:      root,
+				ProposerIndex:  proposerIndex,
+				Source:         source,
+				Status:         slashings.StatusPending,
+			}
+			events[validatorIndex] = event
+			res.events = append(res.events, event)
+		}
+		for _, existing := range event.Offences {
+			if existing.Kind == offence.Kind && existing.EvidenceHash == offence.EvidenceHash {
+				// Same evidence included more than once.
+				return
+			}
+		}
+		event.Offences = append(event.Offences, offence)
+	}
+
+	for _, attesterSlashing := range attesterSlashings {
+		evidenceHash, err := attesterSlashing.HashTreeRoot()
+		if err != nil {
+			s.log.Error().Err(err).Msg("Failed to obtain hash of attester slashing")
+		}
+		kind, surrounding := slashings.ClassifyAttestations(attesterSlashing.Attestation1.Data, attesterSlashing.Attestation2.Data)
+		for _, validatorIndex := range intersection(attesterSlashing.Attestation1.AttestingIndices, attesterSlashing.Attestation2.AttestingIndices) {
+			addOffence(validatorIndex, &slashings.Offence{
+				Kind:         kind,
+				Surrounding:  surrounding,
+				EvidenceHash: evidenceHash,
+				Attestation1: attesterSlashing.Attestation1,
+				Attestation2: attesterSlashing.Attestation2,
+			})
+		}
+	}
+	for _, proposerSlashing := range proposerSlashings {
+		evidenceHash, err := proposerSlashing.HashTreeRoot()
+		if err != nil {
+			s.log.Error().Err(err).Msg("Failed to obtain hash of proposer slashing")
+		}
+		addOffence(proposerSlashing.SignedHeader1.Message.ProposerIndex, &slashings.Offence{
+			Kind:         slashings.OffenceDoubleProposal,
+			EvidenceHash: evidenceHash,
+			Header1:      proposerSlashing.SignedHeader1,
+			Header2:      proposerSlashing.SignedHeader2,
+		})
+	}
+
+	return res, nil
+}
+
+// enrichSlashings adds information about the slashed validators and the evidence
+// against them to the slashings.
+func (s *Service) enrichSlashings(ctx context.Context, block *blockSlashings) {
+	s.addValidatorState(ctx, block)
+	s.verifySlashings(ctx, block)
+	s.estimatePenalties(ctx, block)
+}
+
+// addPubKeys adds the public keys of the slashed validators to their events.
+func (s *Service) addPubKeys(ctx context.Context, block *blockSlashings) {
+	indices := make([]phase0.ValidatorIndex, 0, len(block.events))
+	for _, event := range block.events {
+		indices = append(indices, event.ValidatorIndex)
+	}
+	pubKeys, err := s.validatorPubKeys(ctx, indices)
+	if err != nil {
+		s.log.Warn().Uint64("slot", uint64(block.slot)).Err(err).Msg("Failed to obtain public keys of slashed validators; continuing without them")
 		return
 	}
+	for _, event := range block.events {
+		event.PubKey = pubKeys[event.ValidatorIndex]
+	}
+}
+
+// handleSlashings synchronously reports the slashings and runs the actions for them.
+func (s *Service) handleSlashings(ctx context.Context, block *blockSlashings) {
+	if !block.notified {
+		s.notifySlashings(ctx, block)
+	}
 
-	// Report on attester slashings.
-	s.attesterSlashings(ctx, attesterSlashings)
-	// Report on proposer slashings.
-	s.proposerSlashings(ctx, proposerSlashings)
+	for _, item := range block.actionItems() {
+		s.runAction(ctx, item)
+	}
+	s.runBatch(ctx, block.batchItem())
+	if massItem := s.recordMassSlashings(ctx, block); massItem != nil {
+		s.handleMassSlashing(ctx, massItem)
+	}
 }
 
-func (s *Service) attesterSlashings(ctx context.Context, slashings []*spec.AttesterSlashing) {
-	for _, slashing := range slashings {
-		slashedIndices := intersection(slashing.Attestation1.AttestingIndices, slashing.Attestation2.AttestingIndices)
-		for _, validatorIndex := range slashedIndices {
-			s.log.Info().Uint64("validator_index", uint64(validatorIndex)).Msg("Validator slashed (attester)")
-			if err := s.OnAttesterSlashed(ctx, validatorIndex); err != nil {
-				s.log.Error().Err(err).Msg("Failed to run script")
+// notifySlashings carries out the non-destructive reporting of slashings.
+func (s *Service) notifySlashings(ctx context.Context, block *blockSlashings) {
+	for _, event := range block.events {
+		if !s.firstNotification(ctx, event) {
+			s.log.Debug().Uint64("slot", uint64(event.Slot)).Uint64("validator_index", uint64(event.ValidatorIndex)).Msg("Slashing already reported")
+			continue
+		}
+		if event.AlreadySlashed {
+			s.log.Info().
+				Uint64("slot", uint64(event.Slot)).
+				Uint64("validator_index", uint64(event.ValidatorIndex)).
+				Strs("offences", offenceKinds(event.Offences)).
+				Str("source", string(event.Source)).
+				Msg("Validator included in slashing but already slashed; not penalised")
+			alreadySlashedFound(ctx)
+			s.notifyHandlers(ctx, event)
+			continue
+		}
+		e := s.log.Info().
+			Uint64("slot", uint64(event.Slot)).
+			Uint64("validator_index", uint64(event.ValidatorIndex)).
+			Strs("offences", offenceKinds(event.Offences)).
+			Strs("details", offenceDescriptions(event.Offences)).
+			Str("source", string(event.Source))
+		if event.Penalty != nil {
+			e = e.Uint64("initial_penalty", uint64(event.Penalty.InitialPenalty)).
+				Uint64("correlation_penalty", uint64(event.Penalty.CorrelationPenalty))
+		}
+		e.Msg("Validator slashed")
+		slashingFound(ctx, event)
+		s.notifyHandlers(ctx, event)
+	}
+	s.recordSlashings(ctx, block)
+	block.notified = true
+}
+
+// notifyHandlers passes a slashing event to the handlers.
+func (s *Service) notifyHandlers(ctx context.Context, event *slashings.SlashingEvent) {
+	for _, handler := range s.cfg.Load().handlers {
+		err := handler.OnSlashing(ctx, event)
+		if err != nil {
+			s.log.Error().Uint64("validator_index", uint64(event.ValidatorIndex)).Err(err).Msg("Handler failed to handle slashing")
+		}
+		s.recordDrillResult(actionNotify, handlerTarget(handler), "", err)
+	}
+}
+
+// notifyStatus informs handlers that are interested of a change in the finality status
+// of a slashing.
+func (s *Service) notifyStatus(ctx context.Context, event *slashings.SlashingEvent) {
+	for _, handler := range s.cfg.Load().handlers {
+		statusHandler, isHandler := handler.(slashings.StatusHandler)
+		if !isHandler {
+			continue
+		}
+		if err := statusHandler.OnStatus(ctx, event); err != nil {
+			s.log.Error().Uint64("validator_index", uint64(event.ValidatorIndex)).Err(err).Msg("Handler failed to handle status change")
+		}
+	}
+}
+
+// actionItems returns the actions to run for the slashings, in order.
+func (b *blockSlashings) actionItems() []*actionItem {
+	items := make([]*actionItem, 0, len(b.events))
+	for _, event := range b.events {
+		if event.Unverified() {
+			// Evidence could not be verified, so do not act on it.
+			continue
+		}
+		if event.AlreadySlashed {
+			// Validator is not penalised by these slashings, so do not act on it.
+			continue
+		}
+		items = append(items, &actionItem{
+			event: event,
+		})
+	}
+
+	return items
+}
+
+// OnSlashing handles a slashing event by running the scripts for the slashed validator.
+// The proposer slashed script is run if the validator committed a proposer offence, and
+// the attester slashed script if it committed an attester offence; a validator that
+// committed both is passed to each script once, with details of all of the offences.
+func (s *Service) OnSlashing(ctx context.Context, event *slashings.SlashingEvent) error {
+	var res error
+	for _, script := range s.actionScripts(event) {
+		output, err := s.runEventScript(ctx, event, script)
+		if err != nil {
+			s.log.Warn().Str("action", script.action).Str("output", output).Err(err).Msg("Run information")
+			if res == nil {
+				res = err
 			}
-			slashingFound(ctx, validatorIndex)
 		}
 	}
+
+	return res
 }
 
-func (s *Service) proposerSlashings(ctx context.Context, slashings []*spec.ProposerSlashing) {
-	for _, slashing := range slashings {
-		validatorIndex := slashing.SignedHeader1.Message.ProposerIndex
-		s.log.Info().Uint64("validator_index", uint64(validatorIndex)).Msg("Validator slashed (proposer)")
-		if err := s.OnProposerSlashed(ctx, validatorIndex); err != nil {
-			s.log.Error().Err(err).Msg("Failed to run script")
+// runAction runs the scripts for a single slashed validator.
+func (s *Service) runAction(ctx context.Context, item *actionItem) {
+	for _, script := range s.actionScripts(item.event) {
+		if !s.runScriptAction(ctx, item.event, script) {
+			return
 		}
-		slashingFound(ctx, validatorIndex)
 	}
 }
 
+// runScriptAction runs a single script for a slashed validator.
+// Returns false if the script was stopped by shutdown.
+func (s *Service) runScriptAction(ctx context.Context, event *slashings.SlashingEvent, script *actionScript) bool {
+	action := script.action
+	log := s.log.With().Uint64("slot", uint64(event.Slot)).Uint64("validator_index", uint64(event.ValidatorIndex)).Str("action", action).Logger()
+
+	if reason := s.blocked(action, event.ValidatorIndex); reason != "" {
+		log.Warn().Str("reason", reason).Msg("Action blocked by controls; not running")
+		actionBlocked(ctx, action)
+		s.recordActionResult(ctx, event, action, time.Now(), "", fmt.Errorf("not run: %s", reason))
+		return true
+	}
+
+	begun, err := s.beginAction(ctx, event, action)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to record start of action; not running")
+		return true
+	}
+	if len(begun) == 0 {
+		log.Debug().Msg("Action already succeeded; not running again")
+		return true
+	}
+
+	log.Trace().Str("script", script.script).Msg("Calling script for slashed validator")
+	started := time.Now()
+	output, err := s.runEventScript(ctx, event, script)
+	if err != nil && ctx.Err() != nil {
+		// Script was stopped by shutdown, so leave the action to be resumed on restart.
+		log.Warn().Str("output", output).Err(err).Msg("Script stopped; will run again on restart")
+		return false
+	}
+	switch {
+	case errors.Is(err, errDrillSkipped):
+		log.Debug().Msg("Script skipped for drill")
+	case err != nil:
+		log.Error().Str("output", output).Err(err).Msg("Failed to run script")
+	}
+	s.completeAction(ctx, event, begun, action, err == nil)
+	s.recordActionResult(ctx, event, action, started, output, err)
+
+	return true
+}
+
+// runEventScript runs a script for a slashing event, returning the output of the script
+// and any error.
+func (s *Service) runEventScript(ctx context.Context, event *slashings.SlashingEvent, script *actionScript) (string, error) {
+	env := append(scriptEnv(event.Slot, event.BlockRoot, event.Offences), penaltyEnv(event.Penalty)...)
+	output, err := s.runScript(ctx, script.script, event.ValidatorIndex, env)
+	if err != nil {
+		return output, errors.Wrap(err, fmt.Sprintf("failed to run %s", script.action))
+	}
+
+	return output, nil
+}
+
+// actionScript is a script run as an action for a slashed validator.
+type actionScript struct {
+	action string
+	script string
+}
+
+// actionScripts returns the scripts to run for the event, in the order in which they
+// are run: the proposer slashed script for a proposer offence, followed by the attester
+// slashed script for an attester offence.
+func (s *Service) actionScripts(event *slashings.SlashingEvent) []*actionScript {
+	cfg := s.cfg.Load()
+	scripts := make([]*actionScript, 0, 2)
+	if event.HasType(slashings.TypeProposer) && cfg.proposerSlashedScript != "" {
+		scripts = append(scripts, &actionScript{action: actionProposerSlashedScript, script: cfg.proposerSlashedScript})
+	}
+	if event.HasType(slashings.TypeAttester) && cfg.attesterSlashedScript != "" {
+		scripts = append(scripts, &actionScript{action: actionAttesterSlashedScript, script: cfg.attesterSlashedScript})
+	}
+
+	return scripts
+}
+
+// offenceTypes returns the types of the offences.
+func offenceTypes(offences []*slashings.Offence) []string {
+	types := make([]string, 0, len(offences))
+	for _, offence := range offences {
+		types = append(types, string(offence.Type()))
+	}
+
+	return types
+}
+
+// offenceKinds returns the kinds of the offences.
+func offenceKinds(offences []*slashings.Offence) []string {
+	kinds := make([]string, 0, len(offences))
+	for _, offence := range offences {
+		kinds = append(kinds, string(offence.Kind))
+	}
+
+	return kinds
+}
+
+// offenceDescriptions returns the descriptions of the offences.
+func offenceDescriptions(offences []*slashings.Offence) []string {
+	descriptions := make([]string, 0, len(offences))
+	for _, offence := range offences {
+		descriptions = append(descriptions, offence.Description())
+	}
+
+	return descriptions
+}
+
 // intersection returns a list of items common between the two sets.
-func intersection(set1 []uint64, set2 []uint64) []spec.ValidatorIndex {
+func intersection(set1 []uint64, set2 []uint64) []phase0.ValidatorIndex {
 	sort.Slice(set1, func(i, j int) bool { return set1[i] < set1[j] })
 	sort.Slice(set2, func(i, j int) bool { return set2[i] < set2[j] })
-	res := make([]spec.ValidatorIndex, 0)
+	res := make([]phase0.ValidatorIndex, 0)
 
 	set1Pos := 0
 	set2Pos := 0
@@ -100,7 +455,7 @@ func intersection(set1 []uint64, set2 []uint64) []spec.ValidatorIndex {
 		case set2[set2Pos] < set1[set1Pos]:
 			set2Pos++
 		default:
-			res = append(res, spec.ValidatorIndex(set1[set1Pos]))
+			res = append(res, phase0.ValidatorIndex(set1[set1Pos]))
 			set1Pos++
 			set2Pos++
 		}
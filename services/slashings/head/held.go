@@ -0,0 +1,115 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// holdSlashings holds slashings from an optimistic block until the block is validated.
+func (s *Service) holdSlashings(ctx context.Context, slashings *blockSlashings) {
+	s.log.Info().
+		Uint64("slot", uint64(slashings.slot)).
+		Str("block_root", fmt.Sprintf("%#x", slashings.root)).
+		Msg("Block is optimistic; holding slashings until it is validated")
+
+	for _, event := range slashings.events {
+		event.Optimistic = true
+	}
+	if s.notifyOnHold {
+		s.notifySlashings(ctx, slashings)
+	}
+
+	s.heldSlashingsMu.Lock()
+	s.heldSlashings[slashings.root] = slashings
+	heldBlocks := len(s.heldSlashings)
+	s.heldSlashingsMu.Unlock()
+	setHeldBlocks(ctx, heldBlocks)
+}
+
+// releaseHeldSlashings checks held blocks and handles their slashings if they have been validated.
+func (s *Service) releaseHeldSlashings(ctx context.Context) {
+	s.heldSlashingsMu.Lock()
+	held := make([]*blockSlashings, 0, len(s.heldSlashings))
+	for _, slashings := range s.heldSlashings {
+		held = append(held, slashings)
+	}
+	s.heldSlashingsMu.Unlock()
+
+	for _, slashings := range held {
+		log := s.log.With().Uint64("slot", uint64(slashings.slot)).Str("block_root", fmt.Sprintf("%#x", slashings.root)).Logger()
+		optimistic, err := s.blockOptimistic(ctx, slashings.root)
+		switch {
+		case err != nil && isNotFound(err):
+			log.Warn().Msg("Held block is no longer available; discarding its slashings")
+			s.removeHeldSlashings(ctx, slashings.root)
+		case err != nil:
+			log.Debug().Err(err).Msg("Failed to check held block; will retry")
+		case optimistic:
+			log.Trace().Msg("Held block is still optimistic")
+		default:
+			log.Info().Msg("Held block has been validated; releasing slashings")
+			s.removeHeldSlashings(ctx, slashings.root)
+			for _, event := range slashings.events {
+				event.Optimistic = false
+				if slashings.notified {
+					// Already reported while optimistic, so report the change.
+					s.notifyStatus(ctx, event)
+				}
+			}
+			if slashings.notified {
+				// Already recorded while optimistic, so record the change.
+				s.recordSlashings(ctx, slashings)
+			}
+			s.dispatchSlashings(ctx, slashings)
+		}
+	}
+}
+
+// blockOptimistic returns true if the block with the given root is still optimistic.
+func (s *Service) blockOptimistic(ctx context.Context, root phase0.Root) (bool, error) {
+	blockResponse, err := s.eth2Client.(eth2client.SignedBeaconBlockProvider).SignedBeaconBlock(ctx, &api.SignedBeaconBlockOpts{
+		Block: root.String(),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return executionOptimistic(blockResponse.Metadata) || s.nodeOptimistic(), nil
+}
+
+func (s *Service) removeHeldSlashings(ctx context.Context, root phase0.Root) {
+	s.heldSlashingsMu.Lock()
+	delete(s.heldSlashings, root)
+	heldBlocks := len(s.heldSlashings)
+	s.heldSlashingsMu.Unlock()
+	setHeldBlocks(ctx, heldBlocks)
+}
+
+// isNotFound returns true if the error is a 404 from the beacon node.
+func isNotFound(err error) bool {
+	var apiErr *api.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusNotFound
+	}
+
+	return false
+}
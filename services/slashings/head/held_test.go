@@ -0,0 +1,111 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/attestantio/esd/services/history"
+	standardhistory "github.com/attestantio/esd/services/history/standard"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleaseHeldSlashings(t *testing.T) {
+	tests := []struct {
+		name         string
+		notifyOnHold bool
+		optimistic   bool
+		recorded     bool
+		held         int
+		queued       int
+	}{
+		{
+			name:         "StillOptimistic",
+			notifyOnHold: true,
+			optimistic:   true,
+			recorded:     true,
+			held:         1,
+		},
+		{
+			name:         "ValidatedNotified",
+			notifyOnHold: true,
+			recorded:     true,
+			queued:       1,
+		},
+		{
+			name:   "ValidatedNotNotified",
+			queued: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			historySvc, err := standardhistory.New(ctx, standardhistory.WithPath(filepath.Join(t.TempDir(), "history.db")))
+			require.NoError(t, err)
+			handler := &recordingHandler{}
+			s := &Service{
+				log:           zerolog.Nop(),
+				eth2Client:    &mockClient{optimistic: test.optimistic},
+				chainTime:     &mockChainTime{slot: 100},
+				history:       historySvc,
+				notifyOnHold:  test.notifyOnHold,
+				heldSlashings: make(map[phase0.Root]*blockSlashings),
+				actionQueues:  []chan *actionItem{make(chan *actionItem, 1)},
+			}
+			s.cfg.Store(&config{
+				handlers: []slashings.Handler{handler},
+			})
+
+			root := phase0.Root{0x01}
+			event := &slashings.SlashingEvent{
+				Slot:           100,
+				BlockRoot:      root,
+				ValidatorIndex: 1,
+				Offences: []*slashings.Offence{
+					{Kind: slashings.OffenceDoubleVote},
+				},
+				Status: slashings.StatusPending,
+			}
+			id := history.SlashingID(event.Slot, event.BlockRoot, event.ValidatorIndex)
+			s.holdSlashings(ctx, &blockSlashings{
+				slot:   100,
+				root:   root,
+				events: []*slashings.SlashingEvent{event},
+			})
+			if test.recorded {
+				record, err := historySvc.Slashing(ctx, id)
+				require.NoError(t, err)
+				require.True(t, record.Optimistic)
+			}
+
+			s.releaseHeldSlashings(ctx)
+
+			require.Len(t, s.heldSlashings, test.held)
+			record, err := historySvc.Slashing(ctx, id)
+			require.NoError(t, err)
+			require.Equal(t, test.held > 0, record.Optimistic)
+			// The slashing is reported once, however it was released.
+			require.Equal(t, []phase0.ValidatorIndex{1}, handler.handled())
+			require.Len(t, s.actionQueues[0], test.queued)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/attestantio/esd/services/history"
+	"github.com/attestantio/esd/services/slashings"
+	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// recordSlashings records the slashings in the history store.
+// Slashings that have been recorded previously keep their original details, other than
+// no longer being optimistic once their block has been validated.
+func (s *Service) recordSlashings(ctx context.Context, block *blockSlashings) {
+	if s.history == nil {
+		return
+	}
+
+	for _, event := range block.events {
+		record := &history.Slashing{
+			SlashingEvent: *event,
+			FirstSeen:     time.Now(),
+			Actions:       make([]*history.ActionResult, 0),
+		}
+		existing, err := s.history.Slashing(ctx, record.ID())
+		switch {
+		case err == nil && existing.Optimistic && !event.Optimistic:
+			existing.Optimistic = false
+			if err := s.history.SetSlashing(ctx, existing); err != nil {
+				s.log.Error().Str("id", record.ID()).Err(err).Msg("Failed to record validation of slashing")
+			}
+			continue
+		case err == nil:
+			s.log.Trace().Str("id", record.ID()).Msg("Slashing already recorded")
+			continue
+		case !errors.Is(err, history.ErrNotFound):
+			s.log.Error().Str("id", record.ID()).Err(err).Msg("Failed to check slashing history")
+			continue
+		}
+
+		if err := s.history.SetSlashing(ctx, record); err != nil {
+			s.log.Error().Str("id", record.ID()).Err(err).Msg("Failed to record slashing")
+		}
+	}
+}
+
+// recordActionResult records the outcome of an action in the history store.
+func (s *Service) recordActionResult(ctx context.Context,
+	event *slashings.SlashingEvent,
+	action string,
+	started time.Time,
+	output string,
+	err error,
+) {
+	s.recordDrillResult(action, s.actionTarget(action), output, err)
+	if s.history == nil {
+		return
+	}
+
+	result := &history.ActionResult{
+		Action:   action,
+		Started:  started,
+		Duration: time.Since(started),
+		Success:  err == nil,
+		Output:   output,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	id := history.SlashingID(event.Slot, event.BlockRoot, event.ValidatorIndex)
+	if err := s.history.AddActionResult(ctx, id, result); err != nil {
+		s.log.Error().Str("id", id).Err(err).Msg("Failed to record action result")
+	}
+}
+
+// updateFinality updates the finality status of pending slashings in the history store.
+func (s *Service) updateFinality(ctx context.Context) {
+	if s.history == nil {
+		return
+	}
+
+	finalityProvider, isProvider := s.eth2Client.(eth2client.FinalityProvider)
+	if !isProvider {
+		return
+	}
+	finalityResponse, err := finalityProvider.Finality(ctx, &api.FinalityOpts{
+		State: "head",
+	})
+	if err != nil {
+		s.log.Debug().Err(err).Msg("Failed to obtain finality")
+		return
+	}
+	finalizedEpoch := finalityResponse.Data.Finalized.Epoch
+	if finalizedEpoch <= s.lastFinalizedEpoch {
+		return
+	}
+	finalizedSlot := s.chainTime.FirstSlotOfEpoch(finalizedEpoch)
+
+	pending, err := s.history.Slashings(ctx, &history.Filter{
+		Status: slashings.StatusPending,
+		ToSlot: &finalizedSlot,
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to obtain pending slashings")
+		return
+	}
+
+	canonicalRoots := make(map[phase0.Slot]phase0.Root)
+	for _, record := range pending {
+		canonicalRoot, exists := canonicalRoots[record.Slot]
+		if !exists {
+			canonicalRoot, err = s.canonicalRoot(ctx, record.Slot)
+			if err != nil {
+				s.log.Debug().Uint64("slot", uint64(record.Slot)).Err(err).Msg("Failed to obtain canonical block root; will retry")
+				return
+			}
+			canonicalRoots[record.Slot] = canonicalRoot
+		}
+
+		status := slashings.StatusOrphaned
+		if canonicalRoot == record.BlockRoot {
+			status = slashings.StatusFinalized
+		}
+		if err := s.history.SetStatus(ctx, record.ID(), status); err != nil {
+			s.log.Error().Str("id", record.ID()).Err(err).Msg("Failed to update slashing")
+			return
+		}
+		s.log.Trace().Str("id", record.ID()).Str("status", string(status)).Msg("Updated finality status of slashing")
+		record.Status = status
+		s.notifyStatus(ctx, &record.SlashingEvent)
+	}
+
+	s.lastFinalizedEpoch = finalizedEpoch
+}
+
+// canonicalRoot returns the root of the canonical block at the given slot, or an empty
+// root if the slot is empty.
+func (s *Service) canonicalRoot(ctx context.Context, slot phase0.Slot) (phase0.Root, error) {
+	rootResponse, err := s.eth2Client.(eth2client.BeaconBlockRootProvider).BeaconBlockRoot(ctx, &api.BeaconBlockRootOpts{
+		Block: fmt.Sprintf("%d", slot),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return phase0.Root{}, nil
+		}
+
+		return phase0.Root{}, err
+	}
+
+	return *rootResponse.Data, nil
+}
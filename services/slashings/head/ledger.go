@@ -0,0 +1,260 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/attestantio/esd/services/ledger"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+)
+
+const (
+	actionNotify                = "notify"
+	actionAttesterSlashedScript = "attester-slashed-script"
+	actionProposerSlashedScript = "proposer-slashed-script"
+	actionBatchScript           = "batch-script"
+	actionMassSlashingScript    = "mass-slashing-script"
+)
+
+// Actions are the names of the actions that can be run by the service, as used by the
+// operator controls.
+var Actions = []string{
+	actionAttesterSlashedScript,
+	actionProposerSlashedScript,
+	actionBatchScript,
+	actionMassSlashingScript,
+}
+
+// firstNotification returns true if the slashing includes an offence that has not been
+// reported before.
+func (s *Service) firstNotification(ctx context.Context, event *slashings.SlashingEvent) bool {
+	if s.ledger == nil {
+		return true
+	}
+
+	begun, err := s.beginAction(ctx, event, actionNotify)
+	if err != nil {
+		// Better to report twice than not at all.
+		s.log.Error().Err(err).Msg("Failed to record notification")
+		return true
+	}
+	s.completeAction(ctx, event, begun, actionNotify, true)
+
+	return len(begun) > 0
+}
+
+// beginAction records the start of an action in the ledger for each of the event's
+// offences, returning the offences for which the action has not already succeeded.
+func (s *Service) beginAction(ctx context.Context, event *slashings.SlashingEvent, action string) ([]*slashings.Offence, error) {
+	if s.ledger == nil {
+		return event.Offences, nil
+	}
+
+	begun := make([]*slashings.Offence, 0, len(event.Offences))
+	for _, offence := range event.Offences {
+		begin, err := s.ledger.Begin(ctx, &ledger.Entry{
+			Key:       *actionKey(event, offence, action),
+			Slot:      event.Slot,
+			BlockRoot: event.BlockRoot,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to record start of action")
+		}
+		if begin {
+			begun = append(begun, offence)
+		}
+	}
+
+	return begun, nil
+}
+
+// completeAction records the completion of an action in the ledger for the given offences.
+func (s *Service) completeAction(ctx context.Context,
+	event *slashings.SlashingEvent,
+	offences []*slashings.Offence,
+	action string,
+	success bool,
+) {
+	if s.ledger == nil {
+		return
+	}
+
+	for _, offence := range offences {
+		if err := s.ledger.Complete(ctx, actionKey(event, offence, action), success); err != nil {
+			s.log.Error().Uint64("validator_index", uint64(event.ValidatorIndex)).Str("action", action).Err(err).Msg("Failed to record completion of action")
+		}
+	}
+}
+
+// resumeActions queues the actions that started but did not complete, for example
+// because esd stopped while they were running.
+func (s *Service) resumeActions(ctx context.Context) error {
+	if s.ledger == nil {
+		return nil
+	}
+
+	entries, err := s.ledger.Unfinished(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain unfinished actions")
+	}
+
+	// Regroup the offences into the actions that were running.
+	type actionID struct {
+		slot           phase0.Slot
+		root           phase0.Root
+		validatorIndex phase0.ValidatorIndex
+		action         string
+	}
+	actionEntries := make(map[actionID][]*ledger.Entry)
+	order := make([]actionID, 0)
+	for _, entry := range entries {
+		id := actionID{
+			slot:           entry.Slot,
+			root:           entry.BlockRoot,
+			validatorIndex: entry.ValidatorIndex,
+			action:         entry.Action,
+		}
+		if _, exists := actionEntries[id]; !exists {
+			order = append(order, id)
+		}
+		actionEntries[id] = append(actionEntries[id], entry)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i].slot < order[j].slot })
+
+	blocks := make(map[phase0.Root]*blockSlashings)
+	batches := make(map[phase0.Root]*batchItem)
+	for _, id := range order {
+		if id.action == actionMassSlashingScript {
+			s.resumeMassSlashing(ctx, actionEntries[id][0])
+			continue
+		}
+		event := s.resumedEvent(ctx, blocks, actionEntries[id])
+		switch id.action {
+		case actionNotify:
+			// Notifications are not repeated.
+			s.completeAction(ctx, event, event.Offences, actionNotify, true)
+		case actionBatchScript:
+			// Batch actions are regrouped by block.
+			batch, exists := batches[id.root]
+			if !exists {
+				batch = &batchItem{
+					slot:  id.slot,
+					root:  id.root,
+					items: make([]*actionItem, 0, 1),
+				}
+				batches[id.root] = batch
+			}
+			batch.items = append(batch.items, &actionItem{event: event})
+		default:
+			s.log.Info().Uint64("slot", uint64(id.slot)).Uint64("validator_index", uint64(id.validatorIndex)).Str("action", id.action).Msg("Resuming unfinished action")
+			s.queueAction(ctx, &actionItem{event: event})
+		}
+	}
+	for _, batch := range batches {
+		s.log.Info().Uint64("slot", uint64(batch.slot)).Int("validators", len(batch.items)).Str("action", actionBatchScript).Msg("Resuming unfinished action")
+		s.queueBatch(ctx, batch)
+	}
+
+	return nil
+}
+
+// resumeMassSlashing queues the script of an unfinished mass slashing.  The mass slashing
+// has already been reported, so only its script is run.
+func (s *Service) resumeMassSlashing(ctx context.Context, entry *ledger.Entry) {
+	event := &slashings.MassSlashingEvent{}
+	if err := json.Unmarshal(entry.Data, event); err != nil {
+		s.log.Error().Uint64("slot", uint64(entry.Slot)).Str("action", actionMassSlashingScript).Err(err).Msg("Invalid unfinished mass slashing; not resuming")
+		return
+	}
+
+	s.log.Info().Uint64("slot", uint64(entry.Slot)).Int("count", event.Count).Str("action", actionMassSlashingScript).Msg("Resuming unfinished action")
+	s.queueMassSlashing(ctx, &massItem{
+		slot:    entry.Slot,
+		root:    entry.BlockRoot,
+		event:   event,
+		resumed: true,
+	})
+}
+
+// resumedEvent rebuilds the slashing event for the ledger entries of an unfinished action.
+// The evidence is obtained from the block if it is still available, otherwise the event
+// only contains the information held in the ledger.
+func (s *Service) resumedEvent(ctx context.Context,
+	blocks map[phase0.Root]*blockSlashings,
+	entries []*ledger.Entry,
+) *slashings.SlashingEvent {
+	first := entries[0]
+	block, exists := blocks[first.BlockRoot]
+	if !exists {
+		var err error
+		block, _, err = s.fetchSlashings(ctx, first.BlockRoot.String(), slashings.SourceResume)
+		if err != nil {
+			s.log.Debug().Uint64("slot", uint64(first.Slot)).Err(err).Msg("Failed to fetch block of unfinished action; resuming without evidence")
+		}
+		blocks[first.BlockRoot] = block
+	}
+
+	event := &slashings.SlashingEvent{
+		ValidatorIndex: first.ValidatorIndex,
+		Offences:       make([]*slashings.Offence, 0, len(entries)),
+		Slot:           first.Slot,
+		BlockRoot:      first.BlockRoot,
+		Source:         slashings.SourceResume,
+		Status:         slashings.StatusPending,
+	}
+	var blockEvent *slashings.SlashingEvent
+	if block != nil {
+		for _, candidate := range block.events {
+			if candidate.ValidatorIndex == first.ValidatorIndex {
+				blockEvent = candidate
+				event.PubKey = candidate.PubKey
+				event.ProposerIndex = candidate.ProposerIndex
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		offence := &slashings.Offence{
+			EvidenceHash: entry.EvidenceHash,
+		}
+		if entry.Proposer {
+			offence.Kind = slashings.OffenceDoubleProposal
+		}
+		if blockEvent != nil {
+			for _, candidate := range blockEvent.Offences {
+				if candidate.EvidenceHash == entry.EvidenceHash && (candidate.Type() == slashings.TypeProposer) == entry.Proposer {
+					offence = candidate
+				}
+			}
+		}
+		event.Offences = append(event.Offences, offence)
+	}
+
+	return event
+}
+
+// actionKey returns the ledger key for an action on an offence.
+func actionKey(event *slashings.SlashingEvent, offence *slashings.Offence, action string) *ledger.Key {
+	return &ledger.Key{
+		ValidatorIndex: event.ValidatorIndex,
+		Proposer:       offence.Type() == slashings.TypeProposer,
+		EvidenceHash:   offence.EvidenceHash,
+		Action:         action,
+	}
+}
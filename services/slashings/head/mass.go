@@ -0,0 +1,308 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/attestantio/esd/services/ledger"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+)
+
+// massSlashingEnabled returns true if mass slashings are detected.
+func (s *Service) massSlashingEnabled() bool {
+	return s.massSlashingCount > 0 || s.massSlashingBalance > 0
+}
+
+// massItem is a mass slashing queued for its script.
+type massItem struct {
+	// slot and root are those of the block whose slashings crossed the thresholds.
+	slot  phase0.Slot
+	root  phase0.Root
+	event *slashings.MassSlashingEvent
+	// resumed is true if the mass slashing has already been reported.
+	resumed bool
+}
+
+// recordMassSlashings adds the validators penalised by the slashings in a block to the
+// window of recent slashings.  It returns a mass slashing if the window has crossed the
+// thresholds, otherwise nil.
+func (s *Service) recordMassSlashings(ctx context.Context, block *blockSlashings) *massItem {
+	if !s.massSlashingEnabled() {
+		return nil
+	}
+
+	s.massSlashingMu.Lock()
+	defer s.massSlashingMu.Unlock()
+	fromEpoch := s.massWindowStart()
+	recorded := false
+	// Only validators that are acted on count, so held, unverified and already slashed
+	// validators are excluded.
+	for _, item := range block.actionItems() {
+		epoch := s.chainTime.SlotToEpoch(item.event.Slot)
+		if epoch < fromEpoch {
+			// Too old to be in the window, for example when processing a historical block.
+			continue
+		}
+		if _, exists := s.massSlashings[epoch]; !exists {
+			s.massSlashings[epoch] = make(map[phase0.ValidatorIndex]phase0.Gwei)
+		}
+		s.massSlashings[epoch][item.event.ValidatorIndex] = item.event.EffectiveBalance
+		recorded = true
+	}
+	if !recorded {
+		return nil
+	}
+
+	event := s.checkMassSlashing(ctx, fromEpoch)
+	if event == nil {
+		return nil
+	}
+
+	return &massItem{
+		slot:  block.slot,
+		root:  block.root,
+		event: event,
+	}
+}
+
+// queueMassSlashing places a mass slashing on the mass slashing queue.
+func (s *Service) queueMassSlashing(ctx context.Context, item *massItem) {
+	s.pendingActions.Add(1)
+	select {
+	case s.massQueue <- item:
+	case <-ctx.Done():
+		s.pendingActions.Add(-1)
+		return
+	}
+	setQueueDepth(ctx, "mass_slashings", len(s.massQueue))
+}
+
+// runMassSlashings runs queued mass slashings.
+func (s *Service) runMassSlashings(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-s.massQueue:
+			setQueueDepth(ctx, "mass_slashings", len(s.massQueue))
+			started := time.Now()
+			s.handleMassSlashing(ctx, item)
+			s.pendingActions.Add(-1)
+			actionCompleted(ctx, time.Since(started))
+		}
+	}
+}
+
+// pruneMassSlashings removes slashings that have left the window.
+func (s *Service) pruneMassSlashings(ctx context.Context) {
+	if !s.massSlashingEnabled() {
+		return
+	}
+
+	s.massSlashingMu.Lock()
+	defer s.massSlashingMu.Unlock()
+	s.checkMassSlashing(ctx, s.massWindowStart())
+}
+
+// massWindowStart returns the first epoch of the window.
+func (s *Service) massWindowStart() phase0.Epoch {
+	currentEpoch := s.chainTime.CurrentEpoch()
+	if uint64(currentEpoch) < s.massSlashingWindow {
+		return 0
+	}
+
+	return currentEpoch - phase0.Epoch(s.massSlashingWindow) + 1
+}
+
+// checkMassSlashing prunes the window and compares it with the thresholds.  It returns
+// an event if the thresholds have just been crossed.
+// massSlashingMu must be held.
+func (s *Service) checkMassSlashing(ctx context.Context, fromEpoch phase0.Epoch) *slashings.MassSlashingEvent {
+	event := &slashings.MassSlashingEvent{
+		FromEpoch:        fromEpoch,
+		ToEpoch:          s.chainTime.CurrentEpoch(),
+		ValidatorIndices: make([]phase0.ValidatorIndex, 0),
+	}
+	for epoch, validators := range s.massSlashings {
+		if epoch < fromEpoch {
+			delete(s.massSlashings, epoch)
+			continue
+		}
+		if epoch > event.ToEpoch {
+			event.ToEpoch = epoch
+		}
+		for index, balance := range validators {
+			event.ValidatorIndices = append(event.ValidatorIndices, index)
+			event.Balance += balance
+		}
+	}
+	event.Count = len(event.ValidatorIndices)
+	sort.Slice(event.ValidatorIndices, func(i int, j int) bool {
+		return event.ValidatorIndices[i] < event.ValidatorIndices[j]
+	})
+	setMassSlashingWindow(ctx, event.Count, event.Balance)
+
+	crossed := (s.massSlashingCount > 0 && uint64(event.Count) >= s.massSlashingCount) ||
+		(s.massSlashingBalance > 0 && event.Balance >= s.massSlashingBalance)
+	if !crossed {
+		if s.massSlashingActive {
+			s.log.Info().Int("count", event.Count).Uint64("balance", uint64(event.Balance)).Msg("Slashings in window below mass slashing thresholds")
+		}
+		s.massSlashingActive = false
+		return nil
+	}
+	if s.massSlashingActive {
+		// Already raised for this period of mass slashing.
+		return nil
+	}
+	s.massSlashingActive = true
+
+	return event
+}
+
+// handleMassSlashing reports a mass slashing and runs its script.
+func (s *Service) handleMassSlashing(ctx context.Context, item *massItem) {
+	if !item.resumed {
+		s.reportMassSlashing(ctx, item.event)
+	}
+	s.runMassSlashingScript(ctx, item)
+}
+
+// reportMassSlashing carries out the non-destructive reporting of a mass slashing.
+func (s *Service) reportMassSlashing(ctx context.Context, event *slashings.MassSlashingEvent) {
+	if s.penalties != nil {
+		penalty, err := s.penalties.Projection(ctx, "head")
+		if err != nil {
+			s.log.Warn().Err(err).Msg("Failed to project penalty for mass slashing; continuing without it")
+		} else {
+			event.Penalty = penalty
+		}
+	}
+
+	e := s.log.Warn().
+		Uint64("from_epoch", uint64(event.FromEpoch)).
+		Uint64("to_epoch", uint64(event.ToEpoch)).
+		Int("count", event.Count).
+		Uint64("balance", uint64(event.Balance))
+	if event.Penalty != nil {
+		e = e.Uint64("correlation_penalty", uint64(event.Penalty.CorrelationPenalty))
+	}
+	if event.Drill {
+		e = e.Bool("drill", true)
+	}
+	e.Msg("Mass slashing detected")
+	massSlashingFound(ctx)
+
+	for _, handler := range s.cfg.Load().handlers {
+		massHandler, isHandler := handler.(slashings.MassSlashingHandler)
+		if !isHandler {
+			continue
+		}
+		err := massHandler.OnMassSlashing(ctx, event)
+		if err != nil {
+			s.log.Error().Err(err).Msg("Handler failed to handle mass slashing")
+		}
+		s.recordDrillResult(actionNotifyMassSlashing, handlerTarget(handler), "", err)
+	}
+}
+
+// runMassSlashingScript runs the script for a mass slashing, recording it in the ledger
+// so that it runs to success at most once.
+func (s *Service) runMassSlashingScript(ctx context.Context, item *massItem) {
+	script := s.cfg.Load().massSlashingScript
+	if script == "" {
+		return
+	}
+	event := item.event
+	log := s.log.With().Uint64("slot", uint64(item.slot)).Str("action", actionMassSlashingScript).Logger()
+
+	if reason := s.blocked(actionMassSlashingScript); reason != "" {
+		log.Warn().Str("reason", reason).Msg("Action blocked by controls; not running")
+		actionBlocked(ctx, actionMassSlashingScript)
+		s.recordDrillResult(actionMassSlashingScript, script, "", fmt.Errorf("not run: %s", reason))
+		return
+	}
+	input, err := json.Marshal(event)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to marshal mass slashing")
+		return
+	}
+
+	if s.ledger != nil {
+		begin, err := s.ledger.Begin(ctx, massLedgerEntry(item, input))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to record start of action; not running")
+			return
+		}
+		if !begin {
+			log.Debug().Msg("Action already succeeded; not running again")
+			return
+		}
+	}
+
+	args := make([]string, 0, len(event.ValidatorIndices))
+	for _, index := range event.ValidatorIndices {
+		args = append(args, fmt.Sprintf("%d", index))
+	}
+	env := append([]string{
+		fmt.Sprintf("ESD_MASS_FROM_EPOCH=%d", event.FromEpoch),
+		fmt.Sprintf("ESD_MASS_TO_EPOCH=%d", event.ToEpoch),
+		fmt.Sprintf("ESD_MASS_COUNT=%d", event.Count),
+		fmt.Sprintf("ESD_MASS_BALANCE=%d", event.Balance),
+	}, penaltyEnv(event.Penalty)...)
+
+	log.Info().Str("script", script).Msg("Calling script for mass slashing")
+	output, err := s.runCommand(ctx, script, args, env, input)
+	if err != nil && ctx.Err() != nil {
+		// Script was stopped by shutdown, so leave the action to be resumed on restart.
+		log.Warn().Str("output", output).Err(err).Msg("Mass slashing script stopped; will run again on restart")
+		return
+	}
+	switch {
+	case errors.Is(err, errDrillSkipped):
+		log.Debug().Msg("Mass slashing script skipped for drill")
+	case err != nil:
+		log.Error().Str("output", output).Err(err).Msg("Mass slashing script failed")
+	default:
+		log.Debug().Str("output", output).Msg("Mass slashing script succeeded")
+	}
+	s.recordDrillResult(actionMassSlashingScript, script, output, err)
+	if s.ledger != nil {
+		if err := s.ledger.Complete(ctx, &massLedgerEntry(item, nil).Key, err == nil); err != nil {
+			log.Error().Err(err).Msg("Failed to record completion of action")
+		}
+	}
+}
+
+// massLedgerEntry returns the ledger entry for the script of a mass slashing.  A mass
+// slashing is identified by the block whose slashings crossed the thresholds, and the
+// event is held in the entry so that the script can be resumed.
+func massLedgerEntry(item *massItem, data []byte) *ledger.Entry {
+	return &ledger.Entry{
+		Key: ledger.Key{
+			EvidenceHash: item.root,
+			Action:       actionMassSlashingScript,
+		},
+		Slot:      item.slot,
+		BlockRoot: item.root,
+		Data:      data,
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright © 2021 Attestant Limited.
+// Copyright © 2021, 2024 Attestant Limited.
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
 // You may obtain a copy of the License at
@@ -16,9 +16,11 @@ package head
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/attestantio/esd/services/metrics"
-	spec "github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -27,7 +29,22 @@ var metricsNamespace = "esd"
 
 var (
 	blocksProcessed prometheus.Counter
-	slashings       *prometheus.CounterVec
+	slashingsTotal  *prometheus.CounterVec
+	nodeStatus      *prometheus.GaugeVec
+	heldBlocks      prometheus.Gauge
+	headLag         prometheus.Gauge
+	resubscriptions prometheus.Counter
+	queueDepth      *prometheus.GaugeVec
+	blockLatency    prometheus.Histogram
+	actionLatency   prometheus.Histogram
+	fetchRetries    prometheus.Counter
+	failedBlocks    prometheus.Gauge
+	unverified      prometheus.Counter
+	alreadySlashed  prometheus.Counter
+	windowSlashings prometheus.Gauge
+	windowBalance   prometheus.Gauge
+	massSlashings   prometheus.Counter
+	blockedActions  *prometheus.CounterVec
 )
 
 func registerMetrics(ctx context.Context, monitor metrics.Service) error {
@@ -56,15 +73,152 @@ func registerPrometheusMetrics(_ context.Context) error {
 		return errors.Wrap(err, "failed to register blocks_processed_total")
 	}
 
-	slashings = prometheus.NewCounterVec(prometheus.CounterOpts{
+	slashingsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: metricsNamespace,
 		Name:      "slashings_total",
 		Help:      "Register of slashings found",
-	}, []string{"index"})
-	if err := prometheus.Register(slashings); err != nil {
+	}, []string{"index", "kind"})
+	if err := prometheus.Register(slashingsTotal); err != nil {
 		return errors.Wrap(err, "failed to register slashings")
 	}
 
+	nodeStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "beacon_node_status",
+		Help:      "1 if the beacon node is in the given state, otherwise 0",
+	}, []string{"state"})
+	if err := prometheus.Register(nodeStatus); err != nil {
+		return errors.Wrap(err, "failed to register beacon_node_status")
+	}
+
+	heldBlocks = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "held_blocks",
+		Help:      "Number of optimistic blocks with slashings awaiting validation",
+	})
+	if err := prometheus.Register(heldBlocks); err != nil {
+		return errors.Wrap(err, "failed to register held_blocks")
+	}
+
+	headLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "head_lag_slots",
+		Help:      "Number of slots between the current slot and the last head event",
+	})
+	if err := prometheus.Register(headLag); err != nil {
+		return errors.Wrap(err, "failed to register head_lag_slots")
+	}
+
+	resubscriptions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "event_stream_resubscriptions_total",
+		Help:      "Total number of resubscriptions to a stale event stream",
+	})
+	if err := prometheus.Register(resubscriptions); err != nil {
+		return errors.Wrap(err, "failed to register event_stream_resubscriptions_total")
+	}
+
+	queueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "queue_depth",
+		Help:      "Number of items waiting in each stage of the processing pipeline",
+	}, []string{"queue"})
+	if err := prometheus.Register(queueDepth); err != nil {
+		return errors.Wrap(err, "failed to register queue_depth")
+	}
+
+	blockLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "block_processing_duration_seconds",
+		Help:      "Time from a block being queued to its slashings being dispatched",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 4, 8, 12, 24},
+	})
+	if err := prometheus.Register(blockLatency); err != nil {
+		return errors.Wrap(err, "failed to register block_processing_duration_seconds")
+	}
+
+	actionLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "action_duration_seconds",
+		Help:      "Time taken to run an action",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60, 120},
+	})
+	if err := prometheus.Register(actionLatency); err != nil {
+		return errors.Wrap(err, "failed to register action_duration_seconds")
+	}
+
+	fetchRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "block_fetch_retries_total",
+		Help:      "Total number of retries to fetch blocks",
+	})
+	if err := prometheus.Register(fetchRetries); err != nil {
+		return errors.Wrap(err, "failed to register block_fetch_retries_total")
+	}
+
+	failedBlocks = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "failed_blocks",
+		Help:      "Number of blocks that could not be fetched after all retries",
+	})
+	if err := prometheus.Register(failedBlocks); err != nil {
+		return errors.Wrap(err, "failed to register failed_blocks")
+	}
+
+	unverified = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "unverified_slashings_total",
+		Help:      "Total number of slashed validators whose evidence could not be verified",
+	})
+	if err := prometheus.Register(unverified); err != nil {
+		return errors.Wrap(err, "failed to register unverified_slashings_total")
+	}
+
+	alreadySlashed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "already_slashed_total",
+		Help:      "Total number of validators included in slashings that had already been slashed",
+	})
+	if err := prometheus.Register(alreadySlashed); err != nil {
+		return errors.Wrap(err, "failed to register already_slashed_total")
+	}
+
+	windowSlashings = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "mass_slashing_window_slashings",
+		Help:      "Number of validators slashed in the mass slashing window",
+	})
+	if err := prometheus.Register(windowSlashings); err != nil {
+		return errors.Wrap(err, "failed to register mass_slashing_window_slashings")
+	}
+
+	windowBalance = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "mass_slashing_window_balance_gwei",
+		Help:      "Total effective balance of validators slashed in the mass slashing window",
+	})
+	if err := prometheus.Register(windowBalance); err != nil {
+		return errors.Wrap(err, "failed to register mass_slashing_window_balance_gwei")
+	}
+
+	massSlashings = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "mass_slashings_total",
+		Help:      "Total number of mass slashings detected",
+	})
+	if err := prometheus.Register(massSlashings); err != nil {
+		return errors.Wrap(err, "failed to register mass_slashings_total")
+	}
+
+	blockedActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "blocked_actions_total",
+		Help:      "Total number of actions not run because of the operator controls",
+	}, []string{"action"})
+	if err := prometheus.Register(blockedActions); err != nil {
+		return errors.Wrap(err, "failed to register blocked_actions_total")
+	}
+
 	return nil
 }
 
@@ -74,8 +228,112 @@ func blockProcessed(_ context.Context) {
 	}
 }
 
-func slashingFound(_ context.Context, index spec.ValidatorIndex) {
-	if slashings != nil {
-		slashings.WithLabelValues(fmt.Sprintf("%d", index)).Inc()
+func slashingFound(_ context.Context, event *slashings.SlashingEvent) {
+	if slashingsTotal != nil {
+		kinds := make(map[slashings.OffenceKind]bool)
+		for _, offence := range event.Offences {
+			if kinds[offence.Kind] {
+				continue
+			}
+			kinds[offence.Kind] = true
+			slashingsTotal.WithLabelValues(fmt.Sprintf("%d", event.ValidatorIndex), string(offence.Kind)).Inc()
+		}
+	}
+}
+
+func setSyncStatus(_ context.Context, status *syncStatus) {
+	if nodeStatus != nil {
+		nodeStatus.WithLabelValues("syncing").Set(boolToFloat(status.isSyncing))
+		nodeStatus.WithLabelValues("optimistic").Set(boolToFloat(status.isOptimistic))
+		if status.elOffline != nil {
+			nodeStatus.WithLabelValues("el_offline").Set(boolToFloat(*status.elOffline))
+		}
+	}
+}
+
+func setHeldBlocks(_ context.Context, blocks int) {
+	if heldBlocks != nil {
+		heldBlocks.Set(float64(blocks))
+	}
+}
+
+func setHeadLag(_ context.Context, lag phase0.Slot) {
+	if headLag != nil {
+		headLag.Set(float64(lag))
+	}
+}
+
+func streamResubscribed(_ context.Context) {
+	if resubscriptions != nil {
+		resubscriptions.Inc()
+	}
+}
+
+func setQueueDepth(_ context.Context, queue string, depth int) {
+	if queueDepth != nil {
+		queueDepth.WithLabelValues(queue).Set(float64(depth))
+	}
+}
+
+func blockProcessingCompleted(_ context.Context, duration time.Duration) {
+	if blockLatency != nil {
+		blockLatency.Observe(duration.Seconds())
+	}
+}
+
+func actionCompleted(_ context.Context, duration time.Duration) {
+	if actionLatency != nil {
+		actionLatency.Observe(duration.Seconds())
 	}
 }
+
+func blockFetchRetried(_ context.Context) {
+	if fetchRetries != nil {
+		fetchRetries.Inc()
+	}
+}
+
+func setFailedBlocks(_ context.Context, blocks int) {
+	if failedBlocks != nil {
+		failedBlocks.Set(float64(blocks))
+	}
+}
+
+func slashingUnverified(_ context.Context) {
+	if unverified != nil {
+		unverified.Inc()
+	}
+}
+
+func alreadySlashedFound(_ context.Context) {
+	if alreadySlashed != nil {
+		alreadySlashed.Inc()
+	}
+}
+
+func setMassSlashingWindow(_ context.Context, count int, balance phase0.Gwei) {
+	if windowSlashings != nil {
+		windowSlashings.Set(float64(count))
+		windowBalance.Set(float64(balance))
+	}
+}
+
+func massSlashingFound(_ context.Context) {
+	if massSlashings != nil {
+		massSlashings.Inc()
+	}
+}
+
+func actionBlocked(_ context.Context, action string) {
+	if blockedActions != nil {
+		blockedActions.WithLabelValues(action).Inc()
+	}
+}
+
+func boolToFloat(val bool) float64 {
+	if val {
+		return 1
+	}
+
+	return 0
+}
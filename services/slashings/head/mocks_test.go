@@ -0,0 +1,145 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/attestantio/esd/services/slashings"
+	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// mockClient is a beacon node client that reports a fixed head, or the given error,
+// sends the given head events when subscribed, and serves empty blocks that are
+// optimistic as given.
+type mockClient struct {
+	address    string
+	headSlot   phase0.Slot
+	headEvents []*apiv1.HeadEvent
+	optimistic bool
+	syncingErr error
+}
+
+func (c *mockClient) Name() string {
+	return "mock"
+}
+
+func (c *mockClient) Address() string {
+	return c.address
+}
+
+func (c *mockClient) NodeSyncing(_ context.Context, _ *api.NodeSyncingOpts) (*api.Response[*apiv1.SyncState], error) {
+	if c.syncingErr != nil {
+		return nil, c.syncingErr
+	}
+
+	return &api.Response[*apiv1.SyncState]{
+		Data: &apiv1.SyncState{
+			HeadSlot: c.headSlot,
+		},
+	}, nil
+}
+
+func (c *mockClient) Events(_ context.Context, _ []string, handler eth2client.EventHandlerFunc) error {
+	for _, event := range c.headEvents {
+		handler(&apiv1.Event{
+			Topic: "head",
+			Data:  event,
+		})
+	}
+
+	return nil
+}
+
+func (c *mockClient) SignedBeaconBlock(_ context.Context, _ *api.SignedBeaconBlockOpts) (*api.Response[*spec.VersionedSignedBeaconBlock], error) {
+	return &api.Response[*spec.VersionedSignedBeaconBlock]{
+		Data: &spec.VersionedSignedBeaconBlock{},
+		Metadata: map[string]any{
+			"execution_optimistic": c.optimistic,
+		},
+	}, nil
+}
+
+// mockChainTime is a chain time service fixed at the given slot.
+type mockChainTime struct {
+	slot phase0.Slot
+}
+
+func (c *mockChainTime) GenesisTime() time.Time {
+	return time.Now().Add(-time.Duration(c.slot) * c.SlotDuration())
+}
+
+func (c *mockChainTime) SlotDuration() time.Duration {
+	return 12 * time.Second
+}
+
+func (c *mockChainTime) SlotsPerEpoch() uint64 {
+	return 32
+}
+
+func (c *mockChainTime) StartOfSlot(slot phase0.Slot) time.Time {
+	return c.GenesisTime().Add(time.Duration(slot) * c.SlotDuration())
+}
+
+func (c *mockChainTime) StartOfEpoch(epoch phase0.Epoch) time.Time {
+	return c.StartOfSlot(c.FirstSlotOfEpoch(epoch))
+}
+
+func (c *mockChainTime) CurrentSlot() phase0.Slot {
+	return c.slot
+}
+
+func (c *mockChainTime) CurrentEpoch() phase0.Epoch {
+	return c.SlotToEpoch(c.slot)
+}
+
+func (c *mockChainTime) SlotToEpoch(slot phase0.Slot) phase0.Epoch {
+	return phase0.Epoch(uint64(slot) / c.SlotsPerEpoch())
+}
+
+func (c *mockChainTime) FirstSlotOfEpoch(epoch phase0.Epoch) phase0.Slot {
+	return phase0.Slot(uint64(epoch) * c.SlotsPerEpoch())
+}
+
+func boolPtr(val bool) *bool {
+	return &val
+}
+
+// recordingHandler records the validators of the slashings it handles, in order.
+type recordingHandler struct {
+	validators []phase0.ValidatorIndex
+	mu         sync.Mutex
+}
+
+func (h *recordingHandler) OnSlashing(_ context.Context, event *slashings.SlashingEvent) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.validators = append(h.validators, event.ValidatorIndex)
+
+	return nil
+}
+
+func (h *recordingHandler) handled() []phase0.ValidatorIndex {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]phase0.ValidatorIndex{}, h.validators...)
+}
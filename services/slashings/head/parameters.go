@@ -1,4 +1,4 @@
-// Copyright © 2021 Attestant Limited.
+// Copyright © 2021, 2024 Attestant Limited.
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
 // You may obtain a copy of the License at
@@ -14,20 +14,57 @@
 package head
 
 import (
+	"context"
 	"errors"
+	"time"
 
+	"github.com/attestantio/esd/services/chaintime"
+	"github.com/attestantio/esd/services/controls"
+	"github.com/attestantio/esd/services/deadletter"
+	"github.com/attestantio/esd/services/history"
+	"github.com/attestantio/esd/services/ledger"
 	"github.com/attestantio/esd/services/metrics"
+	"github.com/attestantio/esd/services/penalties"
+	"github.com/attestantio/esd/services/slashings"
 	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/rs/zerolog"
 )
 
 type parameters struct {
 	logLevel              zerolog.Level
 	eth2Client            eth2client.Service
+	chainTime             chaintime.Service
+	deadLetter            deadletter.Service
+	history               history.Service
+	ledger                ledger.Service
+	penalties             penalties.Service
+	controls              controls.Service
 	monitor               metrics.Service
 	attesterSlashedScript string
 	proposerSlashedScript string
+	batchScript           string
+	scriptTimeout         time.Duration
+	handlers              []slashings.Handler
+	verify                bool
 	block                 string
+	syncCheckInterval     time.Duration
+	notifyOnHold          bool
+	readinessHandler      func(ctx context.Context, ready bool)
+	staleSlots            uint64
+	massSlashingWindow    uint64
+	massSlashingCount     uint64
+	massSlashingBalance   phase0.Gwei
+	massSlashingScript    string
+	fetchWorkers          int
+	actionWorkers         int
+	queueSize             int
+	fetchRetries          int
+	fetchRetryDelay       time.Duration
+	retryFailed           bool
+	drill                 bool
+	drillScripts          bool
+	checkpointFile        string
 }
 
 // Parameter is the interface for service parameters.
@@ -62,6 +99,34 @@ func WithETH2Client(eth2Client eth2client.Service) Parameter {
 	})
 }
 
+// WithChainTime sets the chain time service for this module.
+func WithChainTime(chainTime chaintime.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.chainTime = chainTime
+	})
+}
+
+// WithDeadLetter sets the dead-letter service for blocks that cannot be fetched.
+func WithDeadLetter(deadLetter deadletter.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.deadLetter = deadLetter
+	})
+}
+
+// WithHistory sets the history service in which slashings are recorded.
+func WithHistory(history history.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.history = history
+	})
+}
+
+// WithLedger sets the ledger that ensures each action runs to success at most once.
+func WithLedger(ledger ledger.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.ledger = ledger
+	})
+}
+
 // WithAttesterSlashedScript sets the script when an attester is slashed.
 func WithAttesterSlashedScript(script string) Parameter {
 	return parameterFunc(func(p *parameters) {
@@ -76,6 +141,49 @@ func WithProposerSlashedScript(script string) Parameter {
 	})
 }
 
+// WithBatchScript sets the script run once per block with all of the slashed validators.
+func WithBatchScript(script string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.batchScript = script
+	})
+}
+
+// WithScriptTimeout sets the time after which a script that has not finished is killed.
+// 0 means no timeout.
+func WithScriptTimeout(timeout time.Duration) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.scriptTimeout = timeout
+	})
+}
+
+// WithPenalties sets the penalty estimation service.
+func WithPenalties(penalties penalties.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.penalties = penalties
+	})
+}
+
+// WithControls sets the operator controls that can stop actions from running.
+func WithControls(controls controls.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.controls = controls
+	})
+}
+
+// WithHandlers sets additional handlers, such as notifiers, for slashing events.
+func WithHandlers(handlers []slashings.Handler) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.handlers = handlers
+	})
+}
+
+// WithVerify sets whether the evidence for slashings is verified before acting on it.
+func WithVerify(verify bool) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.verify = verify
+	})
+}
+
 // WithBlock sets the block to run against.
 func WithBlock(block string) Parameter {
 	return parameterFunc(func(p *parameters) {
@@ -83,10 +191,148 @@ func WithBlock(block string) Parameter {
 	})
 }
 
+// WithSyncCheckInterval sets the interval between checks of the beacon node sync status.
+func WithSyncCheckInterval(interval time.Duration) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.syncCheckInterval = interval
+	})
+}
+
+// WithNotifyOnHold sets if slashings are reported while their scripts are held
+// awaiting validation of an optimistic block.
+func WithNotifyOnHold(notify bool) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.notifyOnHold = notify
+	})
+}
+
+// WithReadinessHandler sets the function called when the readiness of the service changes.
+func WithReadinessHandler(handler func(ctx context.Context, ready bool)) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.readinessHandler = handler
+	})
+}
+
+// WithStaleSlots sets the number of slots without a head event after which the
+// event stream is considered stale.
+func WithStaleSlots(slots uint64) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.staleSlots = slots
+	})
+}
+
+// WithMassSlashingWindow sets the number of epochs over which slashings are counted
+// towards a mass slashing.
+func WithMassSlashingWindow(epochs uint64) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.massSlashingWindow = epochs
+	})
+}
+
+// WithMassSlashingCount sets the number of slashed validators in the window that
+// constitutes a mass slashing.  0 disables the threshold.
+func WithMassSlashingCount(count uint64) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.massSlashingCount = count
+	})
+}
+
+// WithMassSlashingBalance sets the total effective balance of slashed validators in
+// the window that constitutes a mass slashing.  0 disables the threshold.
+func WithMassSlashingBalance(balance phase0.Gwei) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.massSlashingBalance = balance
+	})
+}
+
+// WithMassSlashingScript sets the script run when a mass slashing is detected.
+func WithMassSlashingScript(script string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.massSlashingScript = script
+	})
+}
+
+// WithFetchWorkers sets the number of workers fetching blocks concurrently.
+func WithFetchWorkers(workers int) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.fetchWorkers = workers
+	})
+}
+
+// WithActionWorkers sets the number of workers running actions concurrently.
+func WithActionWorkers(workers int) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.actionWorkers = workers
+	})
+}
+
+// WithQueueSize sets the size of each of the processing queues.
+func WithQueueSize(size int) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.queueSize = size
+	})
+}
+
+// WithFetchRetries sets the number of times to retry fetching a block before recording it as failed.
+func WithFetchRetries(retries int) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.fetchRetries = retries
+	})
+}
+
+// WithFetchRetryDelay sets the initial delay before retrying to fetch a block.
+// The delay doubles with each further attempt.
+func WithFetchRetryDelay(delay time.Duration) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.fetchRetryDelay = delay
+	})
+}
+
+// WithRetryFailed makes a single attempt to process the failed blocks, then exits.
+func WithRetryFailed(retryFailed bool) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.retryFailed = retryFailed
+	})
+}
+
+// WithDrill creates the service for running drills only, without watching the chain.
+func WithDrill(drill bool) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.drill = drill
+	})
+}
+
+// WithDrillScripts runs the scripts for drills.  If not set, each script is reported as
+// skipped rather than run.
+func WithDrillScripts(run bool) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.drillScripts = run
+	})
+}
+
+// WithCheckpointFile sets the file in which to record the slot up to which blocks have
+// been processed when the service is drained, so that processing resumes from that
+// slot when it restarts.
+func WithCheckpointFile(path string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.checkpointFile = path
+	})
+}
+
 // parseAndCheckParameters parses and checks parameters to ensure that mandatory parameters are present and correct.
 func parseAndCheckParameters(params ...Parameter) (*parameters, error) {
 	parameters := parameters{
-		logLevel: zerolog.GlobalLevel(),
+		logLevel:           zerolog.GlobalLevel(),
+		syncCheckInterval:  12 * time.Second,
+		notifyOnHold:       true,
+		staleSlots:         4,
+		massSlashingWindow: 8,
+		fetchWorkers:       4,
+		actionWorkers:      4,
+		queueSize:          64,
+		fetchRetries:       5,
+		fetchRetryDelay:    time.Second,
+		scriptTimeout:      5 * time.Minute,
 	}
 	for _, p := range params {
 		if params != nil {
@@ -97,6 +343,36 @@ func parseAndCheckParameters(params ...Parameter) (*parameters, error) {
 	if parameters.eth2Client == nil {
 		return nil, errors.New("no Ethereum 2 client specified")
 	}
+	if parameters.chainTime == nil {
+		return nil, errors.New("no chain time specified")
+	}
+	if parameters.syncCheckInterval == 0 {
+		return nil, errors.New("no sync check interval specified")
+	}
+	if parameters.staleSlots == 0 {
+		return nil, errors.New("no stale slots specified")
+	}
+	if parameters.massSlashingWindow == 0 {
+		return nil, errors.New("no mass slashing window specified")
+	}
+	if parameters.fetchWorkers < 1 {
+		return nil, errors.New("fetch workers must be at least 1")
+	}
+	if parameters.actionWorkers < 1 {
+		return nil, errors.New("action workers must be at least 1")
+	}
+	if parameters.queueSize < 1 {
+		return nil, errors.New("queue size must be at least 1")
+	}
+	if parameters.fetchRetries < 0 {
+		return nil, errors.New("fetch retries cannot be negative")
+	}
+	if parameters.fetchRetryDelay <= 0 {
+		return nil, errors.New("no fetch retry delay specified")
+	}
+	if parameters.scriptTimeout < 0 {
+		return nil, errors.New("script timeout cannot be negative")
+	}
 
 	return &parameters, nil
 }
@@ -0,0 +1,66 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// estimatePenalties adds the estimated penalties to the events of validators
+// penalised by the slashings.
+func (s *Service) estimatePenalties(ctx context.Context, block *blockSlashings) {
+	if s.penalties == nil {
+		return
+	}
+
+	indices := make([]phase0.ValidatorIndex, 0, len(block.events))
+	for _, event := range block.events {
+		if !event.AlreadySlashed {
+			indices = append(indices, event.ValidatorIndex)
+		}
+	}
+	if len(indices) == 0 {
+		return
+	}
+
+	// Use the state after the block, which includes its slashings.
+	penalties, err := s.penalties.Estimate(ctx, fmt.Sprintf("%#x", block.stateRoot), indices)
+	if err != nil {
+		s.log.Warn().Uint64("slot", uint64(block.slot)).Err(err).Msg("Failed to estimate penalties; continuing without them")
+		return
+	}
+	for _, event := range block.events {
+		event.Penalty = penalties[event.ValidatorIndex]
+	}
+}
+
+// penaltyEnv returns the environment variables describing a penalty to a script.
+func penaltyEnv(penalty *slashings.Penalty) []string {
+	if penalty == nil {
+		return nil
+	}
+
+	return []string{
+		fmt.Sprintf("ESD_EFFECTIVE_BALANCE=%d", penalty.EffectiveBalance),
+		fmt.Sprintf("ESD_INITIAL_PENALTY=%d", penalty.InitialPenalty),
+		fmt.Sprintf("ESD_CORRELATION_EPOCH=%d", penalty.CorrelationEpoch),
+		fmt.Sprintf("ESD_CORRELATION_PENALTY=%d", penalty.CorrelationPenalty),
+		fmt.Sprintf("ESD_WHISTLEBLOWER_REWARD=%d", penalty.WhistleblowerReward),
+		fmt.Sprintf("ESD_PROPOSER_REWARD=%d", penalty.ProposerReward),
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"time"
+
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// The processing pipeline has three stages:
+//   - a pool of fetchers obtain blocks and extract their slashings concurrently;
+//   - a sequencer reorders the results to match the order in which blocks were
+//     queued, reports the slashings, and hands their actions on;
+//   - a pool of action workers run the actions.  Actions are sharded by validator
+//     index, so the actions for a single validator always run in order;
+//   - a batch worker runs the batch script, once per block;
+//   - a mass slashing worker reports mass slashings and runs their script.
+
+// blockItem is a block queued for processing.
+type blockItem struct {
+	seq     uint64
+	slot    phase0.Slot
+	blockID string
+	// backfill is true if the block was queued by a backfill rather than a head event.
+	backfill bool
+	// attempt is the number of previous attempts to fetch the block.
+	attempt int
+	queued  time.Time
+}
+
+// blockResult is the result of fetching a queued block.
+type blockResult struct {
+	item       *blockItem
+	slashings  *blockSlashings
+	optimistic bool
+	err        error
+}
+
+// actionItem is an action queued for a slashed validator.
+type actionItem struct {
+	event *slashings.SlashingEvent
+}
+
+// startPipeline starts the workers of the processing pipeline.
+func (s *Service) startPipeline(ctx context.Context) {
+	s.blockQueue = make(chan *blockItem, s.queueSize)
+	s.resultQueue = make(chan *blockResult, s.queueSize)
+	s.actionQueues = make([]chan *actionItem, s.actionWorkers)
+	for i := range s.actionQueues {
+		s.actionQueues[i] = make(chan *actionItem, s.queueSize)
+	}
+	s.batchQueue = make(chan *batchItem, s.queueSize)
+	s.massQueue = make(chan *massItem, s.queueSize)
+
+	for i := 0; i < s.fetchWorkers; i++ {
+		go s.fetchBlocks(ctx)
+	}
+	go s.sequenceResults(ctx)
+	// Actions have their own context, so that they can be stopped if a drain times out.
+	actionCtx, stopActions := context.WithCancel(ctx)
+	s.stopActions = stopActions
+	for i := range s.actionQueues {
+		go s.runActions(actionCtx, s.actionQueues[i])
+	}
+	go s.runBatches(actionCtx)
+	go s.runMassSlashings(actionCtx)
+}
+
+// enqueueBlock queues a block for processing.
+func (s *Service) enqueueBlock(ctx context.Context, slot phase0.Slot, blockID string, backfill bool) {
+	s.headMu.Lock()
+	s.inFlightBlocks[slot]++
+	s.headMu.Unlock()
+
+	s.queueBlock(ctx, &blockItem{
+		slot:     slot,
+		blockID:  blockID,
+		backfill: backfill,
+		queued:   time.Now(),
+	})
+}
+
+// queueBlock places a block item on the block queue.
+func (s *Service) queueBlock(ctx context.Context, item *blockItem) {
+	// Sequence numbers must be allocated in the same order as items enter the queue,
+	// so hold the lock while sending.
+	s.blockQueueMu.Lock()
+	defer s.blockQueueMu.Unlock()
+
+	item.seq = s.nextSeq
+	select {
+	case s.blockQueue <- item:
+	default:
+		s.log.Warn().Uint64("slot", uint64(item.slot)).Msg("Block queue is full; waiting for space")
+		select {
+		case s.blockQueue <- item:
+		case <-ctx.Done():
+			return
+		}
+	}
+	s.nextSeq++
+	setQueueDepth(ctx, "blocks", len(s.blockQueue))
+}
+
+// fetchBlocks fetches queued blocks and passes the results to the sequencer.
+func (s *Service) fetchBlocks(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-s.blockQueue:
+			setQueueDepth(ctx, "blocks", len(s.blockQueue))
+			result := &blockResult{
+				item: item,
+			}
+			source := slashings.SourceHead
+			if item.backfill {
+				source = slashings.SourceBackfill
+			}
+			result.slashings, result.optimistic, result.err = s.fetchSlashings(ctx, item.blockID, source)
+			select {
+			case s.resultQueue <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// sequenceResults handles fetched blocks in the order in which they were queued.
+func (s *Service) sequenceResults(ctx context.Context) {
+	pending := make(map[uint64]*blockResult)
+	next := uint64(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case result := <-s.resultQueue:
+			pending[result.item.seq] = result
+			for {
+				result, exists := pending[next]
+				if !exists {
+					break
+				}
+				delete(pending, next)
+				next++
+				s.handleResult(ctx, result)
+				s.headMu.Lock()
+				s.handledBlocks = next
+				s.lastProgress = time.Now()
+				s.headMu.Unlock()
+			}
+			setQueueDepth(ctx, "results", len(pending))
+		}
+	}
+}
+
+// handleResult handles the result of fetching a block.
+func (s *Service) handleResult(ctx context.Context, result *blockResult) {
+	log := s.log.With().Uint64("slot", uint64(result.item.slot)).Str("block", result.item.blockID).Logger()
+	if result.err != nil {
+		if result.item.backfill && isNotFound(result.err) {
+			log.Trace().Msg("No block at slot")
+			s.blockFinished(result.item.slot)
+			return
+		}
+		if !s.retryBlock(ctx, result.item, result.err) {
+			s.blockFinished(result.item.slot)
+		}
+		return
+	}
+	defer s.blockFinished(result.item.slot)
+
+	blockProcessed(ctx)
+	blockProcessingCompleted(ctx, time.Since(result.item.queued))
+	s.headMu.Lock()
+	if result.item.slot > s.lastProcessedSlot {
+		s.lastProcessedSlot = result.item.slot
+	}
+	s.headMu.Unlock()
+
+	if result.slashings == nil {
+		log.Trace().Msg("No slashings")
+		return
+	}
+
+	if result.optimistic {
+		s.holdSlashings(ctx, result.slashings)
+		return
+	}
+
+	s.dispatchSlashings(ctx, result.slashings)
+}
+
+// dispatchSlashings reports the slashings and queues their actions.
+func (s *Service) dispatchSlashings(ctx context.Context, slashings *blockSlashings) {
+	if !slashings.notified {
+		s.notifySlashings(ctx, slashings)
+	}
+
+	for _, item := range slashings.actionItems() {
+		s.queueAction(ctx, item)
+	}
+	s.queueBatch(ctx, slashings.batchItem())
+	if massItem := s.recordMassSlashings(ctx, slashings); massItem != nil {
+		s.queueMassSlashing(ctx, massItem)
+	}
+}
+
+// queueAction places an action on the queue for its validator.
+func (s *Service) queueAction(ctx context.Context, item *actionItem) {
+	queue := s.actionQueues[uint64(item.event.ValidatorIndex)%uint64(len(s.actionQueues))]
+	s.pendingActions.Add(1)
+	select {
+	case queue <- item:
+	case <-ctx.Done():
+		s.pendingActions.Add(-1)
+		return
+	}
+	setQueueDepth(ctx, "actions", s.actionQueueDepth())
+}
+
+// runActions runs queued actions.
+func (s *Service) runActions(ctx context.Context, queue chan *actionItem) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-queue:
+			setQueueDepth(ctx, "actions", s.actionQueueDepth())
+			started := time.Now()
+			s.runAction(ctx, item)
+			s.pendingActions.Add(-1)
+			actionCompleted(ctx, time.Since(started))
+		}
+	}
+}
+
+// blockFinished records that the pipeline has finished with a block, whether or not it
+// was processed successfully.
+func (s *Service) blockFinished(slot phase0.Slot) {
+	s.headMu.Lock()
+	defer s.headMu.Unlock()
+
+	s.inFlightBlocks[slot]--
+	if s.inFlightBlocks[slot] <= 0 {
+		delete(s.inFlightBlocks, slot)
+	}
+}
+
+// actionQueueDepth returns the total number of queued actions.
+func (s *Service) actionQueueDepth() int {
+	depth := 0
+	for i := range s.actionQueues {
+		depth += len(s.actionQueues[i])
+	}
+
+	return depth
+}
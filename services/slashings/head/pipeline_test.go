@@ -0,0 +1,195 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// pipelineService returns a service with a running pipeline that reports slashings to
+// the given handler.
+func pipelineService(ctx context.Context, handler slashings.Handler, actionWorkers int) *Service {
+	s := &Service{
+		log:            zerolog.Nop(),
+		chainTime:      &mockChainTime{slot: 100},
+		fetchWorkers:   1,
+		actionWorkers:  actionWorkers,
+		queueSize:      64,
+		inFlightBlocks: make(map[phase0.Slot]int),
+	}
+	s.cfg.Store(&config{
+		handlers: []slashings.Handler{handler},
+	})
+	s.startPipeline(ctx)
+
+	return s
+}
+
+// slashedBlock returns the result of fetching a block that slashes the given validator.
+func slashedBlock(seq uint64, validator phase0.ValidatorIndex) *blockResult {
+	slot := phase0.Slot(100 + seq)
+
+	return &blockResult{
+		item: &blockItem{
+			seq:     seq,
+			slot:    slot,
+			blockID: "test",
+			queued:  time.Now(),
+		},
+		slashings: &blockSlashings{
+			slot: slot,
+			events: []*slashings.SlashingEvent{
+				{
+					Slot:           slot,
+					ValidatorIndex: validator,
+					Offences: []*slashings.Offence{
+						{Kind: slashings.OffenceDoubleVote},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSequenceResults(t *testing.T) {
+	tests := []struct {
+		name  string
+		order []uint64
+	}{
+		{
+			name:  "InOrder",
+			order: []uint64{0, 1, 2, 3},
+		},
+		{
+			name:  "Reversed",
+			order: []uint64{3, 2, 1, 0},
+		},
+		{
+			name:  "Shuffled",
+			order: []uint64{2, 0, 3, 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			handler := &recordingHandler{}
+			s := pipelineService(ctx, handler, 2)
+			s.headMu.Lock()
+			for seq := range test.order {
+				s.inFlightBlocks[phase0.Slot(100+seq)] = 1
+			}
+			s.headMu.Unlock()
+			require.False(t, s.idle())
+
+			for _, seq := range test.order {
+				// Validator indices match the order in which blocks were queued.
+				s.resultQueue <- slashedBlock(seq, phase0.ValidatorIndex(seq))
+			}
+
+			require.Eventually(t, s.idle, time.Second, time.Millisecond)
+			require.Equal(t, []phase0.ValidatorIndex{0, 1, 2, 3}, handler.handled())
+			s.headMu.Lock()
+			require.Equal(t, uint64(len(test.order)), s.handledBlocks)
+			require.Equal(t, phase0.Slot(100+len(test.order)-1), s.lastProcessedSlot)
+			s.headMu.Unlock()
+		})
+	}
+}
+
+func TestSequenceResultsWaitsForGap(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := &recordingHandler{}
+	s := pipelineService(ctx, handler, 2)
+	s.headMu.Lock()
+	for seq := 0; seq < 3; seq++ {
+		s.inFlightBlocks[phase0.Slot(100+seq)] = 1
+	}
+	s.headMu.Unlock()
+
+	s.resultQueue <- slashedBlock(1, 1)
+	s.resultQueue <- slashedBlock(2, 2)
+	// Nothing is dispatched until the first block is available.
+	require.Never(t, func() bool { return len(handler.handled()) > 0 }, 50*time.Millisecond, time.Millisecond)
+	require.False(t, s.idle())
+
+	s.resultQueue <- slashedBlock(0, 0)
+	require.Eventually(t, s.idle, time.Second, time.Millisecond)
+	require.Equal(t, []phase0.ValidatorIndex{0, 1, 2}, handler.handled())
+}
+
+func TestQueueActionSharding(t *testing.T) {
+	tests := []struct {
+		name       string
+		workers    int
+		validators []phase0.ValidatorIndex
+		queues     [][]phase0.ValidatorIndex
+	}{
+		{
+			name:       "SingleWorker",
+			workers:    1,
+			validators: []phase0.ValidatorIndex{5, 3, 1},
+			queues:     [][]phase0.ValidatorIndex{{5, 3, 1}},
+		},
+		{
+			name:       "ThreeWorkers",
+			workers:    3,
+			validators: []phase0.ValidatorIndex{0, 1, 2, 3, 4, 5, 7},
+			queues:     [][]phase0.ValidatorIndex{{0, 3}, {1, 4, 7}, {2, 5}},
+		},
+		{
+			name:       "SameValidator",
+			workers:    3,
+			validators: []phase0.ValidatorIndex{4, 4, 4},
+			queues:     [][]phase0.ValidatorIndex{{}, {4, 4, 4}, {}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			// No workers are running, so the actions remain in their queues.
+			s := &Service{
+				actionQueues: make([]chan *actionItem, test.workers),
+			}
+			for i := range s.actionQueues {
+				s.actionQueues[i] = make(chan *actionItem, 16)
+			}
+
+			for _, validator := range test.validators {
+				s.queueAction(ctx, &actionItem{event: &slashings.SlashingEvent{ValidatorIndex: validator}})
+			}
+
+			require.Equal(t, int64(len(test.validators)), s.pendingActions.Load())
+			for i, queue := range s.actionQueues {
+				queued := make([]phase0.ValidatorIndex, 0)
+				for len(queue) > 0 {
+					queued = append(queued, (<-queue).event.ValidatorIndex)
+				}
+				require.Equal(t, test.queues[i], queued)
+			}
+		})
+	}
+}
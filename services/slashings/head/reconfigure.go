@@ -0,0 +1,64 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"time"
+
+	"github.com/attestantio/esd/services/slashings"
+)
+
+// config holds the settings of the service that can be changed while it is running.
+type config struct {
+	attesterSlashedScript string
+	proposerSlashedScript string
+	batchScript           string
+	massSlashingScript    string
+	scriptTimeout         time.Duration
+	handlers              []slashings.Handler
+}
+
+// Reconfigure changes the log level, scripts, script timeout and handlers of the running service.
+// Other parameters are ignored.  The new settings are swapped in together, and apply
+// to actions started after this returns; actions already running complete with the
+// previous settings.
+func (s *Service) Reconfigure(_ context.Context, params ...Parameter) {
+	current := s.cfg.Load()
+	parameters := parameters{
+		logLevel:              s.logLevel.Level(),
+		attesterSlashedScript: current.attesterSlashedScript,
+		proposerSlashedScript: current.proposerSlashedScript,
+		batchScript:           current.batchScript,
+		massSlashingScript:    current.massSlashingScript,
+		scriptTimeout:         current.scriptTimeout,
+		handlers:              current.handlers,
+	}
+	for _, p := range params {
+		if params != nil {
+			p.apply(&parameters)
+		}
+	}
+
+	s.cfg.Store(&config{
+		attesterSlashedScript: parameters.attesterSlashedScript,
+		proposerSlashedScript: parameters.proposerSlashedScript,
+		batchScript:           parameters.batchScript,
+		massSlashingScript:    parameters.massSlashingScript,
+		scriptTimeout:         parameters.scriptTimeout,
+		handlers:              parameters.handlers,
+	})
+	s.logLevel.SetLevel(parameters.logLevel)
+	s.log.Info().Str("log_level", parameters.logLevel.String()).Int("handlers", len(parameters.handlers)).Msg("Reconfigured")
+}
@@ -0,0 +1,142 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"time"
+
+	"github.com/attestantio/esd/services/deadletter"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/pkg/errors"
+)
+
+// maxFetchRetryDelay is the maximum delay between attempts to fetch a block.
+const maxFetchRetryDelay = time.Minute
+
+// retryBlock schedules another attempt to fetch a block, or records it as failed if
+// the retry budget has been exhausted.  Returns true if another attempt is scheduled.
+func (s *Service) retryBlock(ctx context.Context, item *blockItem, err error) bool {
+	log := s.log.With().Uint64("slot", uint64(item.slot)).Str("block", item.blockID).Int("attempt", item.attempt+1).Logger()
+
+	if item.attempt < s.fetchRetries {
+		delay := s.fetchRetryDelayFor(item.attempt)
+		log.Debug().Err(err).Dur("delay", delay).Msg("Failed to fetch block; will retry")
+		blockFetchRetried(ctx)
+		time.AfterFunc(delay, func() {
+			if ctx.Err() != nil {
+				return
+			}
+			s.queueBlock(ctx, &blockItem{
+				slot:     item.slot,
+				blockID:  item.blockID,
+				backfill: item.backfill,
+				attempt:  item.attempt + 1,
+				queued:   item.queued,
+			})
+		})
+
+		return true
+	}
+
+	log.Error().Err(err).Msg("Failed to fetch block; recording as failed")
+	if s.deadLetter == nil {
+		return false
+	}
+	now := time.Now()
+	if err := s.deadLetter.Add(ctx, &deadletter.Entry{
+		BlockID:      item.blockID,
+		Slot:         item.slot,
+		Attempts:     item.attempt + 1,
+		FirstFailure: item.queued,
+		LastFailure:  now,
+		Error:        err.Error(),
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to record failed block")
+	}
+	s.updateFailedBlocks(ctx)
+
+	return false
+}
+
+// fetchRetryDelayFor returns the delay before the next attempt to fetch a block.
+func (s *Service) fetchRetryDelayFor(attempt int) time.Duration {
+	delay := s.fetchRetryDelay
+	for i := 0; i < attempt && delay < maxFetchRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxFetchRetryDelay {
+		delay = maxFetchRetryDelay
+	}
+
+	return delay
+}
+
+// retryFailed makes a single further attempt to process each failed block.
+func (s *Service) retryFailed(ctx context.Context) error {
+	if s.deadLetter == nil {
+		return errors.New("no dead-letter service specified")
+	}
+
+	entries, err := s.deadLetter.Entries(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain failed blocks")
+	}
+
+	for _, entry := range entries {
+		log := s.log.With().Uint64("slot", uint64(entry.Slot)).Str("block", entry.BlockID).Logger()
+		block, optimistic, err := s.fetchSlashings(ctx, entry.BlockID, slashings.SourceRetry)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to fetch block")
+			if err := s.deadLetter.Add(ctx, &deadletter.Entry{
+				BlockID:     entry.BlockID,
+				Slot:        entry.Slot,
+				Attempts:    1,
+				LastFailure: time.Now(),
+				Error:       err.Error(),
+			}); err != nil {
+				return errors.Wrap(err, "failed to update failed block")
+			}
+
+			continue
+		}
+		if optimistic {
+			log.Warn().Msg("Block is optimistic; leaving for a later retry")
+			continue
+		}
+
+		if block != nil {
+			s.handleSlashings(ctx, block)
+		}
+		if err := s.deadLetter.Remove(ctx, entry.BlockID); err != nil {
+			return errors.Wrap(err, "failed to remove failed block")
+		}
+		log.Info().Msg("Processed previously failed block")
+	}
+
+	return nil
+}
+
+// updateFailedBlocks updates the failed blocks metric.
+func (s *Service) updateFailedBlocks(ctx context.Context) {
+	if s.deadLetter == nil {
+		return
+	}
+	entries, err := s.deadLetter.Entries(ctx)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("Failed to obtain failed blocks")
+		return
+	}
+	setFailedBlocks(ctx, len(entries))
+}
@@ -0,0 +1,186 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/attestantio/esd/services/deadletter/file"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchRetryDelayFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    time.Duration
+		attempt int
+		delay   time.Duration
+	}{
+		{
+			name:    "First",
+			base:    time.Second,
+			attempt: 0,
+			delay:   time.Second,
+		},
+		{
+			name:    "Second",
+			base:    time.Second,
+			attempt: 1,
+			delay:   2 * time.Second,
+		},
+		{
+			name:    "Fifth",
+			base:    time.Second,
+			attempt: 4,
+			delay:   16 * time.Second,
+		},
+		{
+			name:    "Capped",
+			base:    time.Second,
+			attempt: 6,
+			delay:   maxFetchRetryDelay,
+		},
+		{
+			name:    "CappedManyAttempts",
+			base:    time.Second,
+			attempt: 1000,
+			delay:   maxFetchRetryDelay,
+		},
+		{
+			name:    "BaseAboveCap",
+			base:    2 * time.Minute,
+			attempt: 0,
+			delay:   maxFetchRetryDelay,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &Service{
+				fetchRetryDelay: test.base,
+			}
+			require.Equal(t, test.delay, s.fetchRetryDelayFor(test.attempt))
+		})
+	}
+}
+
+func TestRetryBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		retries int
+		attempt int
+		retried bool
+	}{
+		{
+			name:    "NoRetries",
+			retries: 0,
+			attempt: 0,
+			retried: false,
+		},
+		{
+			name:    "FirstRetry",
+			retries: 2,
+			attempt: 0,
+			retried: true,
+		},
+		{
+			name:    "LastRetry",
+			retries: 2,
+			attempt: 1,
+			retried: true,
+		},
+		{
+			name:    "BudgetExhausted",
+			retries: 2,
+			attempt: 2,
+			retried: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			deadLetter, err := file.New(ctx, file.WithPath(filepath.Join(t.TempDir(), "failed-blocks.json")))
+			require.NoError(t, err)
+			s := &Service{
+				log:             zerolog.Nop(),
+				deadLetter:      deadLetter,
+				fetchRetries:    test.retries,
+				fetchRetryDelay: time.Millisecond,
+				blockQueue:      make(chan *blockItem, 1),
+			}
+			s.nextSeq = 5
+			queued := time.Now().Add(-time.Minute)
+			item := &blockItem{
+				seq:      3,
+				slot:     100,
+				blockID:  "0x01",
+				backfill: true,
+				attempt:  test.attempt,
+				queued:   queued,
+			}
+
+			require.Equal(t, test.retried, s.retryBlock(ctx, item, errors.New("not found")))
+
+			entries, err := deadLetter.Entries(ctx)
+			require.NoError(t, err)
+			if test.retried {
+				require.Empty(t, entries)
+				// The block is queued again after the delay, with a new sequence number.
+				select {
+				case retry := <-s.blockQueue:
+					require.Equal(t, uint64(5), retry.seq)
+					require.Equal(t, item.slot, retry.slot)
+					require.Equal(t, item.blockID, retry.blockID)
+					require.Equal(t, item.backfill, retry.backfill)
+					require.Equal(t, test.attempt+1, retry.attempt)
+					require.Equal(t, queued, retry.queued)
+				case <-time.After(time.Second):
+					require.Fail(t, "block not queued for retry")
+				}
+
+				return
+			}
+
+			require.Len(t, entries, 1)
+			require.Equal(t, "0x01", entries[0].BlockID)
+			require.Equal(t, test.attempt+1, entries[0].Attempts)
+			require.Equal(t, queued, entries[0].FirstFailure)
+			require.Equal(t, "not found", entries[0].Error)
+			require.Empty(t, s.blockQueue)
+		})
+	}
+}
+
+func TestRetryBlockStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Service{
+		log:             zerolog.Nop(),
+		fetchRetries:    1,
+		fetchRetryDelay: 10 * time.Millisecond,
+		blockQueue:      make(chan *blockItem, 1),
+	}
+
+	require.True(t, s.retryBlock(ctx, &blockItem{slot: 100, blockID: "100"}, errors.New("not found")))
+	cancel()
+	// The retry is abandoned once the service stops.
+	require.Never(t, func() bool { return len(s.blockQueue) > 0 }, 50*time.Millisecond, 5*time.Millisecond)
+}
@@ -16,23 +16,25 @@ package head
 import (
 	"context"
 	"fmt"
-	"os/exec"
+	"strings"
 
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/esd/util"
 	spec "github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/pkg/errors"
 )
 
 // OnProposerSlashed handles a proposer slashing event.
-func (s *Service) OnProposerSlashed(_ context.Context, index spec.ValidatorIndex) error {
-	if s.proposerSlashedScript == "" {
+func (s *Service) OnProposerSlashed(ctx context.Context, index spec.ValidatorIndex) error {
+	script := s.cfg.Load().proposerSlashedScript
+	if script == "" {
 		return nil
 	}
 
-	s.log.Trace().Str("script", s.attesterSlashedScript).Msg("Calling script for slashed proposer")
-	//nolint:gosec
-	output, err := exec.Command(s.proposerSlashedScript, fmt.Sprintf("%d", index)).CombinedOutput()
+	s.log.Trace().Str("script", script).Msg("Calling script for slashed proposer")
+	output, err := s.runScript(ctx, script, index, nil)
 	if err != nil {
-		s.log.Warn().Str("output", string(output)).Msg("Run information")
+		s.log.Warn().Str("output", output).Err(err).Msg("Run information")
 		return errors.Wrap(err, "failed to run proposer slashing script")
 	}
 
@@ -40,18 +42,67 @@ func (s *Service) OnProposerSlashed(_ context.Context, index spec.ValidatorIndex
 }
 
 // OnAttesterSlashed handles an attester slashing event.
-func (s *Service) OnAttesterSlashed(_ context.Context, index spec.ValidatorIndex) error {
-	if s.attesterSlashedScript == "" {
+func (s *Service) OnAttesterSlashed(ctx context.Context, index spec.ValidatorIndex) error {
+	script := s.cfg.Load().attesterSlashedScript
+	if script == "" {
 		return nil
 	}
 
-	s.log.Info().Str("script", s.attesterSlashedScript).Msg("Calling script for slashed attester")
-	//nolint:gosec
-	output, err := exec.Command(s.attesterSlashedScript, fmt.Sprintf("%d", index)).CombinedOutput()
+	s.log.Info().Str("script", script).Msg("Calling script for slashed attester")
+	output, err := s.runScript(ctx, script, index, nil)
 	if err != nil {
-		s.log.Warn().Str("output", string(output)).Msg("Run information")
+		s.log.Warn().Str("output", output).Err(err).Msg("Run information")
 		return errors.Wrap(err, "failed to run attester slashing script")
 	}
 
 	return nil
 }
+
+// blocked returns a description of why the action should not run for the given
+// validators, or an empty string if it can run.
+func (s *Service) blocked(action string, indices ...spec.ValidatorIndex) string {
+	if s.controls == nil {
+		return ""
+	}
+
+	return s.controls.Blocked(action, indices...)
+}
+
+// runScript runs a script for a slashed validator, returning its combined output.
+// Additional environment variables can be supplied to give the script context.
+func (s *Service) runScript(ctx context.Context, script string, index spec.ValidatorIndex, env []string) (string, error) {
+	return s.runCommand(ctx, script, []string{fmt.Sprintf("%d", index)}, env, nil)
+}
+
+// runCommand runs a script with the given arguments, environment variables and input,
+// returning its combined output.  The script is killed if it exceeds the script timeout.
+// For a drill the script is skipped unless drill scripts are run, when it is told that
+// it is being run for a drill.
+func (s *Service) runCommand(ctx context.Context, script string, args []string, env []string, input []byte) (string, error) {
+	if s.drill {
+		if !s.drillScripts {
+			return "", errDrillSkipped
+		}
+		env = append(env, drillEnv)
+	}
+
+	return util.RunScript(ctx, s.cfg.Load().scriptTimeout, script, args, env, input)
+}
+
+// scriptEnv returns the environment variables describing a slashing to a script.
+// Offences are omitted if not supplied.
+func scriptEnv(slot spec.Slot, root spec.Root, offences []*slashings.Offence) []string {
+	env := []string{
+		fmt.Sprintf("ESD_SLOT=%d", slot),
+		fmt.Sprintf("ESD_BLOCK_ROOT=%#x", root),
+	}
+	if len(offences) > 0 {
+		env = append(env,
+			fmt.Sprintf("ESD_OFFENCES=%s", strings.Join(offenceTypes(offences), ",")),
+			fmt.Sprintf("ESD_OFFENCE_KINDS=%s", strings.Join(offenceKinds(offences), ",")),
+			fmt.Sprintf("ESD_OFFENCE_DETAILS=%s", strings.Join(offenceDescriptions(offences), "; ")),
+		)
+	}
+
+	return env
+}
@@ -15,10 +15,22 @@ package head
 
 import (
 	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/attestantio/esd/services/chaintime"
+	"github.com/attestantio/esd/services/controls"
+	"github.com/attestantio/esd/services/deadletter"
+	"github.com/attestantio/esd/services/history"
+	"github.com/attestantio/esd/services/ledger"
+	"github.com/attestantio/esd/services/penalties"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/esd/util"
 	eth2client "github.com/attestantio/go-eth2-client"
 	"github.com/attestantio/go-eth2-client/api"
-	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	zerologger "github.com/rs/zerolog/log"
@@ -26,10 +38,82 @@ import (
 
 // Service is slashings services that watches blocks for slashings.
 type Service struct {
-	log                   zerolog.Logger
-	eth2Client            eth2client.Service
-	attesterSlashedScript string
-	proposerSlashedScript string
+	log                 zerolog.Logger
+	logLevel            *util.LevelFilter
+	eth2Client          eth2client.Service
+	chainTime           chaintime.Service
+	deadLetter          deadletter.Service
+	history             history.Service
+	ledger              ledger.Service
+	penalties           penalties.Service
+	controls            controls.Service
+	verify              bool
+	syncCheckInterval   time.Duration
+	notifyOnHold        bool
+	readinessHandler    func(ctx context.Context, ready bool)
+	staleSlots          uint64
+	massSlashingWindow  uint64
+	massSlashingCount   uint64
+	massSlashingBalance phase0.Gwei
+	checkpointFile      string
+	drill               bool
+	drillScripts        bool
+
+	syncStatus   *syncStatus
+	syncStatusMu sync.RWMutex
+	// syncClient fetches the parts of the sync state that the beacon node client does not decode.
+	syncClient *http.Client
+
+	heldSlashings   map[phase0.Root]*blockSlashings
+	heldSlashingsMu sync.Mutex
+
+	lastHeadSlot        phase0.Slot
+	lastQueuedSlot      phase0.Slot
+	lastResubscribeSlot phase0.Slot
+	lastProcessedSlot   phase0.Slot
+	handledBlocks       uint64
+	backfillSeq         uint64
+	lastProgress        time.Time
+	inFlightBlocks      map[phase0.Slot]int
+	draining            bool
+	stale               bool
+	headMu              sync.Mutex
+
+	// pendingActions is the number of actions and batches queued or running.
+	pendingActions atomic.Int64
+	// stopActions stops the action workers, killing any scripts that are running.
+	stopActions context.CancelFunc
+
+	lastFinalizedEpoch phase0.Epoch
+
+	// cfg is the configuration that can be changed while the service is running.
+	cfg atomic.Pointer[config]
+
+	massSlashings      map[phase0.Epoch]map[phase0.ValidatorIndex]phase0.Gwei
+	massSlashingActive bool
+	massSlashingMu     sync.Mutex
+
+	drillResults []*slashings.DrillResult
+	drillMu      sync.Mutex
+
+	chainInfoCache *chainInfo
+	chainInfoMu    sync.Mutex
+
+	cancelSubscription context.CancelFunc
+	subscriptionMu     sync.Mutex
+
+	fetchWorkers    int
+	actionWorkers   int
+	queueSize       int
+	fetchRetries    int
+	fetchRetryDelay time.Duration
+	blockQueue      chan *blockItem
+	blockQueueMu    sync.Mutex
+	nextSeq         uint64
+	resultQueue     chan *blockResult
+	actionQueues    []chan *actionItem
+	batchQueue      chan *batchItem
+	massQueue       chan *massItem
 }
 
 // New creates a new service.
@@ -39,18 +123,50 @@ func New(ctx context.Context, params ...Parameter) (*Service, error) {
 		return nil, errors.Wrap(err, "problem with parameters")
 	}
 
-	// Set logging.
-	log := zerologger.With().Str("service", "slashings").Str("impl", "head").Logger()
-	if parameters.logLevel != log.GetLevel() {
-		log = log.Level(parameters.logLevel)
-	}
+	// Set logging.  The level is applied by a filter so that it can be reconfigured.
+	logLevel := util.NewLevelFilter(parameters.logLevel)
+	log := zerologger.With().Str("service", "slashings").Str("impl", "head").Logger().Level(zerolog.TraceLevel).Hook(logLevel)
 
 	svc := &Service{
-		log:                   log,
-		eth2Client:            parameters.eth2Client,
+		log:                 log,
+		logLevel:            logLevel,
+		eth2Client:          parameters.eth2Client,
+		chainTime:           parameters.chainTime,
+		deadLetter:          parameters.deadLetter,
+		history:             parameters.history,
+		ledger:              parameters.ledger,
+		penalties:           parameters.penalties,
+		controls:            parameters.controls,
+		verify:              parameters.verify,
+		syncCheckInterval:   parameters.syncCheckInterval,
+		notifyOnHold:        parameters.notifyOnHold,
+		readinessHandler:    parameters.readinessHandler,
+		staleSlots:          parameters.staleSlots,
+		massSlashingWindow:  parameters.massSlashingWindow,
+		massSlashingCount:   parameters.massSlashingCount,
+		massSlashingBalance: parameters.massSlashingBalance,
+		checkpointFile:      parameters.checkpointFile,
+		drill:               parameters.drill,
+		drillScripts:        parameters.drillScripts,
+		fetchWorkers:        parameters.fetchWorkers,
+		actionWorkers:       parameters.actionWorkers,
+		queueSize:           parameters.queueSize,
+		fetchRetries:        parameters.fetchRetries,
+		fetchRetryDelay:     parameters.fetchRetryDelay,
+		syncClient:          &http.Client{},
+		heldSlashings:       make(map[phase0.Root]*blockSlashings),
+		lastProgress:        time.Now(),
+		inFlightBlocks:      make(map[phase0.Slot]int),
+		massSlashings:       make(map[phase0.Epoch]map[phase0.ValidatorIndex]phase0.Gwei),
+	}
+	svc.cfg.Store(&config{
 		attesterSlashedScript: parameters.attesterSlashedScript,
 		proposerSlashedScript: parameters.proposerSlashedScript,
-	}
+		batchScript:           parameters.batchScript,
+		massSlashingScript:    parameters.massSlashingScript,
+		scriptTimeout:         parameters.scriptTimeout,
+		handlers:              parameters.handlers,
+	})
 
 	if parameters.block != "" {
 		// Require running for a specific slot (for test purposes).
@@ -65,48 +181,67 @@ func New(ctx context.Context, params ...Parameter) (*Service, error) {
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to obtain block")
 		}
-		block := blockResponse.Data
-
-		slot, err := block.Slot()
+		block, err := svc.slashingsFromBlock(blockResponse.Data, slashings.SourceBlock)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to obtain slot")
+			return nil, err
 		}
-		root, err := block.Root()
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to obtain root")
+		if block != nil {
+			svc.enrichSlashings(ctx, block)
+			svc.handleSlashings(ctx, block)
+		}
+		// Service is not initialised, so do not return it.
+		//nolint:nilnil
+		return nil, nil
+	}
+
+	if parameters.retryFailed {
+		// Require retrying failed blocks (for operator purposes).
+		if err := svc.retryFailed(ctx); err != nil {
+			return nil, err
 		}
-		svc.OnHeadUpdated(ctx, slot, root)
 		// Service is not initialised, so do not return it.
 		//nolint:nilnil
 		return nil, nil
 	}
 
+	if parameters.drill {
+		// Service is only used to run drills, so do not start watching the chain.
+		return svc, nil
+	}
+
 	if parameters.monitor != nil {
 		if err := registerMetrics(ctx, parameters.monitor); err != nil {
 			return nil, errors.Wrap(err, "failed to register metrics")
 		}
 	}
+	svc.updateFailedBlocks(ctx)
 
-	eventsProvider, isEventsProvider := svc.eth2Client.(eth2client.EventsProvider)
-	if !isEventsProvider {
-		return nil, errors.New("eth2 client is not an events provider")
-	}
-	if err := eventsProvider.Events(ctx, []string{"head"}, func(event *apiv1.Event) {
-		if event.Data == nil {
-			return
-		}
+	// Obtain the initial sync status before processing any blocks.
+	svc.updateSyncStatus(ctx)
+	go svc.monitorSync(ctx)
 
-		eventData, isEventData := event.Data.(*apiv1.HeadEvent)
-		if !isEventData {
-			svc.log.Error().Msg("event data is not from a head event; cannot process")
-			return
-		}
+	// Start from the current head of the beacon node.
+	svc.lastHeadSlot = svc.chainTime.CurrentSlot()
+	svc.syncStatusMu.RLock()
+	if svc.syncStatus != nil && svc.syncStatus.headSlot != 0 {
+		svc.lastHeadSlot = svc.syncStatus.headSlot
+	}
+	svc.syncStatusMu.RUnlock()
+	svc.lastQueuedSlot = svc.lastHeadSlot
+	// Resume from the checkpoint of the previous run, if there is one.
+	checkpointed := svc.loadCheckpoint(ctx)
 
-		svc.OnHeadUpdated(ctx, eventData.Slot, eventData.Block)
-		blockProcessed(ctx)
-	}); err != nil {
-		return nil, errors.Wrap(err, "failed to configure head event feed")
+	svc.startPipeline(ctx)
+	if err := svc.resumeActions(ctx); err != nil {
+		return nil, err
+	}
+	if checkpointed {
+		svc.resumeFromCheckpoint(ctx)
+	}
+	if err := svc.subscribe(ctx); err != nil {
+		return nil, err
 	}
+	go svc.watchdog(ctx)
 
 	return svc, nil
 }
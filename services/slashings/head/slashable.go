@@ -0,0 +1,103 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"fmt"
+
+	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+)
+
+// addValidatorState adds the public keys of the slashed validators to their events, and
+// marks those that were not slashable before the block and so are not penalised by it.
+func (s *Service) addValidatorState(ctx context.Context, block *blockSlashings) {
+	indices := make([]phase0.ValidatorIndex, 0, len(block.events))
+	for _, event := range block.events {
+		indices = append(indices, event.ValidatorIndex)
+	}
+
+	validators, err := s.preStateValidators(ctx, block, indices)
+	if err != nil {
+		// The state may have been pruned, so fall back to the head state for the public
+		// keys.  This cannot tell which validators were already slashed, as the head state
+		// includes the slashings in this block, so assume that all are penalised.
+		s.log.Warn().Uint64("slot", uint64(block.slot)).Err(err).Msg("Failed to obtain state of slashed validators before the block; assuming all are penalised")
+		s.addPubKeys(ctx, block)
+		return
+	}
+
+	epoch := s.chainTime.SlotToEpoch(block.slot)
+	for _, event := range block.events {
+		validator, exists := validators[event.ValidatorIndex]
+		if !exists || validator.Validator == nil {
+			continue
+		}
+		event.PubKey = validator.Validator.PublicKey
+		event.EffectiveBalance = validator.Validator.EffectiveBalance
+		if !slashable(validator.Validator, epoch) {
+			event.AlreadySlashed = true
+		}
+	}
+}
+
+// preStateValidators returns the given validators from the state before the block was
+// applied, that is the state of its parent.
+func (s *Service) preStateValidators(ctx context.Context,
+	block *blockSlashings,
+	indices []phase0.ValidatorIndex,
+) (
+	map[phase0.ValidatorIndex]*apiv1.Validator,
+	error,
+) {
+	headerProvider, isProvider := s.eth2Client.(eth2client.BeaconBlockHeadersProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide block headers")
+	}
+	headerResponse, err := headerProvider.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{
+		Block: block.parentRoot.String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain parent block header")
+	}
+	if headerResponse.Data.Header == nil || headerResponse.Data.Header.Message == nil {
+		return nil, errors.New("parent block header missing")
+	}
+
+	validatorsProvider, isProvider := s.eth2Client.(eth2client.ValidatorsProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide validators")
+	}
+	validatorsResponse, err := validatorsProvider.Validators(ctx, &api.ValidatorsOpts{
+		State:   fmt.Sprintf("%#x", headerResponse.Data.Header.Message.StateRoot),
+		Indices: indices,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain validators")
+	}
+
+	return validatorsResponse.Data, nil
+}
+
+// slashable returns true if the validator can be slashed at the given epoch, as per
+// is_slashable_validator.
+func slashable(validator *phase0.Validator, epoch phase0.Epoch) bool {
+	return !validator.Slashed &&
+		validator.ActivationEpoch <= epoch &&
+		epoch < validator.WithdrawableEpoch
+}
@@ -0,0 +1,56 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+
+	"github.com/attestantio/esd/services/slashings"
+)
+
+// Status returns the current status of the service and its beacon node.
+func (s *Service) Status(_ context.Context) *slashings.Status {
+	status := &slashings.Status{}
+
+	s.syncStatusMu.RLock()
+	if s.syncStatus != nil {
+		status.HeadSlot = s.syncStatus.headSlot
+		status.Connected = s.syncStatus.connected
+		status.Syncing = s.syncStatus.isSyncing
+		status.Optimistic = s.syncStatus.isOptimistic
+		status.ELOffline = s.syncStatus.elOffline
+	}
+	s.syncStatusMu.RUnlock()
+
+	s.blockQueueMu.Lock()
+	queuedBlocks := s.nextSeq
+	s.blockQueueMu.Unlock()
+
+	s.headMu.Lock()
+	status.LastEventSlot = s.lastHeadSlot
+	status.LastProcessedSlot = s.lastProcessedSlot
+	status.StreamStale = s.stale
+	if queuedBlocks > s.handledBlocks {
+		status.PendingBlocks = int(queuedBlocks - s.handledBlocks)
+	}
+	status.Backfilling = s.handledBlocks < s.backfillSeq
+	status.LastProgress = s.lastProgress
+	s.headMu.Unlock()
+
+	s.heldSlashingsMu.Lock()
+	status.HeldBlocks = len(s.heldSlashings)
+	s.heldSlashingsMu.Unlock()
+
+	return status
+}
@@ -0,0 +1,209 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+)
+
+// syncStatus is the synchronisation status of the beacon node.
+type syncStatus struct {
+	connected    bool
+	headSlot     phase0.Slot
+	isSyncing    bool
+	isOptimistic bool
+	// elOffline is nil if the state of the execution client is unknown.
+	elOffline *bool
+}
+
+// healthy returns true if the beacon node can be trusted to provide valid blocks.
+func (s *syncStatus) healthy() bool {
+	return !s.isSyncing && !s.isOptimistic && !s.executionClientOffline()
+}
+
+// executionClientOffline returns true if the execution client is known to be offline.
+func (s *syncStatus) executionClientOffline() bool {
+	return s.elOffline != nil && *s.elOffline
+}
+
+// monitorSync periodically updates the sync status of the beacon node, releases
+// held slashings when their blocks have been validated, updates the finality
+// status of recorded slashings, and expires slashings from the mass slashing window.
+func (s *Service) monitorSync(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Trace().Msg("Context done; stopping sync monitor")
+			return
+		case <-time.After(s.syncCheckInterval):
+			s.updateSyncStatus(ctx)
+			s.releaseHeldSlashings(ctx)
+			s.updateFinality(ctx)
+			s.pruneMassSlashings(ctx)
+		}
+	}
+}
+
+// updateSyncStatus fetches and stores the current sync status of the beacon node.
+func (s *Service) updateSyncStatus(ctx context.Context) {
+	status, err := s.fetchSyncStatus(ctx)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("Failed to obtain sync status; treating node as unhealthy")
+		status = &syncStatus{
+			isSyncing: true,
+		}
+	}
+
+	s.syncStatusMu.Lock()
+	previous := s.syncStatus
+	s.syncStatus = status
+	s.syncStatusMu.Unlock()
+
+	if previous == nil || previous.healthy() != status.healthy() {
+		if status.healthy() {
+			s.log.Info().Msg("Beacon node is healthy")
+		} else {
+			s.log.Warn().
+				Bool("is_syncing", status.isSyncing).
+				Bool("is_optimistic", status.isOptimistic).
+				Bool("el_offline", status.executionClientOffline()).
+				Msg("Beacon node is not healthy; slashings from optimistic blocks will be held")
+		}
+	}
+	setSyncStatus(ctx, status)
+	s.updateReadiness(ctx)
+}
+
+// fetchSyncStatus fetches the current sync status of the beacon node.
+func (s *Service) fetchSyncStatus(ctx context.Context) (*syncStatus, error) {
+	provider, isProvider := s.eth2Client.(eth2client.NodeSyncingProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide sync state")
+	}
+	response, err := provider.NodeSyncing(ctx, &api.NodeSyncingOpts{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain sync state")
+	}
+
+	status := &syncStatus{
+		connected:    true,
+		headSlot:     response.Data.HeadSlot,
+		isSyncing:    response.Data.IsSyncing,
+		isOptimistic: response.Data.IsOptimistic,
+	}
+
+	// The client does not decode el_offline, so obtain it from the node separately.  If
+	// it cannot be obtained then the state of the execution client is unknown.
+	elOffline, err := s.fetchELOffline(ctx)
+	if err != nil {
+		s.log.Debug().Err(err).Msg("Failed to obtain state of execution client; treating as unknown")
+	} else {
+		status.elOffline = elOffline
+	}
+
+	return status, nil
+}
+
+// fetchELOffline fetches the el_offline flag of the sync state from the beacon node at
+// the address of the client, including any credentials in the address.  It returns nil
+// if the node does not report the flag.  The request is bounded by the sync check
+// interval, so that a slow node cannot hold up the sync monitor.
+func (s *Service) fetchELOffline(ctx context.Context) (*bool, error) {
+	address := strings.TrimSuffix(s.eth2Client.Address(), "/")
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		address = fmt.Sprintf("http://%s", address)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.syncCheckInterval)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/eth/v1/node/syncing", address), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.syncClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to call syncing endpoint")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("syncing endpoint returned status %d", resp.StatusCode)
+	}
+
+	res := struct {
+		Data struct {
+			ELOffline *bool `json:"el_offline"`
+		} `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, errors.Wrap(err, "failed to decode syncing response")
+	}
+
+	return res.Data.ELOffline, nil
+}
+
+// nodeOptimistic returns true if the beacon node cannot currently validate the blocks it serves.
+func (s *Service) nodeOptimistic() bool {
+	s.syncStatusMu.RLock()
+	defer s.syncStatusMu.RUnlock()
+
+	if s.syncStatus == nil {
+		return false
+	}
+
+	return s.syncStatus.isOptimistic || s.syncStatus.executionClientOffline()
+}
+
+// nodeHealthy returns true if the beacon node is known to be healthy.
+func (s *Service) nodeHealthy() bool {
+	s.syncStatusMu.RLock()
+	defer s.syncStatusMu.RUnlock()
+
+	return s.syncStatus != nil && s.syncStatus.healthy()
+}
+
+// updateReadiness informs the readiness handler of the current readiness of the service.
+func (s *Service) updateReadiness(ctx context.Context) {
+	if s.readinessHandler != nil {
+		s.readinessHandler(ctx, s.nodeHealthy() && !s.streamStale())
+	}
+}
+
+// executionOptimistic returns true if the response metadata marks the data as optimistic.
+func executionOptimistic(metadata map[string]any) bool {
+	for k, v := range metadata {
+		if !strings.EqualFold(k, "execution_optimistic") &&
+			!strings.EqualFold(k, "Eth-Execution-Optimistic") {
+			continue
+		}
+		switch val := v.(type) {
+		case bool:
+			return val
+		case string:
+			return strings.EqualFold(val, "true")
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,124 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchELOffline(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		elOffline *bool
+		err       string
+	}{
+		{
+			name:      "Offline",
+			status:    http.StatusOK,
+			body:      `{"data":{"head_slot":"1","sync_distance":"0","is_syncing":false,"is_optimistic":true,"el_offline":true}}`,
+			elOffline: boolPtr(true),
+		},
+		{
+			name:      "Online",
+			status:    http.StatusOK,
+			body:      `{"data":{"head_slot":"1","sync_distance":"0","is_syncing":false,"is_optimistic":false,"el_offline":false}}`,
+			elOffline: boolPtr(false),
+		},
+		{
+			name:   "Absent",
+			status: http.StatusOK,
+			body:   `{"data":{"head_slot":"1","sync_distance":"0","is_syncing":false}}`,
+		},
+		{
+			name:   "ServerError",
+			status: http.StatusInternalServerError,
+			body:   `{"code":500,"message":"internal error"}`,
+			err:    "syncing endpoint returned status 500",
+		},
+		{
+			name:   "BadJSON",
+			status: http.StatusOK,
+			body:   `{"data":`,
+			err:    "failed to decode syncing response: unexpected EOF",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/eth/v1/node/syncing", r.URL.Path)
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			s := &Service{
+				eth2Client:        &mockClient{address: strings.TrimPrefix(server.URL, "http://")},
+				syncClient:        &http.Client{},
+				syncCheckInterval: time.Second,
+			}
+			elOffline, err := s.fetchELOffline(context.Background())
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.elOffline, elOffline)
+		})
+	}
+}
+
+func TestSyncStatusHealthy(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  *syncStatus
+		healthy bool
+	}{
+		{
+			name:    "Healthy",
+			status:  &syncStatus{connected: true, elOffline: boolPtr(false)},
+			healthy: true,
+		},
+		{
+			name:    "ELUnknown",
+			status:  &syncStatus{connected: true},
+			healthy: true,
+		},
+		{
+			name:    "ELOffline",
+			status:  &syncStatus{connected: true, elOffline: boolPtr(true)},
+			healthy: false,
+		},
+		{
+			name:    "Optimistic",
+			status:  &syncStatus{connected: true, isOptimistic: true},
+			healthy: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.healthy, test.status.healthy())
+		})
+	}
+}
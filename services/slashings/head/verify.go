@@ -0,0 +1,406 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/attestantio/esd/services/slashings"
+	eth2client "github.com/attestantio/go-eth2-client"
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+)
+
+// chainInfo is the information about the chain required to verify signatures.
+type chainInfo struct {
+	genesisValidatorsRoot phase0.Root
+	forks                 []*phase0.Fork
+	attesterDomainType    phase0.DomainType
+	proposerDomainType    phase0.DomainType
+}
+
+// verificationError is evidence that has been checked and found to be invalid.
+type verificationError struct {
+	reason string
+}
+
+func (e *verificationError) Error() string {
+	return e.reason
+}
+
+// invalid returns an error for evidence that is invalid.
+func invalid(format string, args ...any) error {
+	return &verificationError{reason: fmt.Sprintf(format, args...)}
+}
+
+// verifySlashings verifies the evidence of the offences in the block, marking each offence
+// with the result.
+func (s *Service) verifySlashings(ctx context.Context, block *blockSlashings) {
+	if !s.verify {
+		return
+	}
+
+	// The same evidence can implicate many validators, so only verify it once.
+	results := make(map[phase0.Root]error)
+	for _, event := range block.events {
+		for _, offence := range event.Offences {
+			err, exists := results[offence.EvidenceHash]
+			if !exists {
+				err = s.verifyOffence(ctx, offence)
+				results[offence.EvidenceHash] = err
+			}
+
+			var verificationErr *verificationError
+			switch {
+			case err == nil:
+				offence.Verification = slashings.VerificationVerified
+			case errors.As(err, &verificationErr):
+				offence.Verification = slashings.VerificationFailed
+				offence.VerificationError = err.Error()
+			default:
+				offence.Verification = slashings.VerificationIncomplete
+				offence.VerificationError = err.Error()
+			}
+		}
+	}
+
+	for _, event := range block.events {
+		if event.Unverified() {
+			s.log.Warn().
+				Uint64("slot", uint64(event.Slot)).
+				Uint64("validator_index", uint64(event.ValidatorIndex)).
+				Strs("errors", verificationErrors(event.Offences)).
+				Msg("Slashing evidence could not be verified; actions will not be run")
+			slashingUnverified(ctx)
+		}
+	}
+}
+
+// verifyOffence verifies the evidence for an offence.  It returns a *verificationError if
+// the evidence is invalid, or another error if it could not be verified.
+func (s *Service) verifyOffence(ctx context.Context, offence *slashings.Offence) error {
+	info, err := s.chainInfo(ctx)
+	if err != nil {
+		return err
+	}
+
+	if offence.Kind == slashings.OffenceDoubleProposal {
+		return s.verifyProposerOffence(ctx, info, offence)
+	}
+
+	return s.verifyAttesterOffence(ctx, info, offence)
+}
+
+// verifyAttesterOffence verifies the evidence for an attester offence.
+func (s *Service) verifyAttesterOffence(ctx context.Context, info *chainInfo, offence *slashings.Offence) error {
+	attestation1 := offence.Attestation1
+	attestation2 := offence.Attestation2
+	if attestation1 == nil || attestation1.Data == nil || attestation2 == nil || attestation2.Data == nil {
+		return invalid("attestations missing")
+	}
+
+	// Check that the attestation data is slashable, as per is_slashable_attestation_data.
+	root1, err := attestation1.Data.HashTreeRoot()
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain root of attestation 1")
+	}
+	root2, err := attestation2.Data.HashTreeRoot()
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain root of attestation 2")
+	}
+	data1 := attestation1.Data
+	data2 := attestation2.Data
+	doubleVote := root1 != root2 && data1.Target.Epoch == data2.Target.Epoch
+	surroundVote := data1.Source.Epoch < data2.Source.Epoch && data2.Target.Epoch < data1.Target.Epoch
+	if !doubleVote && !surroundVote {
+		return invalid("attestation data is not slashable")
+	}
+
+	for i, attestation := range []*phase0.IndexedAttestation{attestation1, attestation2} {
+		if err := s.verifyAttestation(ctx, info, attestation); err != nil {
+			var verificationErr *verificationError
+			if errors.As(err, &verificationErr) {
+				return invalid("attestation %d: %v", i+1, err)
+			}
+
+			return errors.Wrap(err, fmt.Sprintf("attestation %d", i+1))
+		}
+	}
+
+	return nil
+}
+
+// verifyAttestation verifies the indices and signature of an indexed attestation, as per
+// is_valid_indexed_attestation.
+func (s *Service) verifyAttestation(ctx context.Context, info *chainInfo, attestation *phase0.IndexedAttestation) error {
+	if len(attestation.AttestingIndices) == 0 {
+		return invalid("no attesting indices")
+	}
+	indices := make([]phase0.ValidatorIndex, 0, len(attestation.AttestingIndices))
+	for i, index := range attestation.AttestingIndices {
+		if i > 0 && index <= attestation.AttestingIndices[i-1] {
+			return invalid("attesting indices not sorted and unique")
+		}
+		indices = append(indices, phase0.ValidatorIndex(index))
+	}
+
+	pubKeys, err := s.blsPubKeys(ctx, indices)
+	if err != nil {
+		return err
+	}
+
+	domain, err := computeDomain(info, info.attesterDomainType, attestation.Data.Target.Epoch)
+	if err != nil {
+		return err
+	}
+	signingRoot, err := computeSigningRoot(attestation.Data, domain)
+	if err != nil {
+		return err
+	}
+	signature, err := blsSignature(attestation.Signature)
+	if err != nil {
+		return invalid("invalid signature: %v", err)
+	}
+	if !signature.VerifyAggregateCommon(signingRoot[:], pubKeys) {
+		return invalid("signature does not verify")
+	}
+
+	return nil
+}
+
+// verifyProposerOffence verifies the evidence for a proposer offence.
+func (s *Service) verifyProposerOffence(ctx context.Context, info *chainInfo, offence *slashings.Offence) error {
+	header1 := offence.Header1
+	header2 := offence.Header2
+	if header1 == nil || header1.Message == nil || header2 == nil || header2.Message == nil {
+		return invalid("headers missing")
+	}
+
+	// Check that the headers are slashable, as per process_proposer_slashing.
+	if header1.Message.Slot != header2.Message.Slot {
+		return invalid("headers are for different slots")
+	}
+	if header1.Message.ProposerIndex != header2.Message.ProposerIndex {
+		return invalid("headers are from different proposers")
+	}
+	root1, err := header1.Message.HashTreeRoot()
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain root of header 1")
+	}
+	root2, err := header2.Message.HashTreeRoot()
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain root of header 2")
+	}
+	if root1 == root2 {
+		return invalid("headers are identical")
+	}
+
+	pubKeys, err := s.blsPubKeys(ctx, []phase0.ValidatorIndex{header1.Message.ProposerIndex})
+	if err != nil {
+		return err
+	}
+	domain, err := computeDomain(info, info.proposerDomainType, s.chainTime.SlotToEpoch(header1.Message.Slot))
+	if err != nil {
+		return err
+	}
+
+	for i, header := range []*phase0.SignedBeaconBlockHeader{header1, header2} {
+		signingRoot, err := computeSigningRoot(header.Message, domain)
+		if err != nil {
+			return err
+		}
+		signature, err := blsSignature(header.Signature)
+		if err != nil {
+			return invalid("header %d: invalid signature: %v", i+1, err)
+		}
+		if !signature.Verify(signingRoot[:], pubKeys[0]) {
+			return invalid("header %d: signature does not verify", i+1)
+		}
+	}
+
+	return nil
+}
+
+// blsPubKeys returns the BLS public keys for the given validators, in the same order.
+func (s *Service) blsPubKeys(ctx context.Context, indices []phase0.ValidatorIndex) ([]e2types.PublicKey, error) {
+	pubKeys, err := s.validatorPubKeys(ctx, indices)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]e2types.PublicKey, 0, len(indices))
+	for _, index := range indices {
+		pubKey, exists := pubKeys[index]
+		if !exists {
+			return nil, invalid("unknown validator %d", index)
+		}
+		blsPubKey, err := e2types.BLSPublicKeyFromBytes(pubKey[:])
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid public key for validator %d", index))
+		}
+		res = append(res, blsPubKey)
+	}
+
+	return res, nil
+}
+
+// blsSignature returns the BLS signature for the given signature.
+func blsSignature(signature phase0.BLSSignature) (e2types.Signature, error) {
+	// Copy the signature, as the BLS library cannot be passed memory that is
+	// part of a structure containing pointers.
+	data := make([]byte, len(signature))
+	copy(data, signature[:])
+
+	return e2types.BLSSignatureFromBytes(data)
+}
+
+// chainInfo returns the information about the chain required to verify signatures,
+// fetching it from the beacon node if not already known.
+func (s *Service) chainInfo(ctx context.Context) (*chainInfo, error) {
+	s.chainInfoMu.Lock()
+	defer s.chainInfoMu.Unlock()
+
+	if s.chainInfoCache != nil {
+		return s.chainInfoCache, nil
+	}
+
+	genesisProvider, isProvider := s.eth2Client.(eth2client.GenesisProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide genesis")
+	}
+	genesisResponse, err := genesisProvider.Genesis(ctx, &api.GenesisOpts{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain genesis")
+	}
+
+	forkScheduleProvider, isProvider := s.eth2Client.(eth2client.ForkScheduleProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide fork schedule")
+	}
+	forkScheduleResponse, err := forkScheduleProvider.ForkSchedule(ctx, &api.ForkScheduleOpts{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain fork schedule")
+	}
+	if len(forkScheduleResponse.Data) == 0 {
+		return nil, errors.New("empty fork schedule")
+	}
+	forks := make([]*phase0.Fork, len(forkScheduleResponse.Data))
+	copy(forks, forkScheduleResponse.Data)
+	sort.Slice(forks, func(i, j int) bool { return forks[i].Epoch < forks[j].Epoch })
+
+	specProvider, isProvider := s.eth2Client.(eth2client.SpecProvider)
+	if !isProvider {
+		return nil, errors.New("client does not provide spec")
+	}
+	specResponse, err := specProvider.Spec(ctx, &api.SpecOpts{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain spec")
+	}
+	attesterDomainType, err := specDomainType(specResponse.Data, "DOMAIN_BEACON_ATTESTER")
+	if err != nil {
+		return nil, err
+	}
+	proposerDomainType, err := specDomainType(specResponse.Data, "DOMAIN_BEACON_PROPOSER")
+	if err != nil {
+		return nil, err
+	}
+
+	s.chainInfoCache = &chainInfo{
+		genesisValidatorsRoot: genesisResponse.Data.GenesisValidatorsRoot,
+		forks:                 forks,
+		attesterDomainType:    attesterDomainType,
+		proposerDomainType:    proposerDomainType,
+	}
+
+	return s.chainInfoCache, nil
+}
+
+// specDomainType returns the named domain type from the spec.
+func specDomainType(spec map[string]any, name string) (phase0.DomainType, error) {
+	tmp, exists := spec[name]
+	if !exists {
+		return phase0.DomainType{}, fmt.Errorf("%s not found in spec", name)
+	}
+	domainType, isDomainType := tmp.(phase0.DomainType)
+	if !isDomainType {
+		return phase0.DomainType{}, fmt.Errorf("%s of unexpected type", name)
+	}
+
+	return domainType, nil
+}
+
+// computeDomain computes the signature domain for the given domain type at the given epoch.
+func computeDomain(info *chainInfo, domainType phase0.DomainType, epoch phase0.Epoch) (phase0.Domain, error) {
+	forkVersion := info.forks[0].CurrentVersion
+	for _, fork := range info.forks {
+		if fork.Epoch > epoch {
+			break
+		}
+		forkVersion = fork.CurrentVersion
+	}
+
+	forkData := &phase0.ForkData{
+		CurrentVersion:        forkVersion,
+		GenesisValidatorsRoot: info.genesisValidatorsRoot,
+	}
+	forkDataRoot, err := forkData.HashTreeRoot()
+	if err != nil {
+		return phase0.Domain{}, errors.Wrap(err, "failed to obtain fork data root")
+	}
+
+	var domain phase0.Domain
+	copy(domain[:], domainType[:])
+	copy(domain[4:], forkDataRoot[:28])
+
+	return domain, nil
+}
+
+// hashTreeRooter is an object that can provide its hash tree root.
+type hashTreeRooter interface {
+	HashTreeRoot() ([32]byte, error)
+}
+
+// computeSigningRoot computes the root that is signed for the given object and domain.
+func computeSigningRoot(object hashTreeRooter, domain phase0.Domain) (phase0.Root, error) {
+	objectRoot, err := object.HashTreeRoot()
+	if err != nil {
+		return phase0.Root{}, errors.Wrap(err, "failed to obtain object root")
+	}
+	signingData := &phase0.SigningData{
+		ObjectRoot: objectRoot,
+		Domain:     domain,
+	}
+	signingRoot, err := signingData.HashTreeRoot()
+	if err != nil {
+		return phase0.Root{}, errors.Wrap(err, "failed to obtain signing root")
+	}
+
+	return signingRoot, nil
+}
+
+// verificationErrors returns the verification errors of the offences.
+func verificationErrors(offences []*slashings.Offence) []string {
+	res := make([]string, 0, len(offences))
+	for _, offence := range offences {
+		if offence.VerificationError != "" {
+			res = append(res, fmt.Sprintf("%s: %s", offence.Kind, offence.VerificationError))
+		}
+	}
+
+	return res
+}
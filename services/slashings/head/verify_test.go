@@ -0,0 +1,274 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"testing"
+
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeDomain(t *testing.T) {
+	info := &chainInfo{
+		genesisValidatorsRoot: phase0.Root{0x01, 0x02},
+		forks: []*phase0.Fork{
+			{CurrentVersion: phase0.Version{0x00}, Epoch: 0},
+			{CurrentVersion: phase0.Version{0x01}, Epoch: 10},
+			{CurrentVersion: phase0.Version{0x02}, Epoch: 20},
+		},
+	}
+	domainType := phase0.DomainType{0x01, 0x00, 0x00, 0x00}
+
+	tests := []struct {
+		name    string
+		epoch   phase0.Epoch
+		version phase0.Version
+	}{
+		{
+			name:    "Genesis",
+			epoch:   0,
+			version: phase0.Version{0x00},
+		},
+		{
+			name:    "BeforeFork",
+			epoch:   9,
+			version: phase0.Version{0x00},
+		},
+		{
+			name:    "AtFork",
+			epoch:   10,
+			version: phase0.Version{0x01},
+		},
+		{
+			name:    "BetweenForks",
+			epoch:   15,
+			version: phase0.Version{0x01},
+		},
+		{
+			name:    "LastFork",
+			epoch:   20,
+			version: phase0.Version{0x02},
+		},
+		{
+			name:    "FarFuture",
+			epoch:   0xffffffffffffffff,
+			version: phase0.Version{0x02},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			domain, err := computeDomain(info, domainType, test.epoch)
+			require.NoError(t, err)
+
+			forkDataRoot, err := (&phase0.ForkData{
+				CurrentVersion:        test.version,
+				GenesisValidatorsRoot: info.genesisValidatorsRoot,
+			}).HashTreeRoot()
+			require.NoError(t, err)
+			require.Equal(t, domainType[:], domain[:4])
+			require.Equal(t, forkDataRoot[:28], domain[4:])
+		})
+	}
+}
+
+func TestComputeDomainGenesisValidatorsRoot(t *testing.T) {
+	forks := []*phase0.Fork{{CurrentVersion: phase0.Version{0x00}, Epoch: 0}}
+	domainType := phase0.DomainType{0x00, 0x00, 0x00, 0x00}
+
+	domain1, err := computeDomain(&chainInfo{genesisValidatorsRoot: phase0.Root{0x01}, forks: forks}, domainType, 0)
+	require.NoError(t, err)
+	domain2, err := computeDomain(&chainInfo{genesisValidatorsRoot: phase0.Root{0x02}, forks: forks}, domainType, 0)
+	require.NoError(t, err)
+	require.NotEqual(t, domain1, domain2)
+}
+
+func TestVerifyAttestationIndices(t *testing.T) {
+	tests := []struct {
+		name    string
+		indices []uint64
+		err     string
+	}{
+		{
+			name:    "Empty",
+			indices: []uint64{},
+			err:     "no attesting indices",
+		},
+		{
+			name:    "Unsorted",
+			indices: []uint64{1, 3, 2},
+			err:     "attesting indices not sorted and unique",
+		},
+		{
+			name:    "Duplicate",
+			indices: []uint64{1, 2, 2, 3},
+			err:     "attesting indices not sorted and unique",
+		},
+	}
+
+	s := &Service{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.verifyAttestation(context.Background(), &chainInfo{}, &phase0.IndexedAttestation{
+				AttestingIndices: test.indices,
+				Data:             testAttestationData(1, 2, 0x01),
+			})
+			requireInvalid(t, err, test.err)
+		})
+	}
+}
+
+func TestVerifyAttesterOffenceSlashable(t *testing.T) {
+	tests := []struct {
+		name    string
+		offence *slashings.Offence
+		err     string
+	}{
+		{
+			name:    "Missing",
+			offence: &slashings.Offence{Kind: slashings.OffenceDoubleVote},
+			err:     "attestations missing",
+		},
+		{
+			name: "Identical",
+			offence: &slashings.Offence{
+				Kind:         slashings.OffenceDoubleVote,
+				Attestation1: &phase0.IndexedAttestation{Data: testAttestationData(1, 2, 0x01)},
+				Attestation2: &phase0.IndexedAttestation{Data: testAttestationData(1, 2, 0x01)},
+			},
+			err: "attestation data is not slashable",
+		},
+		{
+			name: "NotConflicting",
+			offence: &slashings.Offence{
+				Kind:         slashings.OffenceUnknown,
+				Attestation1: &phase0.IndexedAttestation{Data: testAttestationData(1, 2, 0x01)},
+				Attestation2: &phase0.IndexedAttestation{Data: testAttestationData(2, 3, 0x02)},
+			},
+			err: "attestation data is not slashable",
+		},
+		{
+			// Only attestation 1 surrounding attestation 2 is slashable.
+			name: "Attestation2Surrounds",
+			offence: &slashings.Offence{
+				Kind:         slashings.OffenceSurroundVote,
+				Attestation1: &phase0.IndexedAttestation{Data: testAttestationData(2, 3, 0x01)},
+				Attestation2: &phase0.IndexedAttestation{Data: testAttestationData(1, 4, 0x02)},
+			},
+			err: "attestation data is not slashable",
+		},
+		{
+			name: "DoubleVoteNoIndices",
+			offence: &slashings.Offence{
+				Kind:         slashings.OffenceDoubleVote,
+				Attestation1: &phase0.IndexedAttestation{Data: testAttestationData(1, 2, 0x01)},
+				Attestation2: &phase0.IndexedAttestation{Data: testAttestationData(1, 2, 0x02)},
+			},
+			err: "attestation 1: no attesting indices",
+		},
+		{
+			name: "SurroundVoteUnsortedIndices",
+			offence: &slashings.Offence{
+				Kind:         slashings.OffenceSurroundVote,
+				Attestation1: &phase0.IndexedAttestation{Data: testAttestationData(1, 4, 0x01), AttestingIndices: []uint64{2, 1}},
+				Attestation2: &phase0.IndexedAttestation{Data: testAttestationData(2, 3, 0x02)},
+			},
+			err: "attestation 1: attesting indices not sorted and unique",
+		},
+	}
+
+	s := &Service{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.verifyAttesterOffence(context.Background(), &chainInfo{}, test.offence)
+			requireInvalid(t, err, test.err)
+		})
+	}
+}
+
+func TestVerifyProposerOffenceSlashable(t *testing.T) {
+	header := func(slot phase0.Slot, proposerIndex phase0.ValidatorIndex, root byte) *phase0.SignedBeaconBlockHeader {
+		return &phase0.SignedBeaconBlockHeader{
+			Message: &phase0.BeaconBlockHeader{
+				Slot:          slot,
+				ProposerIndex: proposerIndex,
+				BodyRoot:      phase0.Root{root},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		header1 *phase0.SignedBeaconBlockHeader
+		header2 *phase0.SignedBeaconBlockHeader
+		err     string
+	}{
+		{
+			name: "Missing",
+			err:  "headers missing",
+		},
+		{
+			name:    "DifferentSlots",
+			header1: header(1, 2, 0x01),
+			header2: header(2, 2, 0x02),
+			err:     "headers are for different slots",
+		},
+		{
+			name:    "DifferentProposers",
+			header1: header(1, 2, 0x01),
+			header2: header(1, 3, 0x02),
+			err:     "headers are from different proposers",
+		},
+		{
+			name:    "Identical",
+			header1: header(1, 2, 0x01),
+			header2: header(1, 2, 0x01),
+			err:     "headers are identical",
+		},
+	}
+
+	s := &Service{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.verifyProposerOffence(context.Background(), &chainInfo{}, &slashings.Offence{
+				Kind:    slashings.OffenceDoubleProposal,
+				Header1: test.header1,
+				Header2: test.header2,
+			})
+			requireInvalid(t, err, test.err)
+		})
+	}
+}
+
+// requireInvalid requires that the error marks the evidence as invalid, with the given reason.
+func requireInvalid(t *testing.T, err error, reason string) {
+	t.Helper()
+
+	var verificationErr *verificationError
+	require.True(t, errors.As(err, &verificationErr), "expected verification error, got %v", err)
+	require.EqualError(t, err, reason)
+}
+
+// testAttestationData returns attestation data with the given source and target epochs.
+func testAttestationData(source phase0.Epoch, target phase0.Epoch, root byte) *phase0.AttestationData {
+	return &phase0.AttestationData{
+		BeaconBlockRoot: phase0.Root{root},
+		Source:          &phase0.Checkpoint{Epoch: source},
+		Target:          &phase0.Checkpoint{Epoch: target, Root: phase0.Root{root}},
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+)
+
+// headReceived records the receipt of a head event.
+func (s *Service) headReceived(ctx context.Context, slot phase0.Slot) {
+	s.headMu.Lock()
+	if slot > s.lastHeadSlot {
+		s.lastHeadSlot = slot
+	}
+	if slot > s.lastQueuedSlot {
+		s.lastQueuedSlot = slot
+	}
+	wasStale := s.stale
+	s.stale = false
+	s.headMu.Unlock()
+
+	setHeadLag(ctx, s.headLag(slot))
+
+	if wasStale {
+		s.log.Info().Uint64("slot", uint64(slot)).Msg("Head events resumed")
+		s.updateReadiness(ctx)
+	}
+}
+
+// watchdog checks the event stream halfway through each slot, and resubscribes if it is stale.
+func (s *Service) watchdog(ctx context.Context) {
+	for {
+		nextCheck := s.chainTime.StartOfSlot(s.chainTime.CurrentSlot() + 1).Add(s.chainTime.SlotDuration() / 2)
+		select {
+		case <-ctx.Done():
+			s.log.Trace().Msg("Context done; stopping watchdog")
+			return
+		case <-time.After(time.Until(nextCheck)):
+			s.checkEventStream(ctx)
+		}
+	}
+}
+
+// checkEventStream checks if the event stream is stale and, if so, resubscribes and backfills.
+func (s *Service) checkEventStream(ctx context.Context) {
+	if s.isDraining() {
+		// Head events are no longer wanted.
+		return
+	}
+
+	s.headMu.Lock()
+	lastHeadSlot := s.lastHeadSlot
+	lastResubscribeSlot := s.lastResubscribeSlot
+	s.headMu.Unlock()
+
+	lag := s.headLag(lastHeadSlot)
+	setHeadLag(ctx, lag)
+	if uint64(lag) < s.staleSlots {
+		return
+	}
+	if uint64(s.headLag(lastResubscribeSlot)) < s.staleSlots {
+		// Give the previous resubscription time to deliver events.
+		return
+	}
+
+	// Take the start of the backfill before resubscribing, as head events received once
+	// resubscribed move the last queued slot past the blocks that were missed.
+	s.headMu.Lock()
+	wasStale := s.stale
+	s.stale = true
+	s.lastResubscribeSlot = s.chainTime.CurrentSlot()
+	fromSlot := s.lastQueuedSlot + 1
+	s.headMu.Unlock()
+	if !wasStale {
+		s.updateReadiness(ctx)
+	}
+
+	s.log.Warn().
+		Uint64("last_head_slot", uint64(lastHeadSlot)).
+		Uint64("lag", uint64(lag)).
+		Msg("No head events received; resubscribing")
+	streamResubscribed(ctx)
+	if err := s.subscribe(ctx); err != nil {
+		s.log.Error().Err(err).Msg("Failed to resubscribe to head events")
+		return
+	}
+
+	if err := s.backfill(ctx, fromSlot); err != nil {
+		s.log.Warn().Err(err).Msg("Failed to backfill blocks")
+	}
+}
+
+// backfill queues the blocks between the given slot and the beacon node's head.
+func (s *Service) backfill(ctx context.Context, fromSlot phase0.Slot) error {
+	status, err := s.fetchSyncStatus(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain beacon node head")
+	}
+
+	toSlot := status.headSlot
+	if fromSlot > toSlot {
+		return nil
+	}
+
+	s.log.Info().Uint64("from_slot", uint64(fromSlot)).Uint64("to_slot", uint64(toSlot)).Msg("Backfilling blocks")
+	for slot := fromSlot; slot <= toSlot; slot++ {
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "backfill stopped")
+		}
+		s.enqueueBlock(ctx, slot, fmt.Sprintf("%d", slot), true)
+	}
+	s.blockQueueMu.Lock()
+	backfillSeq := s.nextSeq
+	s.blockQueueMu.Unlock()
+
+	s.headMu.Lock()
+	if toSlot > s.lastQueuedSlot {
+		s.lastQueuedSlot = toSlot
+	}
+	// The backfill is complete once the pipeline has handled every block queued so far.
+	s.backfillSeq = backfillSeq
+	s.headMu.Unlock()
+
+	return nil
+}
+
+// headLag returns the number of slots between the given slot and the current slot.
+func (s *Service) headLag(slot phase0.Slot) phase0.Slot {
+	currentSlot := s.chainTime.CurrentSlot()
+	if slot >= currentSlot {
+		return 0
+	}
+
+	return currentSlot - slot
+}
+
+// streamStale returns true if the event stream is stale.
+func (s *Service) streamStale() bool {
+	s.headMu.Lock()
+	defer s.headMu.Unlock()
+
+	return s.stale
+}
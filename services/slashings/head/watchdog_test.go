@@ -0,0 +1,99 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package head
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckEventStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		lastSlot   phase0.Slot
+		headEvents []*apiv1.HeadEvent
+		head       []phase0.Slot
+		backfill   []phase0.Slot
+		lastQueued phase0.Slot
+	}{
+		{
+			name:       "Fresh",
+			lastSlot:   108,
+			head:       []phase0.Slot{},
+			backfill:   []phase0.Slot{},
+			lastQueued: 108,
+		},
+		{
+			name:       "Gap",
+			lastSlot:   100,
+			head:       []phase0.Slot{},
+			backfill:   []phase0.Slot{101, 102, 103, 104, 105, 106, 107, 108, 109, 110},
+			lastQueued: 110,
+		},
+		{
+			name:     "GapWithHeadEvent",
+			lastSlot: 100,
+			headEvents: []*apiv1.HeadEvent{
+				{Slot: 110, Block: phase0.Root{0x01}},
+			},
+			head:       []phase0.Slot{110},
+			backfill:   []phase0.Slot{101, 102, 103, 104, 105, 106, 107, 108, 109, 110},
+			lastQueued: 110,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := &Service{
+				log: zerolog.Nop(),
+				eth2Client: &mockClient{
+					headSlot:   110,
+					headEvents: test.headEvents,
+				},
+				chainTime:         &mockChainTime{slot: 110},
+				syncClient:        &http.Client{},
+				syncCheckInterval: time.Second,
+				staleSlots:        4,
+				lastHeadSlot:      test.lastSlot,
+				lastQueuedSlot:    test.lastSlot,
+				inFlightBlocks:    make(map[phase0.Slot]int),
+				blockQueue:        make(chan *blockItem, 64),
+			}
+
+			s.checkEventStream(ctx)
+
+			head := make([]phase0.Slot, 0)
+			backfill := make([]phase0.Slot, 0)
+			close(s.blockQueue)
+			for item := range s.blockQueue {
+				if item.backfill {
+					backfill = append(backfill, item.slot)
+				} else {
+					head = append(head, item.slot)
+				}
+			}
+			require.Equal(t, test.head, head)
+			require.Equal(t, test.backfill, backfill)
+			require.Equal(t, test.lastQueued, s.lastQueuedSlot)
+		})
+	}
+}
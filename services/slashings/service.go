@@ -1,4 +1,4 @@
-// Copyright © 2021 Attestant Limited.
+// Copyright © 2021, 2024 Attestant Limited.
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
 // You may obtain a copy of the License at
@@ -16,12 +16,367 @@ package slashings
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	spec "github.com/attestantio/go-eth2-client/spec/phase0"
 )
 
+// Type is the type of a slashing.
+type Type string
+
+const (
+	// TypeAttester is an attester slashing.
+	TypeAttester Type = "attester"
+	// TypeProposer is a proposer slashing.
+	TypeProposer Type = "proposer"
+)
+
+// OffenceKind is the kind of offence for which a validator is slashed.
+type OffenceKind string
+
+const (
+	// OffenceDoubleVote is two different attestations for the same target epoch.
+	OffenceDoubleVote OffenceKind = "double_vote"
+	// OffenceSurroundVote is an attestation that surrounds another.
+	OffenceSurroundVote OffenceKind = "surround_vote"
+	// OffenceDoubleProposal is two different blocks proposed for the same slot.
+	OffenceDoubleProposal OffenceKind = "double_proposal"
+	// OffenceUnknown is a pair of attestations that neither double vote nor surround.
+	OffenceUnknown OffenceKind = "unknown"
+)
+
+// Surrounding is the attestation of a surround vote that surrounds the other.
+type Surrounding string
+
+const (
+	// SurroundingAttestation1 is the first attestation surrounding the second.
+	SurroundingAttestation1 Surrounding = "attestation_1"
+	// SurroundingAttestation2 is the second attestation surrounding the first.
+	SurroundingAttestation2 Surrounding = "attestation_2"
+)
+
+// Source is the means by which a slashing was detected.
+type Source string
+
+const (
+	// SourceHead is a slashing found in a block announced by a head event.
+	SourceHead Source = "head"
+	// SourceBackfill is a slashing found in a block fetched to fill a gap in head events.
+	SourceBackfill Source = "backfill"
+	// SourceRetry is a slashing found in a block that previously failed to be fetched.
+	SourceRetry Source = "retry"
+	// SourceResume is a slashing whose actions were resumed after a restart.
+	SourceResume Source = "resume"
+	// SourceBlock is a slashing found in a block requested explicitly.
+	SourceBlock Source = "block"
+	// SourceDrill is a synthetic slashing created for a drill.
+	SourceDrill Source = "drill"
+)
+
+// FinalityStatus is the finality status of the block that included a slashing.
+type FinalityStatus string
+
+const (
+	// StatusPending is a slashing in a block that is not yet finalized.
+	StatusPending FinalityStatus = "pending"
+	// StatusFinalized is a slashing in a finalized block.
+	StatusFinalized FinalityStatus = "finalized"
+	// StatusOrphaned is a slashing in a block that is not part of the finalized chain.
+	StatusOrphaned FinalityStatus = "orphaned"
+)
+
+// Verification is the result of verifying the evidence for an offence.
+type Verification string
+
+const (
+	// VerificationVerified is evidence that is slashable and correctly signed.
+	VerificationVerified Verification = "verified"
+	// VerificationFailed is evidence that is not slashable or not correctly signed.
+	VerificationFailed Verification = "failed"
+	// VerificationIncomplete is evidence that could not be verified, for example
+	// because the beacon node did not supply the information required.
+	VerificationIncomplete Verification = "incomplete"
+)
+
+// Offence is a single piece of evidence against a validator.
+type Offence struct {
+	// Kind is the kind of offence.
+	Kind OffenceKind `json:"kind"`
+	// Surrounding is the attestation that surrounds the other, for a surround vote.
+	Surrounding Surrounding `json:"surrounding,omitempty"`
+	// EvidenceHash is the hash tree root of the attester or proposer slashing.
+	EvidenceHash spec.Root `json:"evidence_hash"`
+	// Attestation1 is the first conflicting attestation of an attester offence.
+	Attestation1 *spec.IndexedAttestation `json:"attestation_1,omitempty"`
+	// Attestation2 is the second conflicting attestation of an attester offence.
+	Attestation2 *spec.IndexedAttestation `json:"attestation_2,omitempty"`
+	// Header1 is the first conflicting block header of a proposer offence.
+	Header1 *spec.SignedBeaconBlockHeader `json:"header_1,omitempty"`
+	// Header2 is the second conflicting block header of a proposer offence.
+	Header2 *spec.SignedBeaconBlockHeader `json:"header_2,omitempty"`
+	// Verification is the result of verifying the evidence, if it has been verified.
+	Verification Verification `json:"verification,omitempty"`
+	// VerificationError is the reason the evidence was not verified.
+	VerificationError string `json:"verification_error,omitempty"`
+}
+
+// Type returns the type of the slashing that contains the offence.
+func (o *Offence) Type() Type {
+	if o.Kind == OffenceDoubleProposal {
+		return TypeProposer
+	}
+
+	return TypeAttester
+}
+
+// Description returns a human-readable description of the offence.
+func (o *Offence) Description() string {
+	switch o.Kind {
+	case OffenceDoubleProposal:
+		if o.Header1 == nil || o.Header1.Message == nil {
+			return "double proposal"
+		}
+
+		return fmt.Sprintf("double proposal at slot %d", o.Header1.Message.Slot)
+	case OffenceDoubleVote, OffenceSurroundVote, OffenceUnknown:
+		if o.Attestation1 == nil || o.Attestation1.Data == nil ||
+			o.Attestation2 == nil || o.Attestation2.Data == nil {
+			return string(o.Kind)
+		}
+		data1 := o.Attestation1.Data
+		data2 := o.Attestation2.Data
+		switch {
+		case o.Kind == OffenceDoubleVote:
+			return fmt.Sprintf("double vote for target epoch %d", data1.Target.Epoch)
+		case o.Surrounding == SurroundingAttestation1:
+			return fmt.Sprintf("attestation 1 (source %d, target %d) surrounds attestation 2 (source %d, target %d)",
+				data1.Source.Epoch, data1.Target.Epoch, data2.Source.Epoch, data2.Target.Epoch)
+		case o.Surrounding == SurroundingAttestation2:
+			return fmt.Sprintf("attestation 2 (source %d, target %d) surrounds attestation 1 (source %d, target %d)",
+				data2.Source.Epoch, data2.Target.Epoch, data1.Source.Epoch, data1.Target.Epoch)
+		default:
+			return fmt.Sprintf("attestations (source %d, target %d) and (source %d, target %d) do not conflict",
+				data1.Source.Epoch, data1.Target.Epoch, data2.Source.Epoch, data2.Target.Epoch)
+		}
+	default:
+		return string(o.Kind)
+	}
+}
+
+// ClassifyAttestations returns the kind of offence committed by signing both
+// attestations and, for a surround vote, the attestation that surrounds the other.
+// Signing the same attestation twice is not an offence, so identical attestations
+// are of unknown kind.
+func ClassifyAttestations(data1 *spec.AttestationData, data2 *spec.AttestationData) (OffenceKind, Surrounding) {
+	switch {
+	case sameAttestationData(data1, data2):
+		return OffenceUnknown, ""
+	case data1.Target.Epoch == data2.Target.Epoch:
+		return OffenceDoubleVote, ""
+	case data1.Source.Epoch < data2.Source.Epoch && data2.Target.Epoch < data1.Target.Epoch:
+		return OffenceSurroundVote, SurroundingAttestation1
+	case data2.Source.Epoch < data1.Source.Epoch && data1.Target.Epoch < data2.Target.Epoch:
+		return OffenceSurroundVote, SurroundingAttestation2
+	default:
+		return OffenceUnknown, ""
+	}
+}
+
+// sameAttestationData returns true if the attestation data are identical.
+func sameAttestationData(data1 *spec.AttestationData, data2 *spec.AttestationData) bool {
+	return data1.Slot == data2.Slot &&
+		data1.Index == data2.Index &&
+		data1.BeaconBlockRoot == data2.BeaconBlockRoot &&
+		*data1.Source == *data2.Source &&
+		*data1.Target == *data2.Target
+}
+
+// Penalty is the estimated cost of a slashing to a validator.
+type Penalty struct {
+	// EffectiveBalance is the effective balance of the validator.
+	EffectiveBalance spec.Gwei `json:"effective_balance"`
+	// InitialPenalty is the penalty applied when the validator is slashed.
+	InitialPenalty spec.Gwei `json:"initial_penalty"`
+	// CorrelationEpoch is the epoch at which the correlation penalty is applied.
+	CorrelationEpoch spec.Epoch `json:"correlation_epoch"`
+	// CorrelationPenalty is the projected correlation penalty, assuming no further slashings.
+	CorrelationPenalty spec.Gwei `json:"correlation_penalty"`
+	// SlashedBalance is the total slashed balance that contributes to the correlation penalty.
+	SlashedBalance spec.Gwei `json:"slashed_balance"`
+	// TotalActiveBalance is the total active balance of the chain.
+	TotalActiveBalance spec.Gwei `json:"total_active_balance"`
+	// WhistleblowerReward is the reward to the whistleblower, excluding the proposer reward.
+	WhistleblowerReward spec.Gwei `json:"whistleblower_reward"`
+	// ProposerReward is the reward to the proposer of the including block.
+	ProposerReward spec.Gwei `json:"proposer_reward"`
+}
+
+// SlashingEvent is a validator slashed in a block, along with all of the offences
+// in the block that implicate it.
+type SlashingEvent struct {
+	// ValidatorIndex is the index of the slashed validator.
+	ValidatorIndex spec.ValidatorIndex `json:"validator_index"`
+	// PubKey is the public key of the slashed validator, if known.
+	PubKey spec.BLSPubKey `json:"pubkey"`
+	// EffectiveBalance is the effective balance of the slashed validator before the
+	// including block, if known.
+	EffectiveBalance spec.Gwei `json:"effective_balance,omitempty"`
+	// Offences are the offences that implicate the validator.
+	Offences []*Offence `json:"offences"`
+	// Slot is the slot of the block that included the slashing.
+	Slot spec.Slot `json:"slot"`
+	// BlockRoot is the root of the block that included the slashing.
+	BlockRoot spec.Root `json:"block_root"`
+	// ProposerIndex is the proposer of the block that included the slashing, who is
+	// also the whistleblower.
+	ProposerIndex spec.ValidatorIndex `json:"proposer_index"`
+	// Source is the means by which the slashing was detected.
+	Source Source `json:"source"`
+	// Status is the finality status of the block that included the slashing.
+	Status FinalityStatus `json:"status"`
+	// AlreadySlashed is true if the validator had already been slashed, or was otherwise
+	// not slashable, before the including block, so is not penalised by it.
+	AlreadySlashed bool `json:"already_slashed,omitempty"`
+	// Optimistic is true if the including block had not been validated when the
+	// slashing was reported.
+	Optimistic bool `json:"optimistic,omitempty"`
+	// Penalty is the estimated penalty for the slashing, if known.
+	Penalty *Penalty `json:"penalty,omitempty"`
+}
+
+// HasType returns true if any of the offences are of the given type.
+func (e *SlashingEvent) HasType(slashingType Type) bool {
+	for _, offence := range e.Offences {
+		if offence.Type() == slashingType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasKind returns true if any of the offences are of the given kind.
+func (e *SlashingEvent) HasKind(kind OffenceKind) bool {
+	for _, offence := range e.Offences {
+		if offence.Kind == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Unverified returns true if the evidence for the offences has been checked and
+// none of it could be verified.
+func (e *SlashingEvent) Unverified() bool {
+	checked := false
+	for _, offence := range e.Offences {
+		if offence.Verification == VerificationVerified {
+			return false
+		}
+		if offence.Verification != "" {
+			checked = true
+		}
+	}
+
+	return checked
+}
+
+// MassSlashingEvent is a number of validators slashed across the network in recent
+// epochs that has crossed the configured thresholds.
+type MassSlashingEvent struct {
+	// FromEpoch is the first epoch of the window.
+	FromEpoch spec.Epoch `json:"from_epoch"`
+	// ToEpoch is the last epoch of the window.
+	ToEpoch spec.Epoch `json:"to_epoch"`
+	// Count is the number of validators slashed in the window.
+	Count int `json:"count"`
+	// Balance is the total effective balance of the validators slashed in the window,
+	// where known.
+	Balance spec.Gwei `json:"balance"`
+	// ValidatorIndices are the indices of the validators slashed in the window.
+	ValidatorIndices []spec.ValidatorIndex `json:"validator_indices"`
+	// Penalty is the projected penalty for a validator slashed now, if known.
+	Penalty *Penalty `json:"penalty,omitempty"`
+	// Drill is true if the mass slashing is synthetic, created for a drill.
+	Drill bool `json:"drill,omitempty"`
+}
+
+// DrillResult is the result of a step of a drill.
+type DrillResult struct {
+	// Action is the name of the action.
+	Action string `json:"action"`
+	// Target is the script run or the notifier informed by the action, if any.
+	Target string `json:"target,omitempty"`
+	// Success is true if the action succeeded.
+	Success bool `json:"success"`
+	// Skipped is true if the action was a script that was not run.
+	Skipped bool `json:"skipped,omitempty"`
+	// Output is the combined output of the script.
+	Output string `json:"output,omitempty"`
+	// Error is the reason the action failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Handler is the interface for consumers of slashing events.
+type Handler interface {
+	// OnSlashing handles a slashing event.
+	OnSlashing(ctx context.Context, event *SlashingEvent) error
+}
+
+// MassSlashingHandler is the interface for handlers of mass slashing events.
+type MassSlashingHandler interface {
+	// OnMassSlashing is called when a mass slashing is detected.
+	OnMassSlashing(ctx context.Context, event *MassSlashingEvent) error
+}
+
+// StatusHandler is the interface for handlers of changes to the finality status of
+// slashings.
+type StatusHandler interface {
+	// OnStatus is called when the block that included a slashing is finalized or
+	// orphaned.  The event carries the new status.
+	OnStatus(ctx context.Context, event *SlashingEvent) error
+}
+
+// Status is the status of the slashings service and its beacon node.
+type Status struct {
+	// HeadSlot is the head slot reported by the beacon node.
+	HeadSlot spec.Slot `json:"head_slot"`
+	// LastEventSlot is the slot of the most recent head event.
+	LastEventSlot spec.Slot `json:"last_event_slot"`
+	// LastProcessedSlot is the slot of the most recent block processed.
+	LastProcessedSlot spec.Slot `json:"last_processed_slot"`
+	// Connected is true if the beacon node responded to the most recent status check.
+	Connected bool `json:"connected"`
+	// Syncing is true if the beacon node is syncing.
+	Syncing bool `json:"syncing"`
+	// Optimistic is true if the beacon node is optimistic.
+	Optimistic bool `json:"optimistic"`
+	// ELOffline is true if the beacon node's execution client is offline, and nil if unknown.
+	ELOffline *bool `json:"el_offline"`
+	// StreamStale is true if no head events have been received recently.
+	StreamStale bool `json:"stream_stale"`
+	// HeldBlocks is the number of optimistic blocks with slashings awaiting validation.
+	HeldBlocks int `json:"held_blocks"`
+	// PendingBlocks is the number of blocks queued but not yet processed.
+	PendingBlocks int `json:"pending_blocks"`
+	// Backfilling is true if blocks queued by a backfill are still being processed.
+	Backfilling bool `json:"backfilling"`
+	// LastProgress is the time at which the processing pipeline last handled a block.
+	LastProgress time.Time `json:"last_progress"`
+}
+
+// StatusProvider provides the status of the slashings service.
+type StatusProvider interface {
+	// Status returns the current status.
+	Status(ctx context.Context) *Status
+}
+
 // Service is the slashings service.
 type Service interface {
+	Handler
+
 	// OnAttesterSlashed handles an attester slashing event.
 	OnAttesterSlashed(ctx context.Context, index spec.ValidatorIndex) error
 
@@ -0,0 +1,101 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slashings_test
+
+import (
+	"testing"
+
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/stretchr/testify/require"
+)
+
+// attestationData returns attestation data with the given source and target epochs.
+func attestationData(source phase0.Epoch, target phase0.Epoch, root byte) *phase0.AttestationData {
+	return &phase0.AttestationData{
+		Slot:            phase0.Slot(target) * 32,
+		BeaconBlockRoot: phase0.Root{root},
+		Source: &phase0.Checkpoint{
+			Epoch: source,
+			Root:  phase0.Root{0x01},
+		},
+		Target: &phase0.Checkpoint{
+			Epoch: target,
+			Root:  phase0.Root{root},
+		},
+	}
+}
+
+func TestClassifyAttestations(t *testing.T) {
+	tests := []struct {
+		name        string
+		data1       *phase0.AttestationData
+		data2       *phase0.AttestationData
+		kind        slashings.OffenceKind
+		surrounding slashings.Surrounding
+	}{
+		{
+			name:  "DoubleVote",
+			data1: attestationData(10, 11, 0x02),
+			data2: attestationData(10, 11, 0x03),
+			kind:  slashings.OffenceDoubleVote,
+		},
+		{
+			name:  "DoubleVoteDifferentSource",
+			data1: attestationData(9, 11, 0x02),
+			data2: attestationData(10, 11, 0x02),
+			kind:  slashings.OffenceDoubleVote,
+		},
+		{
+			name:        "Attestation1Surrounds",
+			data1:       attestationData(8, 12, 0x02),
+			data2:       attestationData(9, 11, 0x03),
+			kind:        slashings.OffenceSurroundVote,
+			surrounding: slashings.SurroundingAttestation1,
+		},
+		{
+			name:        "Attestation2Surrounds",
+			data1:       attestationData(9, 11, 0x02),
+			data2:       attestationData(8, 12, 0x03),
+			kind:        slashings.OffenceSurroundVote,
+			surrounding: slashings.SurroundingAttestation2,
+		},
+		{
+			name:  "SharedSource",
+			data1: attestationData(8, 12, 0x02),
+			data2: attestationData(8, 11, 0x03),
+			kind:  slashings.OffenceUnknown,
+		},
+		{
+			name:  "Sequential",
+			data1: attestationData(10, 11, 0x02),
+			data2: attestationData(11, 12, 0x03),
+			kind:  slashings.OffenceUnknown,
+		},
+		{
+			name:  "Identical",
+			data1: attestationData(10, 11, 0x02),
+			data2: attestationData(10, 11, 0x02),
+			kind:  slashings.OffenceUnknown,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kind, surrounding := slashings.ClassifyAttestations(test.data1, test.data2)
+			require.Equal(t, test.kind, kind)
+			require.Equal(t, test.surrounding, surrounding)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+
+	"github.com/attestantio/esd/services/metrics"
+	"github.com/attestantio/esd/services/stream"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var metricsNamespace = "esd"
+
+var (
+	subscribers     prometheus.Gauge
+	publishedEvents *prometheus.CounterVec
+	droppedSubs     prometheus.Counter
+)
+
+func registerMetrics(ctx context.Context, monitor metrics.Service) error {
+	if subscribers != nil {
+		// Already registered.
+		return nil
+	}
+	if monitor == nil {
+		// No monitor.
+		return nil
+	}
+	if monitor.Presenter() == "prometheus" {
+		return registerPrometheusMetrics(ctx)
+	}
+
+	return nil
+}
+
+func registerPrometheusMetrics(_ context.Context) error {
+	subscribers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "stream",
+		Name:      "subscribers",
+		Help:      "Number of subscribers to the event stream",
+	})
+	if err := prometheus.Register(subscribers); err != nil {
+		return errors.Wrap(err, "failed to register subscribers")
+	}
+
+	publishedEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "stream",
+		Name:      "events_total",
+		Help:      "Total number of events published to the event stream",
+	}, []string{"type"})
+	if err := prometheus.Register(publishedEvents); err != nil {
+		return errors.Wrap(err, "failed to register events_total")
+	}
+
+	droppedSubs = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "stream",
+		Name:      "dropped_subscribers_total",
+		Help:      "Total number of subscribers dropped for falling behind the event stream",
+	})
+	if err := prometheus.Register(droppedSubs); err != nil {
+		return errors.Wrap(err, "failed to register dropped_subscribers_total")
+	}
+
+	return nil
+}
+
+func setSubscribers(_ context.Context, count int) {
+	if subscribers != nil {
+		subscribers.Set(float64(count))
+	}
+}
+
+func eventPublished(_ context.Context, eventType stream.EventType) {
+	if publishedEvents != nil {
+		publishedEvents.WithLabelValues(string(eventType)).Inc()
+	}
+}
+
+func subscriberDropped(_ context.Context) {
+	if droppedSubs != nil {
+		droppedSubs.Inc()
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package memory provides an event stream that holds recent events in memory for replay.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/attestantio/esd/services/history"
+	"github.com/attestantio/esd/services/lifecycle"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/attestantio/esd/services/stream"
+	"github.com/attestantio/esd/util"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+)
+
+// subscriberBuffer is the number of events that a subscriber can fall behind before
+// it is dropped.
+const subscriberBuffer = 256
+
+// slashingData is the data of slashing and status events.
+type slashingData struct {
+	// ID is the ID of the slashing in the history.
+	ID string `json:"id"`
+	*slashings.SlashingEvent
+}
+
+// Service is an event stream that holds recent events in memory.
+type Service struct {
+	log         zerolog.Logger
+	logLevel    *util.LevelFilter
+	bufferSize  int
+	nextID      uint64
+	events      []*stream.Event
+	subscribers map[chan *stream.Event]struct{}
+	mu          sync.Mutex
+}
+
+// New creates a new in-memory event stream.
+func New(ctx context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.  The level is applied by a filter so that it can be reconfigured.
+	logLevel := util.NewLevelFilter(parameters.logLevel)
+	log := zerologger.With().Str("service", "stream").Str("impl", "memory").Logger().Level(zerolog.TraceLevel).Hook(logLevel)
+
+	if err := registerMetrics(ctx, parameters.monitor); err != nil {
+		return nil, errors.Wrap(err, "failed to register metrics")
+	}
+
+	s := &Service{
+		log:        log,
+		logLevel:   logLevel,
+		bufferSize: parameters.bufferSize,
+		// Events are not retained across restarts, so seed IDs from the current time
+		// to ensure that they continue to increase.
+		nextID:      uint64(time.Now().UnixMicro()),
+		events:      make([]*stream.Event, 0),
+		subscribers: make(map[chan *stream.Event]struct{}),
+	}
+
+	return s, nil
+}
+
+// SetLogLevel changes the log level of the running service.
+func (s *Service) SetLogLevel(level zerolog.Level) {
+	s.logLevel.SetLevel(level)
+	s.log.Info().Str("log_level", level.String()).Msg("Reconfigured")
+}
+
+// OnSlashing publishes a slashing.
+func (s *Service) OnSlashing(ctx context.Context, event *slashings.SlashingEvent) error {
+	return s.publish(ctx, stream.EventTypeSlashing, &slashingData{
+		ID:            history.SlashingID(event.Slot, event.BlockRoot, event.ValidatorIndex),
+		SlashingEvent: event,
+	})
+}
+
+// OnStatus publishes a change in the finality status of a slashing.
+func (s *Service) OnStatus(ctx context.Context, event *slashings.SlashingEvent) error {
+	return s.publish(ctx, stream.EventTypeStatus, &slashingData{
+		ID:            history.SlashingID(event.Slot, event.BlockRoot, event.ValidatorIndex),
+		SlashingEvent: event,
+	})
+}
+
+// OnMassSlashing publishes a mass slashing.
+func (s *Service) OnMassSlashing(ctx context.Context, event *slashings.MassSlashingEvent) error {
+	return s.publish(ctx, stream.EventTypeMassSlashing, event)
+}
+
+// OnLifecycle publishes a milestone reached by a slashed validator.
+func (s *Service) OnLifecycle(ctx context.Context, event *lifecycle.Event) error {
+	return s.publish(ctx, stream.EventTypeLifecycle, event)
+}
+
+// publish publishes an event to the subscribers, and holds it for replay.
+func (s *Service) publish(ctx context.Context, eventType stream.EventType, data any) error {
+	// Encode now, as the source of the event may be altered after it is published.
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode event")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	event := &stream.Event{
+		ID:   s.nextID,
+		Type: eventType,
+		Time: time.Now(),
+		Data: encoded,
+	}
+	s.events = append(s.events, event)
+	if len(s.events) > s.bufferSize {
+		s.events = s.events[len(s.events)-s.bufferSize:]
+	}
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// The subscriber has fallen behind; drop it so that it can reconnect and
+			// replay from its last event.
+			s.log.Warn().Msg("Subscriber fell behind the event stream; dropping")
+			delete(s.subscribers, ch)
+			close(ch)
+			subscriberDropped(ctx)
+		}
+	}
+	setSubscribers(ctx, len(s.subscribers))
+	eventPublished(ctx, eventType)
+	s.log.Trace().Uint64("id", event.ID).Str("type", string(eventType)).Msg("Published event")
+
+	return nil
+}
+
+// Subscribe subscribes to events published after the event with the given ID, or to
+// new events only if the ID is 0.
+func (s *Service) Subscribe(ctx context.Context, fromID uint64) *stream.Subscription {
+	ch := make(chan *stream.Event, subscriberBuffer)
+
+	s.mu.Lock()
+	subscription := &stream.Subscription{
+		Replay: make([]*stream.Event, 0),
+		Events: ch,
+	}
+	if fromID != 0 {
+		oldestID := s.nextID + 1
+		if len(s.events) > 0 {
+			oldestID = s.events[0].ID
+		}
+		subscription.Missed = fromID+1 < oldestID
+		for _, event := range s.events {
+			if event.ID > fromID {
+				subscription.Replay = append(subscription.Replay, event)
+			}
+		}
+	}
+	s.subscribers[ch] = struct{}{}
+	setSubscribers(ctx, len(s.subscribers))
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		if _, exists := s.subscribers[ch]; exists {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+		setSubscribers(ctx, len(s.subscribers))
+		s.mu.Unlock()
+	}()
+
+	return subscription
+}
@@ -0,0 +1,84 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stream re-publishes the events processed by esd to downstream consumers.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/attestantio/esd/services/lifecycle"
+	"github.com/attestantio/esd/services/slashings"
+)
+
+// EventType is the type of a published event.
+type EventType string
+
+const (
+	// EventTypeSlashing is a slashing found in a block.
+	EventTypeSlashing EventType = "slashing"
+	// EventTypeStatus is a change in the finality status of a slashing.
+	EventTypeStatus EventType = "status"
+	// EventTypeMassSlashing is a mass slashing.
+	EventTypeMassSlashing EventType = "mass_slashing"
+	// EventTypeLifecycle is a milestone reached by a slashed validator.
+	EventTypeLifecycle EventType = "lifecycle"
+)
+
+// EventTypes are the types of event that are published.
+var EventTypes = []EventType{
+	EventTypeSlashing,
+	EventTypeStatus,
+	EventTypeMassSlashing,
+	EventTypeLifecycle,
+}
+
+// Event is a published event.
+type Event struct {
+	// ID is the identifier of the event.  IDs increase with each event, including
+	// across restarts.
+	ID uint64 `json:"id"`
+	// Type is the type of the event.
+	Type EventType `json:"type"`
+	// Time is the time at which the event was published.
+	Time time.Time `json:"time"`
+	// Data is the JSON-encoded event.
+	Data json.RawMessage `json:"data"`
+}
+
+// Subscription is a subscription to published events.
+type Subscription struct {
+	// Replay are the held events published after the requested ID, oldest first.
+	Replay []*Event
+	// Missed is true if some events published after the requested ID are no longer
+	// held, so cannot be replayed.
+	Missed bool
+	// Events receives events as they are published.  It is closed when the
+	// subscription's context is done, or if the subscriber falls too far behind.
+	Events <-chan *Event
+}
+
+// Service is the event stream service.
+type Service interface {
+	slashings.Handler
+	slashings.StatusHandler
+	slashings.MassSlashingHandler
+	lifecycle.Handler
+
+	// Subscribe subscribes to events published after the event with the given ID,
+	// or to new events only if the ID is 0.  The subscription lasts until the
+	// context is done.
+	Subscribe(ctx context.Context, fromID uint64) *Subscription
+}
@@ -0,0 +1,29 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package watchlist provides the validators that esd watches.
+package watchlist
+
+import (
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// Service is the watchlist service.
+type Service interface {
+	// Watched returns true if the validator with the given index or public key is
+	// watched.  All validators are watched if the watchlist is empty.
+	Watched(index phase0.ValidatorIndex, pubKey phase0.BLSPubKey) bool
+
+	// Size returns the number of entries in the watchlist, 0 if all validators are watched.
+	Size() int
+}
@@ -0,0 +1,156 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package static provides a watchlist of validators fixed by configuration.
+package static
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+)
+
+// Service is a watchlist of validators fixed by configuration.
+type Service struct {
+	log       zerolog.Logger
+	entries   *entries
+	entriesMu sync.RWMutex
+}
+
+// entries are the validators in the watchlist.
+type entries struct {
+	indices map[phase0.ValidatorIndex]struct{}
+	pubKeys map[phase0.BLSPubKey]struct{}
+}
+
+// New creates a new static watchlist.
+func New(_ context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.
+	log := zerologger.With().Str("service", "watchlist").Str("impl", "static").Logger()
+	if parameters.logLevel != log.GetLevel() {
+		log = log.Level(parameters.logLevel)
+	}
+
+	entries, err := parseEntries(parameters.validators)
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{
+		log:     log,
+		entries: entries,
+	}
+	log.Trace().Int("size", s.Size()).Msg("Watchlist created")
+
+	return s, nil
+}
+
+// Update replaces the validators in the watchlist.  The watchlist is unchanged if any
+// of the validators is invalid.
+func (s *Service) Update(validators []string) error {
+	entries, err := parseEntries(validators)
+	if err != nil {
+		return err
+	}
+
+	s.entriesMu.Lock()
+	s.entries = entries
+	s.entriesMu.Unlock()
+	s.log.Trace().Int("size", s.Size()).Msg("Watchlist updated")
+
+	return nil
+}
+
+// parseEntries parses validators, given as indices or public keys.
+func parseEntries(validators []string) (*entries, error) {
+	e := &entries{
+		indices: make(map[phase0.ValidatorIndex]struct{}),
+		pubKeys: make(map[phase0.BLSPubKey]struct{}),
+	}
+	for _, validator := range validators {
+		if err := e.add(validator); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// add adds a validator, given as an index or a public key, to the entries.
+func (e *entries) add(validator string) error {
+	validator = strings.TrimSpace(validator)
+	if strings.HasPrefix(validator, "0x") {
+		data, err := hex.DecodeString(strings.TrimPrefix(validator, "0x"))
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("invalid public key %q", validator))
+		}
+		if len(data) != phase0.PublicKeyLength {
+			return fmt.Errorf("public key %q has incorrect length", validator)
+		}
+		var pubKey phase0.BLSPubKey
+		copy(pubKey[:], data)
+		e.pubKeys[pubKey] = struct{}{}
+
+		return nil
+	}
+
+	index, err := strconv.ParseUint(validator, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid validator %q; must be an index or a public key", validator)
+	}
+	e.indices[phase0.ValidatorIndex(index)] = struct{}{}
+
+	return nil
+}
+
+// Watched returns true if the validator with the given index or public key is
+// watched.  All validators are watched if the watchlist is empty.
+func (s *Service) Watched(index phase0.ValidatorIndex, pubKey phase0.BLSPubKey) bool {
+	s.entriesMu.RLock()
+	defer s.entriesMu.RUnlock()
+
+	if s.entries.size() == 0 {
+		return true
+	}
+	if _, exists := s.entries.indices[index]; exists {
+		return true
+	}
+	_, exists := s.entries.pubKeys[pubKey]
+
+	return exists
+}
+
+// Size returns the number of entries in the watchlist, 0 if all validators are watched.
+func (s *Service) Size() int {
+	s.entriesMu.RLock()
+	defer s.entriesMu.RUnlock()
+
+	return s.entries.size()
+}
+
+// size returns the number of entries.
+func (e *entries) size() int {
+	return len(e.indices) + len(e.pubKeys)
+}
@@ -0,0 +1,134 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	healthsvc "github.com/attestantio/esd/services/health"
+	"github.com/attestantio/esd/services/slashings"
+	"github.com/pkg/errors"
+)
+
+// systemdStatusInterval is the interval between status updates sent to systemd if it
+// does not require watchdog pings more often.
+const systemdStatusInterval = 30 * time.Second
+
+// notifySystemd sends the given state to systemd, if esd is running as a notify service.
+func notifySystemd(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if strings.HasPrefix(socket, "@") {
+		// Abstract namespace socket.
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to systemd notify socket")
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return errors.Wrap(err, "failed to notify systemd")
+	}
+
+	return nil
+}
+
+// systemdWatchdogInterval returns the interval at which systemd requires watchdog
+// pings, or 0 if the watchdog is not enabled for this process.
+func systemdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseUint(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec == 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		// The watchdog is for another process.
+		return 0
+	}
+
+	// Ping at twice the required rate, as recommended by sd_watchdog_enabled(3).
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// startSystemd informs systemd when esd is ready, and then keeps its status up to date
+// and pings its watchdog while head events are flowing and being processed.
+func startSystemd(ctx context.Context, status slashings.StatusProvider, health healthsvc.Service) {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		log.Trace().Msg("Not running as a systemd notify service")
+		return
+	}
+
+	watchdogInterval := systemdWatchdogInterval()
+	interval := systemdStatusInterval
+	if watchdogInterval != 0 && watchdogInterval < interval {
+		interval = watchdogInterval
+	}
+	log.Trace().Dur("watchdog_interval", watchdogInterval).Msg("Running as a systemd notify service")
+
+	go func() {
+		// Ready once the first block announced by a head event has been processed.
+		for status.Status(ctx).LastProcessedSlot == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+		if err := notifySystemd(fmt.Sprintf("READY=1\nSTATUS=%s", systemdStatus(status.Status(ctx)))); err != nil {
+			log.Warn().Err(err).Msg("Failed to notify systemd of readiness")
+		} else {
+			log.Debug().Msg("Notified systemd of readiness")
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			current := status.Status(ctx)
+			state := fmt.Sprintf("STATUS=%s", systemdStatus(current))
+			if watchdogInterval != 0 {
+				if !current.StreamStale && health.Liveness(ctx).OK {
+					state += "\nWATCHDOG=1"
+				} else {
+					log.Debug().Msg("Head events are not being processed; not pinging systemd watchdog")
+				}
+			}
+			if err := notifySystemd(state); err != nil {
+				log.Warn().Err(err).Msg("Failed to notify systemd of status")
+			}
+		}
+	}()
+}
+
+// systemdStatus returns the status text shown by systemd.
+func systemdStatus(status *slashings.Status) string {
+	if status.StreamStale {
+		return fmt.Sprintf("No head events since slot %d; last processed slot %d", status.LastEventSlot, status.LastProcessedSlot)
+	}
+
+	return fmt.Sprintf("Last processed slot %d", status.LastProcessedSlot)
+}
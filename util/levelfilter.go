@@ -0,0 +1,52 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// LevelFilter is a zerolog hook that discards events below a level that can be changed
+// while the logger is in use.  Loggers using the filter should themselves log at trace
+// level, as events below the level of the logger never reach the filter.
+type LevelFilter struct {
+	level atomic.Int32
+}
+
+// NewLevelFilter creates a new level filter.
+func NewLevelFilter(level zerolog.Level) *LevelFilter {
+	f := &LevelFilter{}
+	f.SetLevel(level)
+
+	return f
+}
+
+// Level returns the current level of the filter.
+func (f *LevelFilter) Level() zerolog.Level {
+	return zerolog.Level(f.level.Load())
+}
+
+// SetLevel sets the level of the filter.
+func (f *LevelFilter) SetLevel(level zerolog.Level) {
+	f.level.Store(int32(level))
+}
+
+// Run discards the event if it is below the level of the filter.
+func (f *LevelFilter) Run(e *zerolog.Event, level zerolog.Level, _ string) {
+	if level < f.Level() {
+		e.Discard()
+	}
+}
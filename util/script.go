@@ -0,0 +1,70 @@
+// Copyright © 2024 Attestant Limited.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// scriptWaitDelay is the time to wait for the output of a script that has been killed,
+// in case it has started processes that hold its output open.
+const scriptWaitDelay = 5 * time.Second
+
+// RunScript runs a script with the given arguments, additional environment variables
+// and input, returning its combined output.  The script is killed if it has not
+// finished within the timeout, or when the context is done.  A timeout of 0 means no
+// timeout.
+func RunScript(ctx context.Context,
+	timeout time.Duration,
+	script string,
+	args []string,
+	env []string,
+	input []byte,
+) (
+	string,
+	error,
+) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	//nolint:gosec
+	cmd := exec.CommandContext(ctx, script, args...)
+	cmd.WaitDelay = scriptWaitDelay
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil && ctx.Err() != nil {
+		if timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("script did not finish within %s: %w", timeout, err)
+		} else {
+			err = fmt.Errorf("script stopped: %w", err)
+		}
+	}
+
+	return strings.TrimSpace(string(output)), err
+}